@@ -10,12 +10,19 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/NorskHelsenett/shorty/internal/bots"
+	"github.com/NorskHelsenett/shorty/internal/commands"
 	"github.com/NorskHelsenett/shorty/internal/config"
 	docs "github.com/NorskHelsenett/shorty/internal/docs"
+	"github.com/NorskHelsenett/shorty/internal/events"
+	"github.com/NorskHelsenett/shorty/internal/geoip"
 	"github.com/NorskHelsenett/shorty/internal/handlers"
+	"github.com/NorskHelsenett/shorty/internal/keygen"
 	"github.com/NorskHelsenett/shorty/internal/media"
 	"github.com/NorskHelsenett/shorty/internal/metrics"
 	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
 
 	rlog "github.com/NorskHelsenett/ror/pkg/rlog"
 	redis "github.com/go-redis/redis/v8"
@@ -137,6 +144,7 @@ func setupRouter(rdb *redis.Client) *mux.Router {
 	r := mux.NewRouter()
 	r.Use(middleware.RecoveryMiddleware)
 	r.Use(middleware.CORSMiddleware)
+	r.Use(middleware.DomainMiddleware)
 	r.PathPrefix("/").HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}).Methods(http.MethodOptions)
@@ -145,7 +153,7 @@ func setupRouter(rdb *redis.Client) *mux.Router {
 
 	// defines routes
 	r.HandleFunc("/health", HealthCheck)
-	r.HandleFunc("/{id}", handlers.Redirect(rdb)).Methods("GET")
+	r.Handle("/{id}", middleware.BotMiddleware(middleware.NamespaceMiddleware(handlers.Redirect(rdb)))).Methods("GET", "HEAD")
 	r.HandleFunc("/", handlers.Redirect(rdb)).Methods("GET")
 	r.HandleFunc("", handlers.Redirect(rdb)).Methods("GET")
 
@@ -157,17 +165,87 @@ func setupRouter(rdb *redis.Client) *mux.Router {
 	adminRoute.HandleFunc("/user", handlers.AddUserRedirect(rdb)).Methods("POST")
 	adminRoute.HandleFunc("/user", handlers.GetAllUsersRedirect(rdb)).Methods("GET")
 	adminRoute.HandleFunc("/user/{id}", handlers.DeleteUserRedirect(rdb)).Methods("DELETE")
+	adminRoute.HandleFunc("/me", handlers.GetCurrentUserRedirect()).Methods("GET")
+
+	// Admin reports
+	adminRoute.HandleFunc("/admin/loops", handlers.GetRedirectLoopsRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/admin/clicks", handlers.ExportClicksRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/admin/overview", handlers.GetOverviewRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/admin/tokens", handlers.GetAllAccessTokensRedirect(rdb)).Methods("GET")
+
+	// Service accounts
+	adminRoute.HandleFunc("/admin/services", handlers.GetServiceAccountsRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/admin/services/{name}", handlers.SetServiceAccountRedirect(rdb)).Methods("PUT")
+	adminRoute.HandleFunc("/admin/services/{name}", handlers.DeleteServiceAccountRedirect(rdb)).Methods("DELETE")
+
+	// Personal access tokens
+	adminRoute.HandleFunc("/tokens", handlers.GetAccessTokensRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/tokens", handlers.CreateAccessTokenRedirect(rdb)).Methods("POST")
+	adminRoute.HandleFunc("/tokens/{id}", handlers.RevokeAccessTokenRedirect(rdb)).Methods("DELETE")
+
+	// Stale and orphaned link reports
+	adminRoute.HandleFunc("/reports/stale", handlers.GetStaleLinksRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/reports/stale", handlers.UpdateStaleLinksRedirect(rdb)).Methods("POST")
+	adminRoute.HandleFunc("/reports/orphans", handlers.GetOrphanedLinksRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/reports/orphans/archive", handlers.ArchiveOrphanedLinksRedirect(rdb)).Methods("POST")
+
+	// UTM campaign presets
+	adminRoute.HandleFunc("/campaigns", handlers.GetCampaignsRedirect(rdb)).Methods("GET")
+	adminRoute.HandleFunc("/campaigns/{name}", handlers.SetCampaignRedirect(rdb)).Methods("PUT")
+	adminRoute.HandleFunc("/campaigns/{name}", handlers.DeleteCampaignRedirect(rdb)).Methods("DELETE")
+
+	// Key availability
+	adminRoute.HandleFunc("/availability", handlers.CheckAvailabilityRedirect(rdb)).Methods("GET")
 
 	// URL
 	urlRoute := adminRoute.PathPrefix("/").Subrouter()
+	urlRoute.Use(middleware.NamespaceMiddleware)
 	urlRoute.Use(middleware.IsOwnerMiddlewareWrapper(rdb))
 	urlRoute.HandleFunc("/", handlers.AddRedirect(rdb)).Methods("POST")
 	urlRoute.HandleFunc("/", handlers.GetAllRedirects(rdb)).Methods("GET")
 	urlRoute.HandleFunc("/{id}", handlers.UpdateRedirect(rdb)).Methods("PATCH")
 	urlRoute.HandleFunc("/{id}", handlers.DeleteRedirect(rdb)).Methods("DELETE")
 
+	// Weighted targets
+	urlRoute.HandleFunc("/{id}/targets", handlers.GetTargetsRedirect(rdb)).Methods("GET")
+	urlRoute.HandleFunc("/{id}/targets", handlers.SetTargetsRedirect(rdb)).Methods("PUT")
+	urlRoute.HandleFunc("/{id}/targets", handlers.AddTargetRedirect(rdb)).Methods("POST")
+	urlRoute.HandleFunc("/{id}/targets", handlers.UpdateTargetRedirect(rdb)).Methods("PATCH")
+	urlRoute.HandleFunc("/{id}/targets", handlers.RemoveTargetRedirect(rdb)).Methods("DELETE")
+
+	// Language variants
+	urlRoute.HandleFunc("/{id}/languages", handlers.GetLanguagesRedirect(rdb)).Methods("GET")
+	urlRoute.HandleFunc("/{id}/languages", handlers.SetLanguagesRedirect(rdb)).Methods("PUT")
+	urlRoute.HandleFunc("/{id}/languages/{lang}", handlers.SetLanguageRedirect(rdb)).Methods("PUT")
+	urlRoute.HandleFunc("/{id}/languages/{lang}", handlers.RemoveLanguageRedirect(rdb)).Methods("DELETE")
+
+	// Device targets
+	urlRoute.HandleFunc("/{id}/devices", handlers.GetDevicesRedirect(rdb)).Methods("GET")
+	urlRoute.HandleFunc("/{id}/devices", handlers.SetDevicesRedirect(rdb)).Methods("PUT")
+	urlRoute.HandleFunc("/{id}/devices/{device}", handlers.SetDeviceRedirect(rdb)).Methods("PUT")
+	urlRoute.HandleFunc("/{id}/devices/{device}", handlers.RemoveDeviceRedirect(rdb)).Methods("DELETE")
+
+	// Aliases
+	urlRoute.HandleFunc("/{id}/aliases", handlers.GetAliasesRedirect(rdb)).Methods("GET")
+	urlRoute.HandleFunc("/{id}/aliases", handlers.AddAliasRedirect(rdb)).Methods("POST")
+	urlRoute.HandleFunc("/{id}/aliases/{alias}", handlers.RemoveAliasRedirect(rdb)).Methods("DELETE")
+
+	// Enabled/paused status
+	urlRoute.HandleFunc("/{id}/status", handlers.GetStatusRedirect(rdb)).Methods("GET")
+	urlRoute.HandleFunc("/{id}/status", handlers.SetStatusRedirect(rdb)).Methods("PUT")
+
+	// UTM tagging
+	urlRoute.HandleFunc("/{id}/utm", handlers.GetUTMRedirect(rdb)).Methods("GET")
+	urlRoute.HandleFunc("/{id}/utm", handlers.SetUTMRedirect(rdb)).Methods("PUT")
+
+	// Statistics
+	urlRoute.HandleFunc("/{id}/stats", handlers.GetStatsRedirect(rdb)).Methods("GET")
+
+	// Rename
+	urlRoute.HandleFunc("/{id}/rename", handlers.RenameRedirect(rdb)).Methods("POST")
+
 	// QR-code
-	adminRoute.HandleFunc("/qr/{id}", handlers.GenerateQRCode(rdb)).Methods("GET")
+	adminRoute.Handle("/qr/{id}", middleware.NamespaceMiddleware(handlers.GenerateQRCode(rdb))).Methods("GET")
 	qrRouter := r.PathPrefix("/qr").Subrouter()
 	qrRouter.HandleFunc("/", handlers.GenerateQRCodeFromUrl()).Methods("GET")
 
@@ -196,21 +274,83 @@ func main() {
 	viper.SetDefault("OIDC_CLIENT_ID", defaultOIDCClientID)
 	viper.SetDefault("OIDC_PROVIDER_URL", defaultOIDCProviderURL)
 	viper.SetDefault("HOST", "https://k.nhn.no")
+	viper.SetDefault("OIDC_REFRESH_INTERVAL", time.Hour)
+	viper.SetDefault("OIDC_RETRY_INTERVAL", 10*time.Second)
 	viper.SetDefault("SKIPISSUERCHECK", false)
 	viper.SetDefault("INSECURE_SKIP_SIGNATURE_CHECK", false)
+	viper.SetDefault("KEY_FOLD_CASE", false)
+	viper.SetDefault("KEY_FOLD_SEPARATORS", false)
+	viper.SetDefault("COLLAPSE_REDIRECT_CHAINS", false)
+	viper.SetDefault("MAX_REDIRECT_CHAIN", 3)
+	viper.SetDefault("KEY_LENGTH", keygen.DefaultLength)
+	viper.SetDefault("KEY_ALPHABET", keygen.DefaultAlphabet)
+	viper.SetDefault("KEY_EXCLUDE_AMBIGUOUS", false)
+	viper.SetDefault("TRUST_PROXY_HEADERS", false)
+	viper.SetDefault("TRUSTED_PROXIES", middleware.DefaultTrustedProxies)
+	viper.SetDefault("BOT_FILTER_ENABLED", true)
+	viper.SetDefault("BOT_RELOAD_INTERVAL", time.Minute)
+	viper.SetDefault("METRICS_TOP_LINKS", 0)
+	viper.SetDefault("CLICK_QUEUE_SIZE", events.DefaultQueueSize)
+	viper.SetDefault("CLICK_WORKERS", events.DefaultWorkers)
+	viper.SetDefault("CLICK_BATCH_SIZE", events.DefaultBatchSize)
+	viper.SetDefault("CLICK_FLUSH_INTERVAL", events.DefaultFlushInterval)
+	viper.SetDefault("CLICK_EVENT_RETENTION", 90*24*time.Hour)
+	viper.SetDefault("USER_INACTIVE_DAYS", redisdb.DefaultUserInactiveDays)
+	viper.SetDefault("TOKEN_MAX_DAYS", redisdb.DefaultTokenMaxDays)
+	viper.SetDefault("ADMIN_SOURCE", middleware.AdminSourceCombined)
+	viper.SetDefault("OIDC_GROUPS_CLAIM", "groups")
+	viper.SetDefault("OIDC_ADMIN_GROUPS", "")
 	viper.AutomaticEnv()
 
 	if version == "" {
 		version = "v1-develop"
 	}
 
+	// Run maintenance subcommands instead of the server
+	if commands.IsCommand(os.Args[1:]) {
+		os.Exit(commands.Run(os.Args[1:]))
+	}
+
+	if err := config.LoadDomains(); err != nil {
+		rlog.Error("Failed to load domain config", err)
+		os.Exit(1)
+	}
+
+	if err := middleware.CheckAdminSource(); err != nil {
+		rlog.Error("Invalid admin role config", err)
+		os.Exit(1)
+	}
+
 	media.Load()
 
+	// Country breakdowns in the link statistics need a GeoIP2/GeoLite2 country database
+	if err := geoip.Load(viper.GetString("GEOIP_DB_PATH")); err != nil {
+		rlog.Error("Failed to load GeoIP database, clicks are counted without country", err)
+	}
+
+	// Bot filtering uses built-in patterns plus optional User-Agent and address range files,
+	// which are reloaded when they change
+	botConfig := bots.Config{
+		UserAgentsFile: viper.GetString("BOT_USER_AGENTS_FILE"),
+		IPRangesFile:   viper.GetString("BOT_IP_RANGES_FILE"),
+	}
+	if err := bots.Load(botConfig); err != nil {
+		rlog.Error("Failed to load bot lists, using built-in patterns only", err)
+	}
+	go bots.Watch(ctx, botConfig, viper.GetDuration("BOT_RELOAD_INTERVAL"))
+
 	rlog.Info(fmt.Sprintf("## Starting k.nhn.no version %s", version))
 
 	// initializes metrics
 	metrics.InitMetrics()
 
+	// The OIDC provider is discovered once and refreshed in the background. If the identity provider
+	// cannot be reached, the server still starts, and the v1 API answers 503 until discovery succeeds.
+	if err := middleware.LoadOIDC(ctx); err != nil {
+		rlog.Error("Failed to load OIDC provider, retrying in the background", err)
+	}
+	go middleware.WatchOIDC(ctx, viper.GetDuration("OIDC_REFRESH_INTERVAL"), viper.GetDuration("OIDC_RETRY_INTERVAL"))
+
 	//loads listener config
 	listener = NewHTTPServer()
 
@@ -222,6 +362,23 @@ func main() {
 	}
 	server = newServer(db)
 
+	// Clicks are written to the statistics in batches by background workers, off the redirect path
+	handlers.Clicks = events.NewQueue(events.Config{
+		QueueSize:     viper.GetInt("CLICK_QUEUE_SIZE"),
+		Workers:       viper.GetInt("CLICK_WORKERS"),
+		BatchSize:     viper.GetInt("CLICK_BATCH_SIZE"),
+		FlushInterval: viper.GetDuration("CLICK_FLUSH_INTERVAL"),
+	}, func(clicks []models.ClickEvent) error {
+		return redisdb.RecordClicks(db, clicks)
+	})
+
+	// Per-link clicks are only exported for the most clicked links, to keep the number of series capped
+	if limit := viper.GetInt("METRICS_TOP_LINKS"); limit > 0 {
+		metrics.RegisterTopLinks(limit, func(limit int) ([]models.LinkClicks, error) {
+			return redisdb.TopLinks(db, limit)
+		})
+	}
+
 	// Configure Swagger
 	configureSwagger()
 
@@ -257,6 +414,11 @@ func main() {
 			rlog.Error("Server shutdown error", err)
 		}
 
+		// Write the queued clicks before the redis connection is closed
+		if err := handlers.Clicks.Close(shutdownCtx); err != nil {
+			rlog.Error("Failed to flush click queue", err)
+		}
+
 		// Perform cleanup operations
 		if err := db.Close(); err != nil {
 			rlog.Error("Error closing Redis connection", err)
@@ -278,5 +440,6 @@ func main() {
 	<-ctx.Done()
 
 	media.Unload()
+	geoip.Unload()
 	rlog.Info("Server stopped gracefully")
 }
@@ -0,0 +1,240 @@
+// Package bots classifies requests from crawlers, link previewers and security scanners,
+// so they can be counted apart from human clicks.
+package bots
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/mileusna/useragent"
+)
+
+// Reasons a request is classified as automated
+const (
+	ReasonUserAgent = "user-agent"
+	ReasonAddress   = "address"
+	ReasonPrefetch  = "prefetch"
+	ReasonHead      = "head"
+)
+
+// defaultUserAgents are User-Agent fragments of common link previewers, mail scanners, crawlers and HTTP libraries.
+// Matching is case-insensitive.
+var defaultUserAgents = []string{
+	"bot", "crawler", "spider", "slurp", "preview", "scanner", "monitor", "headless",
+	"facebookexternalhit", "slack-imgproxy", "skypeuripreview", "microsoft office", "ms-office",
+	"whatsapp", "telegram", "discord", "embedly", "iframely", "outlook-ios", "bitlybot",
+	"barracuda", "mimecast", "proofpoint", "safelinks", "trendmicro", "symantec", "forcepoint",
+	"curl/", "wget/", "python-requests", "python-urllib", "go-http-client", "java/", "okhttp",
+	"libwww-perl", "apache-httpclient", "node-fetch", "axios/", "postmanruntime",
+}
+
+// Config holds the files with extra User-Agent fragments and scanner address ranges.
+// Both files have one entry per line; empty lines and lines starting with # are ignored.
+type Config struct {
+	UserAgentsFile string
+	IPRangesFile   string
+}
+
+type classifier struct {
+	userAgents []string
+	networks   []*net.IPNet
+	modTimes   map[string]time.Time
+}
+
+var (
+	mu      sync.RWMutex
+	current = &classifier{userAgents: defaultUserAgents}
+)
+
+// Load reads the configured files and replaces the classifier in use.
+// On error the previous classifier stays in use.
+func Load(config Config) error {
+	next := &classifier{
+		userAgents: append([]string{}, defaultUserAgents...),
+		modTimes:   map[string]time.Time{},
+	}
+
+	if config.UserAgentsFile != "" {
+		lines, modTime, err := readLines(config.UserAgentsFile)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			next.userAgents = append(next.userAgents, strings.ToLower(line))
+		}
+		next.modTimes[config.UserAgentsFile] = modTime
+	}
+
+	if config.IPRangesFile != "" {
+		lines, modTime, err := readLines(config.IPRangesFile)
+		if err != nil {
+			return err
+		}
+		for _, line := range lines {
+			network, err := parseNetwork(line)
+			if err != nil {
+				return err
+			}
+			next.networks = append(next.networks, network)
+		}
+		next.modTimes[config.IPRangesFile] = modTime
+	}
+
+	mu.Lock()
+	current = next
+	mu.Unlock()
+
+	rlog.Info("Bot classifier loaded", rlog.Int("userAgents", len(next.userAgents)), rlog.Int("ipRanges", len(next.networks)))
+	return nil
+}
+
+// Watch reloads the classifier whenever one of the configured files changes, until ctx is done
+func Watch(ctx context.Context, config Config, interval time.Duration) {
+	if interval <= 0 || (config.UserAgentsFile == "" && config.IPRangesFile == "") {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !changed(config) {
+				continue
+			}
+			if err := Load(config); err != nil {
+				rlog.Error("Failed to reload bot classifier, keeping the previous one", err)
+			}
+		}
+	}
+}
+
+// changed reports whether any configured file has another modification time than when it was loaded
+func changed(config Config) bool {
+	mu.RLock()
+	loaded := current.modTimes
+	mu.RUnlock()
+
+	for _, path := range []string{config.UserAgentsFile, config.IPRangesFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if !info.ModTime().Equal(loaded[path]) {
+			return true
+		}
+	}
+	return false
+}
+
+// Classify returns why a request looks automated, or an empty string for requests from people
+func Classify(r *http.Request, ip string) string {
+	if r.Method == http.MethodHead {
+		return ReasonHead
+	}
+	if isPrefetch(r) {
+		return ReasonPrefetch
+	}
+
+	mu.RLock()
+	c := current
+	mu.RUnlock()
+
+	if c.matchesUserAgent(r.UserAgent()) {
+		return ReasonUserAgent
+	}
+	if c.matchesAddress(ip) {
+		return ReasonAddress
+	}
+	return ""
+}
+
+// isPrefetch reports whether the browser fetches the link speculatively, without the user clicking it
+func isPrefetch(r *http.Request) bool {
+	for _, header := range []string{"Sec-Purpose", "Purpose", "X-Purpose", "X-Moz"} {
+		value := strings.ToLower(r.Header.Get(header))
+		if strings.Contains(value, "prefetch") || strings.Contains(value, "preview") {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *classifier) matchesUserAgent(userAgent string) bool {
+	if strings.TrimSpace(userAgent) == "" || useragent.Parse(userAgent).Bot {
+		return true
+	}
+
+	lower := strings.ToLower(userAgent)
+	for _, fragment := range c.userAgents {
+		if strings.Contains(lower, fragment) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *classifier) matchesAddress(ip string) bool {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return false
+	}
+	for _, network := range c.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseNetwork parses a CIDR range, or a single address
+func parseNetwork(value string) (*net.IPNet, error) {
+	if !strings.Contains(value, "/") {
+		if ip := net.ParseIP(value); ip != nil {
+			bits := 8 * len(ip.To16())
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 32
+			}
+			return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+		}
+	}
+	_, network, err := net.ParseCIDR(value)
+	return network, err
+}
+
+// readLines returns the entries of a list file together with its modification time
+func readLines(path string) ([]string, time.Time, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, info.ModTime(), scanner.Err()
+}
@@ -0,0 +1,116 @@
+package bots
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+const browserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36"
+
+func TestClassify(t *testing.T) {
+	dir := t.TempDir()
+	userAgents := filepath.Join(dir, "user-agents.txt")
+	ipRanges := filepath.Join(dir, "ip-ranges.txt")
+	writeFile(t, userAgents, "# internal link checker\nNHN-LinkCheck\n")
+	writeFile(t, ipRanges, "# mail scanners\n203.0.113.0/24\n\n2001:db8::1\n")
+
+	if err := Load(Config{UserAgentsFile: userAgents, IPRangesFile: ipRanges}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = Load(Config{}) })
+
+	tests := []struct {
+		name      string
+		method    string
+		userAgent string
+		header    string
+		ip        string
+		want      string
+	}{
+		{name: "Browser", userAgent: browserUserAgent, ip: "192.0.2.1", want: ""},
+		{name: "HEAD request", method: http.MethodHead, userAgent: browserUserAgent, ip: "192.0.2.1", want: ReasonHead},
+		{name: "Prefetch", userAgent: browserUserAgent, header: "prefetch", ip: "192.0.2.1", want: ReasonPrefetch},
+		{name: "Teams preview", userAgent: "Mozilla/5.0 (Windows NT 6.1; WOW64) SkypeUriPreview Preview/0.5", ip: "192.0.2.1", want: ReasonUserAgent},
+		{name: "Slack", userAgent: "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)", ip: "192.0.2.1", want: ReasonUserAgent},
+		{name: "HTTP library", userAgent: "python-requests/2.31.0", ip: "192.0.2.1", want: ReasonUserAgent},
+		{name: "Empty User-Agent", userAgent: "", ip: "192.0.2.1", want: ReasonUserAgent},
+		{name: "User-Agent from file", userAgent: "nhn-linkcheck/1.0", ip: "192.0.2.1", want: ReasonUserAgent},
+		{name: "Scanner range", userAgent: browserUserAgent, ip: "203.0.113.42", want: ReasonAddress},
+		{name: "Single scanner address", userAgent: browserUserAgent, ip: "2001:db8::1", want: ReasonAddress},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			method := tc.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			req := httptest.NewRequest(method, "/ab", nil)
+			req.Header.Set("User-Agent", tc.userAgent)
+			if tc.header != "" {
+				req.Header.Set("Sec-Purpose", tc.header)
+			}
+
+			if got := Classify(req, tc.ip); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestLoadKeepsPreviousOnError(t *testing.T) {
+	dir := t.TempDir()
+	ipRanges := filepath.Join(dir, "ip-ranges.txt")
+	writeFile(t, ipRanges, "203.0.113.0/24\n")
+
+	if err := Load(Config{IPRangesFile: ipRanges}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = Load(Config{}) })
+
+	writeFile(t, ipRanges, "not a range\n")
+	if err := Load(Config{IPRangesFile: ipRanges}); err == nil {
+		t.Fatal("expected error for invalid range")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+	req.Header.Set("User-Agent", browserUserAgent)
+	if got := Classify(req, "203.0.113.1"); got != ReasonAddress {
+		t.Errorf("expected previous ranges to stay in use, got %q", got)
+	}
+}
+
+func TestChanged(t *testing.T) {
+	dir := t.TempDir()
+	userAgents := filepath.Join(dir, "user-agents.txt")
+	writeFile(t, userAgents, "checker\n")
+	config := Config{UserAgentsFile: userAgents}
+
+	if err := Load(config); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { _ = Load(Config{}) })
+
+	if changed(config) {
+		t.Error("expected no change right after loading")
+	}
+
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(userAgents, later, later); err != nil {
+		t.Fatalf("failed to touch file: %v", err)
+	}
+	if !changed(config) {
+		t.Error("expected change after the file was modified")
+	}
+}
+
+func writeFile(t *testing.T, path string, content string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write %s: %v", path, err)
+	}
+}
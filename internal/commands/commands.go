@@ -0,0 +1,140 @@
+// Package commands implements the maintenance subcommands of the shorty binary
+package commands
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/config"
+	"github.com/NorskHelsenett/shorty/internal/events"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+)
+
+// command is a maintenance subcommand run instead of the HTTP server
+type command struct {
+	name        string
+	description string
+	run         func(args []string, out io.Writer) error
+}
+
+var commands = []command{
+	{
+		name:        "migrate-keys",
+		description: "report keys that collide after key normalisation, and merge them with -apply",
+		run:         migrateKeys,
+	},
+	{
+		name:        "export-clicks",
+		description: "write the click events of a period as NDJSON or CSV",
+		run:         exportClicks,
+	},
+}
+
+// IsCommand reports whether the arguments select a maintenance subcommand
+func IsCommand(args []string) bool {
+	return len(args) > 0 && args[0] != "" && args[0][0] != '-'
+}
+
+// Run executes the subcommand selected by args and returns the process exit code
+func Run(args []string) int {
+	for _, cmd := range commands {
+		if cmd.name == args[0] {
+			if err := cmd.run(args[1:], os.Stdout); err != nil {
+				rlog.Error(fmt.Sprintf("%s failed", cmd.name), err)
+				return 1
+			}
+			return 0
+		}
+	}
+
+	fmt.Fprintf(os.Stderr, "unknown command %q\n\nAvailable commands:\n", args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-16s %s\n", cmd.name, cmd.description)
+	}
+	return 2
+}
+
+// migrateKeys reports keys that collide after normalisation and optionally merges them
+func migrateKeys(args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("migrate-keys", flag.ContinueOnError)
+	apply := flags.Bool("apply", false, "merge colliding keys instead of only reporting them")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	rdb, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	collisions, err := redisdb.MergeKeyCollisions(rdb, *apply)
+	if err != nil {
+		return err
+	}
+
+	encoder := json.NewEncoder(out)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(collisions); err != nil {
+		return err
+	}
+
+	if !*apply && len(collisions) > 0 {
+		fmt.Fprintf(os.Stderr, "%d key collisions found, run with -apply to merge them\n", len(collisions))
+	}
+	return nil
+}
+
+// exportClicks writes the click events of a period, by default the previous UTC day
+func exportClicks(args []string, out io.Writer) error {
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	flags := flag.NewFlagSet("export-clicks", flag.ContinueOnError)
+	from := flags.String("from", today.AddDate(0, 0, -1).Format(time.RFC3339), "start of the period, RFC 3339, included")
+	to := flags.String("to", today.Format(time.RFC3339), "end of the period, RFC 3339, excluded")
+	format := flags.String("format", events.FormatNDJSON, "output format, ndjson or csv")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	start, err := time.Parse(time.RFC3339, *from)
+	if err != nil {
+		return fmt.Errorf("invalid -from: %w", err)
+	}
+	end, err := time.Parse(time.RFC3339, *to)
+	if err != nil {
+		return fmt.Errorf("invalid -to: %w", err)
+	}
+
+	writer, err := events.NewExportWriter(out, *format)
+	if err != nil {
+		return err
+	}
+
+	rdb, err := config.NewClient()
+	if err != nil {
+		return err
+	}
+	defer rdb.Close()
+
+	exported := 0
+	err = redisdb.ExportClicks(rdb, start, end, func(click models.ExportedClick) error {
+		exported++
+		return writer.Write(click)
+	})
+	if err != nil {
+		return err
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "%d clicks exported\n", exported)
+	return nil
+}
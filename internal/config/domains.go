@@ -0,0 +1,194 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+// defaultFallbackURL is where unknown keys are sent when a domain has no fallback URL configured
+const defaultFallbackURL = "https://nhn.no"
+
+var namespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// Domain is a short link host name served by this deployment.
+// Keys on a domain with a namespace are stored as "<namespace>:<key>", so every
+// domain has its own keys. The default domain has no namespace.
+type Domain struct {
+	Host        string   `json:"host"`
+	BaseURL     string   `json:"baseUrl,omitempty"`
+	Namespace   string   `json:"namespace,omitempty"`
+	FallbackURL string   `json:"fallbackUrl,omitempty"`
+	QRLogo      string   `json:"qrLogo,omitempty"`
+	Admins      []string `json:"admins,omitempty"`
+}
+
+var (
+	domainsMu sync.RWMutex
+	domains   []Domain
+)
+
+// LoadDomains reads the domain configuration from the JSON file in DOMAINS_CONFIG.
+// Without a file the deployment serves a single domain built from BASE_URL.
+// The first domain is the default, used for requests to unknown hosts.
+func LoadDomains() error {
+	loaded := []Domain{{}}
+	if path := viper.GetString("DOMAINS_CONFIG"); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read domain config: %w", err)
+		}
+		if err := json.Unmarshal(data, &loaded); err != nil {
+			return fmt.Errorf("failed to parse domain config: %w", err)
+		}
+		if len(loaded) == 0 {
+			return fmt.Errorf("domain config %s has no domains", path)
+		}
+	}
+
+	namespaces := make(map[string]bool, len(loaded))
+	for i := range loaded {
+		if err := loaded[i].complete(i == 0); err != nil {
+			return err
+		}
+		if namespaces[loaded[i].Namespace] {
+			return fmt.Errorf("domain %s: namespace %q is used by another domain", loaded[i].Host, loaded[i].Namespace)
+		}
+		namespaces[loaded[i].Namespace] = true
+	}
+
+	domainsMu.Lock()
+	domains = loaded
+	domainsMu.Unlock()
+	return nil
+}
+
+// complete validates a domain and fills in defaults for fields that are not set
+func (d *Domain) complete(isDefault bool) error {
+	if isDefault && d.Host == "" {
+		u, err := url.Parse(BaseURL())
+		if err != nil || u.Hostname() == "" {
+			return fmt.Errorf("invalid BASE_URL %q", BaseURL())
+		}
+		d.Host = u.Hostname()
+		if d.BaseURL == "" {
+			d.BaseURL = BaseURL()
+		}
+	}
+
+	d.Host = strings.ToLower(strings.TrimSpace(d.Host))
+	if d.Host == "" {
+		return fmt.Errorf("domain without host in domain config")
+	}
+	if d.BaseURL == "" {
+		d.BaseURL = "https://" + d.Host
+	}
+	d.BaseURL = strings.TrimSuffix(d.BaseURL, "/")
+	if d.FallbackURL == "" {
+		d.FallbackURL = defaultFallbackURL
+	}
+
+	d.Namespace = strings.ToLower(strings.TrimSpace(d.Namespace))
+	if d.Namespace != "" && !namespacePattern.MatchString(d.Namespace) {
+		return fmt.Errorf("domain %s: namespace can only contain lowercase letters, numbers and dash", d.Host)
+	}
+	if isDefault && d.Namespace != "" {
+		return fmt.Errorf("domain %s: the first (default) domain cannot have a namespace", d.Host)
+	}
+
+	for i, admin := range d.Admins {
+		d.Admins[i] = strings.ToLower(strings.TrimSpace(admin))
+	}
+	return nil
+}
+
+// Domains returns all configured domains, the default domain first
+func Domains() []Domain {
+	domainsMu.RLock()
+	defer domainsMu.RUnlock()
+
+	if len(domains) == 0 {
+		// LoadDomains has not been called, fall back to BASE_URL
+		d := Domain{}
+		if err := d.complete(true); err != nil {
+			return nil
+		}
+		return []Domain{d}
+	}
+	return slices.Clone(domains)
+}
+
+// LookupDomain returns the domain configured for a host name, ignoring any port.
+// Hosts listed in SHORT_DOMAINS are extra host names of the default domain.
+func LookupDomain(host string) (Domain, bool) {
+	host = strings.ToLower(host)
+	if h, _, found := strings.Cut(host, ":"); found {
+		host = h
+	}
+
+	all := Domains()
+	for _, d := range all {
+		if d.Host == host {
+			return d, true
+		}
+	}
+
+	for _, extra := range GetList("SHORT_DOMAINS") {
+		// Entries may be given as bare host names or as URLs
+		if u, err := url.Parse(extra); err == nil && u.Host != "" {
+			extra = u.Hostname()
+		}
+		if len(all) > 0 && strings.EqualFold(extra, host) {
+			return all[0], true
+		}
+	}
+	return Domain{}, false
+}
+
+// DomainForHost returns the domain configured for a host name, or the default domain for unknown hosts
+func DomainForHost(host string) Domain {
+	if d, ok := LookupDomain(host); ok {
+		return d
+	}
+	all := Domains()
+	if len(all) == 0 {
+		return Domain{FallbackURL: defaultFallbackURL}
+	}
+	return all[0]
+}
+
+// Key returns the stored key of a key on this domain
+func (d Domain) Key(key string) string {
+	if d.Namespace == "" || key == "" {
+		return key
+	}
+	return d.Namespace + ":" + key
+}
+
+// LocalKey returns a stored key as it is used on this domain, without the namespace
+func (d Domain) LocalKey(key string) string {
+	if d.Namespace == "" {
+		return key
+	}
+	return strings.TrimPrefix(key, d.Namespace+":")
+}
+
+// Owns reports whether a stored key belongs to this domain
+func (d Domain) Owns(key string) bool {
+	if d.Namespace == "" {
+		return !strings.Contains(key, ":")
+	}
+	return strings.HasPrefix(key, d.Namespace+":")
+}
+
+// IsAdmin reports whether the user is an admin of this domain
+func (d Domain) IsAdmin(email string) bool {
+	return slices.Contains(d.Admins, strings.ToLower(email))
+}
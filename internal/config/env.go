@@ -0,0 +1,36 @@
+package config
+
+import (
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// GetList returns a list setting, with entries separated by semicolons or commas
+func GetList(key string) []string {
+	value := viper.GetString(key)
+	if value == "" {
+		return nil
+	}
+
+	parts := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ';' || r == ','
+	})
+
+	list := make([]string, 0, len(parts))
+	for _, part := range parts {
+		if part = strings.TrimSpace(part); part != "" {
+			list = append(list, part)
+		}
+	}
+	return list
+}
+
+// BaseURL returns the public base URL of the short links, without a trailing slash
+func BaseURL() string {
+	baseURL := strings.TrimSuffix(viper.GetString("BASE_URL"), "/")
+	if baseURL == "" {
+		baseURL = "https://k.nhn.no"
+	}
+	return baseURL
+}
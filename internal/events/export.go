@@ -0,0 +1,79 @@
+package events
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+)
+
+// Export formats
+const (
+	FormatNDJSON = "ndjson"
+	FormatCSV    = "csv"
+)
+
+// ErrUnknownFormat is returned for export formats other than ndjson and csv
+var ErrUnknownFormat = errors.New("unknown export format, use ndjson or csv")
+
+// exportColumns is the CSV header, in the same order and with the same names as the NDJSON fields
+var exportColumns = []string{"timestamp", "key", "target", "referrerDomain", "deviceClass", "bot"}
+
+// ExportWriter encodes exported clicks as NDJSON, one object per line, or as CSV with a header
+type ExportWriter struct {
+	json *json.Encoder
+	csv  *csv.Writer
+}
+
+// NewExportWriter creates a writer for the format. CSV output starts with the header.
+func NewExportWriter(w io.Writer, format string) (*ExportWriter, error) {
+	switch format {
+	case FormatNDJSON:
+		return &ExportWriter{json: json.NewEncoder(w)}, nil
+	case FormatCSV:
+		writer := &ExportWriter{csv: csv.NewWriter(w)}
+		if err := writer.csv.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return writer, nil
+	default:
+		return nil, ErrUnknownFormat
+	}
+}
+
+// ContentType returns the media type of an export format
+func ContentType(format string) string {
+	if format == FormatCSV {
+		return "text/csv; charset=utf-8"
+	}
+	return "application/x-ndjson"
+}
+
+// Write encodes one click
+func (e *ExportWriter) Write(click models.ExportedClick) error {
+	click.Timestamp = click.Timestamp.UTC()
+	if e.json != nil {
+		return e.json.Encode(click)
+	}
+	return e.csv.Write([]string{
+		click.Timestamp.Format(time.RFC3339Nano),
+		click.Key,
+		click.Target,
+		click.ReferrerDomain,
+		click.DeviceClass,
+		strconv.FormatBool(click.Bot),
+	})
+}
+
+// Flush writes any buffered output
+func (e *ExportWriter) Flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		return e.csv.Error()
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package events
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+)
+
+func TestExportWriter(t *testing.T) {
+	click := models.ExportedClick{
+		Timestamp:      time.Date(2025, 3, 15, 12, 0, 0, 500_000_000, time.UTC),
+		Key:            "onboarding",
+		Target:         "https://example.com/?a=1,2",
+		ReferrerDomain: "qr",
+		DeviceClass:    "mobile",
+	}
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{
+			format: FormatNDJSON,
+			want:   `{"timestamp":"2025-03-15T12:00:00.5Z","key":"onboarding","target":"https://example.com/?a=1,2","referrerDomain":"qr","deviceClass":"mobile","bot":false}` + "\n",
+		},
+		{
+			format: FormatCSV,
+			want:   "timestamp,key,target,referrerDomain,deviceClass,bot\n2025-03-15T12:00:00.5Z,onboarding,\"https://example.com/?a=1,2\",qr,mobile,false\n",
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.format, func(t *testing.T) {
+			var out bytes.Buffer
+			writer, err := NewExportWriter(&out, tc.format)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := writer.Write(click); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if err := writer.Flush(); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out.String() != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, out.String())
+			}
+		})
+	}
+
+	if _, err := NewExportWriter(&bytes.Buffer{}, "xml"); !errors.Is(err, ErrUnknownFormat) {
+		t.Errorf("expected ErrUnknownFormat, got %v", err)
+	}
+}
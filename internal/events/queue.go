@@ -0,0 +1,152 @@
+// Package events writes click events in the background, so redirects never wait on the statistics,
+// and encodes them for export.
+package events
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/metrics"
+	"github.com/NorskHelsenett/shorty/internal/models"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+)
+
+// Default queue settings
+const (
+	DefaultQueueSize     = 10000
+	DefaultWorkers       = 2
+	DefaultBatchSize     = 100
+	DefaultFlushInterval = time.Second
+)
+
+// Writer stores a batch of click events. The batch is reused after Writer returns.
+type Writer func(events []models.ClickEvent) error
+
+// Config holds the size of the queue, the number of workers, and how many events a worker
+// collects before writing them, or how long it waits for a batch to fill up
+type Config struct {
+	QueueSize     int
+	Workers       int
+	BatchSize     int
+	FlushInterval time.Duration
+}
+
+// Queue is a bounded in-process queue of click events, written in batches by background workers.
+// Events are dropped, and counted, when the queue is full.
+type Queue struct {
+	config Config
+	write  Writer
+	events chan models.ClickEvent
+	wg     sync.WaitGroup
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewQueue creates a queue and starts its workers. Settings that are not positive get the defaults.
+func NewQueue(config Config, write Writer) *Queue {
+	if config.QueueSize <= 0 {
+		config.QueueSize = DefaultQueueSize
+	}
+	if config.Workers <= 0 {
+		config.Workers = DefaultWorkers
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = DefaultBatchSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = DefaultFlushInterval
+	}
+
+	q := &Queue{
+		config: config,
+		write:  write,
+		events: make(chan models.ClickEvent, config.QueueSize),
+	}
+	for range config.Workers {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue adds an event to the queue without blocking. It returns false when the event was dropped.
+func (q *Queue) Enqueue(event models.ClickEvent) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		metrics.ClickEventsDropped.Inc()
+		return false
+	}
+
+	select {
+	case q.events <- event:
+		return true
+	default:
+		metrics.ClickEventsDropped.Inc()
+		return false
+	}
+}
+
+// Close stops accepting events and waits until the workers have written the queued ones, or ctx is done
+func (q *Queue) Close(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		rlog.Warn("Click queue not flushed before shutdown", rlog.Int("pending", len(q.events)))
+		return ctx.Err()
+	}
+}
+
+// work collects events into batches, and writes a batch when it is full, when the flush
+// interval has passed, and when the queue is closed
+func (q *Queue) work() {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]models.ClickEvent, 0, q.config.BatchSize)
+	flush := func() {
+		if len(batch) == 0 {
+			return
+		}
+		if err := q.write(batch); err != nil {
+			rlog.Error("Failed to write click events", err, rlog.Int("events", len(batch)))
+			metrics.ClickEventsFailed.Add(float64(len(batch)))
+		}
+		batch = batch[:0]
+	}
+
+	for {
+		select {
+		case event, ok := <-q.events:
+			if !ok {
+				flush()
+				return
+			}
+			batch = append(batch, event)
+			if len(batch) >= q.config.BatchSize {
+				flush()
+			}
+		case <-ticker.C:
+			flush()
+		}
+	}
+}
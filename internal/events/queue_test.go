@@ -0,0 +1,124 @@
+package events
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+)
+
+type recorder struct {
+	mu      sync.Mutex
+	batches [][]models.ClickEvent
+}
+
+func (r *recorder) write(events []models.ClickEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, append([]models.ClickEvent{}, events...))
+	return nil
+}
+
+func (r *recorder) count() (batches int, events int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, batch := range r.batches {
+		events += len(batch)
+	}
+	return len(r.batches), events
+}
+
+func TestQueueWritesFullBatches(t *testing.T) {
+	rec := &recorder{}
+	q := NewQueue(Config{QueueSize: 10, Workers: 1, BatchSize: 3, FlushInterval: time.Hour}, rec.write)
+
+	for range 6 {
+		if !q.Enqueue(models.ClickEvent{Key: "onboarding"}) {
+			t.Fatal("expected event to be queued")
+		}
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if batches, events := rec.count(); batches == 2 && events == 6 {
+			break
+		}
+		if time.Now().After(deadline) {
+			batches, events := rec.count()
+			t.Fatalf("expected 2 batches of 3 events, got %d batches and %d events", batches, events)
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestQueueFlushesOnInterval(t *testing.T) {
+	rec := &recorder{}
+	q := NewQueue(Config{QueueSize: 10, Workers: 1, BatchSize: 100, FlushInterval: 10 * time.Millisecond}, rec.write)
+	defer q.Close(context.Background())
+
+	q.Enqueue(models.ClickEvent{Key: "onboarding"})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		if _, events := rec.count(); events == 1 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the partial batch to be written after the flush interval")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestQueueDropsWhenFull(t *testing.T) {
+	block := make(chan struct{})
+	rec := &recorder{}
+	q := NewQueue(Config{QueueSize: 2, Workers: 1, BatchSize: 1, FlushInterval: time.Hour}, func(events []models.ClickEvent) error {
+		<-block
+		return rec.write(events)
+	})
+
+	// The worker holds one event while writing is blocked, and the queue holds two more
+	queued := 0
+	for range 10 {
+		if q.Enqueue(models.ClickEvent{Key: "onboarding"}) {
+			queued++
+		}
+	}
+	if queued > 3 {
+		t.Errorf("expected at most 3 queued events, got %d", queued)
+	}
+
+	close(block)
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, events := rec.count(); events != queued {
+		t.Errorf("expected every queued event to be written, got %d of %d", events, queued)
+	}
+}
+
+func TestQueueFlushesOnClose(t *testing.T) {
+	rec := &recorder{}
+	q := NewQueue(Config{QueueSize: 10, Workers: 2, BatchSize: 100, FlushInterval: time.Hour}, rec.write)
+
+	for range 5 {
+		q.Enqueue(models.ClickEvent{Key: "onboarding"})
+	}
+	if err := q.Close(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, events := rec.count(); events != 5 {
+		t.Errorf("expected 5 events written on close, got %d", events)
+	}
+
+	if q.Enqueue(models.ClickEvent{Key: "onboarding"}) {
+		t.Error("expected events to be dropped after close")
+	}
+}
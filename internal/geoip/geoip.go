@@ -0,0 +1,68 @@
+// Package geoip looks up the country of client addresses in a local MaxMind GeoIP2 or GeoLite2 database file
+package geoip
+
+import (
+	"net"
+	"sync"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/oschwald/geoip2-golang"
+)
+
+var (
+	mu     sync.RWMutex
+	reader *geoip2.Reader
+)
+
+// Load opens the country database at path, replacing any database loaded before.
+// Without a path country lookups are disabled.
+func Load(path string) error {
+	var next *geoip2.Reader
+	if path != "" {
+		var err error
+		next, err = geoip2.Open(path)
+		if err != nil {
+			return err
+		}
+	}
+
+	mu.Lock()
+	previous := reader
+	reader = next
+	mu.Unlock()
+
+	if previous != nil {
+		if err := previous.Close(); err != nil {
+			rlog.Error("Failed to close GeoIP database", err)
+		}
+	}
+	return nil
+}
+
+// Unload closes the database
+func Unload() {
+	if err := Load(""); err != nil {
+		rlog.Error("Failed to unload GeoIP database", err)
+	}
+}
+
+// Country returns the ISO 3166-1 alpha-2 code of the country of an address.
+// Returns an empty string when no database is loaded or the address is unknown.
+func Country(ip string) string {
+	addr := net.ParseIP(ip)
+	if addr == nil {
+		return ""
+	}
+
+	mu.RLock()
+	defer mu.RUnlock()
+	if reader == nil {
+		return ""
+	}
+
+	record, err := reader.Country(addr)
+	if err != nil {
+		return ""
+	}
+	return record.Country.IsoCode
+}
@@ -0,0 +1,227 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/events"
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// defaultExportPeriod is the period exported when no from time is given
+	defaultExportPeriod = 24 * time.Hour
+	// overviewTop is the number of entries in each ranking of the admin overview
+	overviewTop = 10
+	// overviewWeeks is the number of weeks with created links in the admin overview
+	overviewWeeks = 12
+	// overviewCacheTTL is how long the admin overview is served from memory
+	overviewCacheTTL = time.Minute
+)
+
+var (
+	FindRedirectLoops = redisdb.FindRedirectLoops
+	ExportClicks      = redisdb.ExportClicks
+	GetOverview       = redisdb.GetOverview
+)
+
+// overviewCache holds the last admin overview, since it reads every link
+var overviewCache struct {
+	sync.Mutex
+	overview models.AdminOverview
+	expires  time.Time
+}
+
+// Get redirect loops
+//
+//	@Summary	Get redirect loops
+//	@Schemes
+//	@Description	reports existing redirects whose targets loop through our own short links, admin only
+//	@Tags			v1 admin
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{array}		models.RedirectLoop
+//	@Failure		403	{string}	Forbidden
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/admin/loops [get]
+//	@Security		AccessToken
+func GetRedirectLoopsRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		loops, err := FindRedirectLoops(rdb)
+		if err != nil {
+			rlog.Error("Failed to find redirect loops", err)
+			http.Error(w, "Failed to find redirect loops", http.StatusInternalServerError)
+			return
+		}
+
+		if len(loops) == 0 {
+			loops = []models.RedirectLoop{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(loops); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// parseExportTime reads an RFC 3339 time, or a date (YYYY-MM-DD) meaning the start of that day in UTC.
+// With endOfDay a date means the end of that day, so the day is included.
+func parseExportTime(value string, endOfDay bool) (time.Time, bool) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, true
+	}
+	day, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if endOfDay {
+		day = day.AddDate(0, 0, 1)
+	}
+	return day, true
+}
+
+// parseExportPeriod reads the from and to query parameters. The period defaults to the last 24 hours.
+func parseExportPeriod(r *http.Request) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, ok := parseExportTime(value, true)
+		if !ok {
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+
+	from := to.Add(-defaultExportPeriod)
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, ok := parseExportTime(value, false)
+		if !ok {
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	return from, to, true
+}
+
+// Export clicks
+//
+//	@Summary	Export clicks
+//	@Schemes
+//	@Description	streams the raw click events of a period as NDJSON or CSV, with timestamp, key, target, referrer domain, device class and bot flag, admin only. Times are RFC 3339 or YYYY-MM-DD; a to date includes that day. Defaults to the last 24 hours
+//	@Tags			v1 admin
+//	@Accept			application/json
+//	@Produce		application/x-ndjson,text/csv
+//	@Param			from	query		string	false	"Start, included"
+//	@Param			to		query		string	false	"End, excluded"
+//	@Param			format	query		string	false	"ndjson (default) or csv"
+//	@Success		200		{array}		models.ExportedClick
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/admin/clicks [get]
+//	@Security		AccessToken
+func ExportClicksRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		from, to, ok := parseExportPeriod(r)
+		if !ok || !to.After(from) {
+			http.Error(w, "Invalid period, use RFC 3339 times or YYYY-MM-DD with from before to", http.StatusBadRequest)
+			return
+		}
+
+		format := r.URL.Query().Get("format")
+		if format == "" {
+			format = events.FormatNDJSON
+		}
+		writer, err := events.NewExportWriter(w, format)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// The response starts with the first event, so failures before that still get an error status
+		w.Header().Set("Content-Type", events.ContentType(format))
+		exported := 0
+		err = ExportClicks(rdb, from, to, func(click models.ExportedClick) error {
+			exported++
+			return writer.Write(click)
+		})
+		if err == nil {
+			err = writer.Flush()
+		}
+		if err != nil {
+			rlog.Error("Failed to export clicks", err, rlog.Int("exported", exported))
+			if exported == 0 {
+				http.Error(w, "Failed to export clicks", http.StatusInternalServerError)
+			}
+			return
+		}
+		rlog.Info("Clicks exported", rlog.Any("from", from), rlog.Any("to", to), rlog.Int("clicks", exported))
+	}
+}
+
+// Get overview
+//
+//	@Summary	Get overview
+//	@Schemes
+//	@Description	gets global usage statistics, admin only: total links, links created per ISO week for the last 12 weeks, the most clicked links, the most active creators, the most requested keys without a link, and the most used target domains. Cached for a minute
+//	@Tags			v1 admin
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{object}	models.AdminOverview
+//	@Failure		403	{string}	Forbidden
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/admin/overview [get]
+//	@Security		AccessToken
+func GetOverviewRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		// Concurrent requests wait for the first one to refresh the cache instead of reading every link again
+		overviewCache.Lock()
+		if time.Now().After(overviewCache.expires) {
+			overview, err := GetOverview(rdb, overviewTop, overviewWeeks)
+			if err != nil {
+				overviewCache.Unlock()
+				rlog.Error("Failed to get overview", err)
+				http.Error(w, "Failed to get overview", http.StatusInternalServerError)
+				return
+			}
+			overviewCache.overview, overviewCache.expires = overview, time.Now().Add(overviewCacheTTL)
+		}
+		overview := overviewCache.overview
+		overviewCache.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Cache-Control", fmt.Sprintf("private, max-age=%d", int(overviewCacheTTL.Seconds())))
+		if err := json.NewEncoder(w).Encode(overview); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
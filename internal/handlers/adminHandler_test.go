@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestExportClicksRedirect(t *testing.T) {
+	original := ExportClicks
+	t.Cleanup(func() { ExportClicks = original })
+
+	var gotFrom, gotTo time.Time
+	var exportErr error
+	ExportClicks = func(rdb *redis.Client, from time.Time, to time.Time, each func(models.ExportedClick) error) error {
+		gotFrom, gotTo = from, to
+		if exportErr != nil {
+			return exportErr
+		}
+		return each(models.ExportedClick{Timestamp: from, Key: "onboarding", Target: "https://example.com", ReferrerDomain: "qr", DeviceClass: "mobile"})
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		isAdmin    bool
+		err        error
+		wantStatus int
+		wantType   string
+		wantBody   string
+		wantFrom   string
+		wantTo     string
+	}{
+		{name: "NDJSON for a day", query: "?from=2025-03-15&to=2025-03-15", isAdmin: true, wantStatus: http.StatusOK, wantType: "application/x-ndjson",
+			wantBody: `"key":"onboarding"`, wantFrom: "2025-03-15T00:00:00Z", wantTo: "2025-03-16T00:00:00Z"},
+		{name: "CSV with times", query: "?from=2025-03-15T06:00:00Z&to=2025-03-15T18:00:00Z&format=csv", isAdmin: true, wantStatus: http.StatusOK, wantType: "text/csv; charset=utf-8",
+			wantBody: "timestamp,key,target,referrerDomain,deviceClass,bot\n", wantFrom: "2025-03-15T06:00:00Z", wantTo: "2025-03-15T18:00:00Z"},
+		{name: "Unknown format", query: "?format=xml", isAdmin: true, wantStatus: http.StatusBadRequest},
+		{name: "Reversed period", query: "?from=2025-03-16&to=2025-03-14", isAdmin: true, wantStatus: http.StatusBadRequest},
+		{name: "Invalid time", query: "?from=yesterday", isAdmin: true, wantStatus: http.StatusBadRequest},
+		{name: "Export failure", query: "", isAdmin: true, err: errors.New("redis down"), wantStatus: http.StatusInternalServerError},
+		{name: "Not admin", query: "", isAdmin: false, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			exportErr = tc.err
+			req := httptest.NewRequest(http.MethodGet, "/v1/admin/clicks"+tc.query, nil)
+			ctx := context.WithValue(req.Context(), middleware.IsAdminKey, tc.isAdmin)
+
+			rr := httptest.NewRecorder()
+			ExportClicksRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			if got := rr.Header().Get("Content-Type"); got != tc.wantType {
+				t.Errorf("expected content type %q, got %q", tc.wantType, got)
+			}
+			if !strings.Contains(rr.Body.String(), tc.wantBody) {
+				t.Errorf("expected body to contain %q, got %q", tc.wantBody, rr.Body.String())
+			}
+			if got := gotFrom.Format(time.RFC3339); got != tc.wantFrom {
+				t.Errorf("expected from %s, got %s", tc.wantFrom, got)
+			}
+			if got := gotTo.Format(time.RFC3339); got != tc.wantTo {
+				t.Errorf("expected to %s, got %s", tc.wantTo, got)
+			}
+		})
+	}
+}
+
+func TestGetOverviewRedirect(t *testing.T) {
+	original := GetOverview
+	t.Cleanup(func() {
+		GetOverview = original
+		overviewCache.expires = time.Time{}
+	})
+
+	calls := 0
+	GetOverview = func(rdb *redis.Client, top int, weeks int) (models.AdminOverview, error) {
+		calls++
+		return models.AdminOverview{TotalLinks: 42}, nil
+	}
+
+	request := func(isAdmin bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/v1/admin/overview", nil)
+		ctx := context.WithValue(req.Context(), middleware.IsAdminKey, isAdmin)
+		rr := httptest.NewRecorder()
+		GetOverviewRedirect(nil)(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	if rr := request(false); rr.Code != http.StatusForbidden {
+		t.Fatalf("expected status 403 for non-admins, got %d", rr.Code)
+	}
+
+	overviewCache.expires = time.Time{}
+	for range 2 {
+		rr := request(true)
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status 200, got %d", rr.Code)
+		}
+		if !strings.Contains(rr.Body.String(), `"totalLinks":42`) {
+			t.Errorf("expected overview in body, got %q", rr.Body.String())
+		}
+	}
+	if calls != 1 {
+		t.Errorf("expected the second request to be served from the cache, got %d calls", calls)
+	}
+}
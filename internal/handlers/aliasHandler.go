@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+var (
+	IsAlias     = redisdb.IsAlias
+	GetAliases  = redisdb.GetAliases
+	AddAlias    = redisdb.AddAlias
+	RemoveAlias = redisdb.RemoveAlias
+)
+
+// removeAlias removes an alias from the canonical redirect it points at
+func removeAlias(rdb *redis.Client, alias string, user string) error {
+	redirect, err := GetRedirect(rdb, alias)
+	if err != nil {
+		return err
+	}
+	return RemoveAlias(rdb, redirect.Path, alias, user)
+}
+
+// Get aliases
+//
+//	@Summary	Get aliases
+//	@Schemes
+//	@Description	gets the alias keys pointing at a redirect
+//	@Tags			v1 aliases
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Id"
+//	@Success		200	{array}		string
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/{id}/aliases [get]
+//	@Security		AccessToken
+func GetAliasesRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		aliases, err := GetAliases(rdb, id)
+		if err != nil {
+			rlog.Error("Failed to get aliases", err, rlog.String("id", id))
+			http.Error(w, "Failed to get aliases", errorStatus(err))
+			return
+		}
+		aliases = localKeys(middleware.GetDomain(r), aliases)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(aliases); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Add alias
+//
+//	@Summary	Add alias
+//	@Schemes
+//	@Description	adds an alias key that redirects to the same link; updates, stats and QR codes follow the canonical key
+//	@Tags			v1 aliases
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string				true	"Id"
+//	@Param			query	body		models.RedirectAlias	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		409		{string}	Conflict
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/aliases [post]
+//	@Security		AccessToken
+func AddAliasRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var alias models.RedirectAlias
+		if err := json.NewDecoder(r.Body).Decode(&alias); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if alias.Alias == "" || strings.Contains(alias.Alias, ":") {
+			http.Error(w, "Missing or invalid alias", http.StatusBadRequest)
+			return
+		}
+
+		// Aliases are created in the namespace of the requested domain
+		if err := AddAlias(rdb, id, middleware.GetDomain(r).Key(alias.Alias), user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Alias added successfully")
+	}
+}
+
+// Remove alias
+//
+//	@Summary	Remove alias
+//	@Schemes
+//	@Description	removes an alias key from a redirect
+//	@Tags			v1 aliases
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string	true	"Id"
+//	@Param			alias	path		string	true	"Alias"
+//	@Success		200		{object}	models.Response
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/aliases/{alias} [delete]
+//	@Security		AccessToken
+func RemoveAliasRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		params := mux.Vars(r)
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		alias := middleware.GetDomain(r).Key(params["alias"])
+		if err := RemoveAlias(rdb, params["id"], alias, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Alias removed successfully")
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+func TestAddAliasRedirect(t *testing.T) {
+	original := AddAlias
+	t.Cleanup(func() { AddAlias = original })
+
+	AddAlias = func(rdb *redis.Client, key string, alias string, user string) error {
+		if alias == "taken" {
+			return fmt.Errorf("%w: `%s`", redisdb.ErrKeyExists, alias)
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		isOwner    bool
+		wantStatus int
+	}{
+		{name: "Owner adds alias", body: `{"alias":"onboard"}`, isOwner: true, wantStatus: http.StatusOK},
+		{name: "Alias already in use", body: `{"alias":"taken"}`, isOwner: true, wantStatus: http.StatusConflict},
+		{name: "Missing alias", body: `{}`, isOwner: true, wantStatus: http.StatusBadRequest},
+		{name: "Not owner", body: `{"alias":"onboard"}`, isOwner: false, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/onboarding/aliases", bytes.NewBufferString(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "onboarding"})
+			ctx := context.WithValue(req.Context(), middleware.IsOwnerKey, tc.isOwner)
+			ctx = context.WithValue(ctx, middleware.UserKey, "owner@example.com")
+
+			rr := httptest.NewRecorder()
+			AddAliasRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Errorf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+		})
+	}
+}
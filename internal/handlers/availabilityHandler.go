@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+)
+
+// maxSuggestions is the number of alternative keys proposed for an unavailable key
+const maxSuggestions = 5
+
+var (
+	CheckKey    = redisdb.CheckKey
+	SuggestKeys = redisdb.SuggestKeys
+)
+
+// Check key availability
+//
+//	@Summary	Check key availability
+//	@Schemes
+//	@Description	reports whether a key is available, taken, reserved or invalid, and suggests free alternatives based on the key and the target url
+//	@Tags			v1
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			path	query		string	true	"Requested key"
+//	@Param			url		query		string	false	"Target url, used for suggestions"
+//	@Success		200		{object}	models.KeyAvailability
+//	@Failure		400		{string}	Bad	request
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/availability [get]
+//	@Security		AccessToken
+func CheckAvailabilityRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimSpace(r.URL.Query().Get("path"))
+		target := strings.TrimSpace(r.URL.Query().Get("url"))
+		if key == "" {
+			http.Error(w, "Missing path", http.StatusBadRequest)
+			return
+		}
+
+		domain := middleware.GetDomain(r)
+
+		availability := models.KeyAvailability{Path: key, Status: models.KeyInvalid, Reason: "key cannot contain `:`"}
+		if !strings.Contains(key, ":") {
+			var err error
+			availability, err = CheckKey(rdb, domain.Key(key))
+			if err != nil {
+				rlog.Error("Failed to check key availability", err, rlog.String("key", key))
+				http.Error(w, "Failed to check key availability", http.StatusInternalServerError)
+				return
+			}
+			availability.Path = key
+		}
+
+		if !availability.Available {
+			suggestions, err := SuggestKeys(rdb, domain.Key(strings.ReplaceAll(key, ":", "-")), target, maxSuggestions)
+			if err != nil {
+				rlog.Error("Failed to suggest keys", err, rlog.String("key", key))
+				http.Error(w, "Failed to suggest keys", http.StatusInternalServerError)
+				return
+			}
+			availability.Suggestions = localKeys(domain, suggestions)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(availability); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
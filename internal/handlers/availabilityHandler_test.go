@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestCheckAvailabilityRedirect(t *testing.T) {
+	originalCheck, originalSuggest := CheckKey, SuggestKeys
+	t.Cleanup(func() { CheckKey, SuggestKeys = originalCheck, originalSuggest })
+
+	CheckKey = func(rdb *redis.Client, key string) (models.KeyAvailability, error) {
+		if key == "taken" {
+			return models.KeyAvailability{Path: key, Status: models.KeyTaken, Reason: "key already in use"}, nil
+		}
+		return models.KeyAvailability{Path: key, Status: models.KeyAvailable, Available: true}, nil
+	}
+	SuggestKeys = func(rdb *redis.Client, key string, target string, limit int) ([]string, error) {
+		return []string{key + "-2"}, nil
+	}
+
+	tests := []struct {
+		name            string
+		query           string
+		wantCode        int
+		wantStatus      string
+		wantSuggestions int
+	}{
+		{name: "Free key", query: "?path=free", wantCode: http.StatusOK, wantStatus: models.KeyAvailable},
+		{name: "Taken key gets suggestions", query: "?path=taken&url=https://example.com", wantCode: http.StatusOK, wantStatus: models.KeyTaken, wantSuggestions: 1},
+		{name: "Namespace separator is invalid", query: "?path=hr:x", wantCode: http.StatusOK, wantStatus: models.KeyInvalid, wantSuggestions: 1},
+		{name: "Missing path", query: "", wantCode: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			CheckAvailabilityRedirect(nil)(rr, httptest.NewRequest(http.MethodGet, "/v1/availability"+tc.query, nil))
+
+			if rr.Code != tc.wantCode {
+				t.Fatalf("expected status %d, got %d", tc.wantCode, rr.Code)
+			}
+			if tc.wantCode != http.StatusOK {
+				return
+			}
+
+			var got models.KeyAvailability
+			if err := json.NewDecoder(rr.Body).Decode(&got); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if got.Status != tc.wantStatus {
+				t.Errorf("expected status %q, got %q", tc.wantStatus, got.Status)
+			}
+			if len(got.Suggestions) != tc.wantSuggestions {
+				t.Errorf("expected %d suggestions, got %v", tc.wantSuggestions, got.Suggestions)
+			}
+		})
+	}
+}
@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/mileusna/useragent"
+)
+
+var (
+	GetDevices   = redisdb.GetDevices
+	SetDevices   = redisdb.SetDevices
+	SetDevice    = redisdb.SetDevice
+	RemoveDevice = redisdb.RemoveDevice
+)
+
+// deviceClass classifies the client from its User-Agent into one of the
+// device classes that can be given a target. Returns an empty string for
+// clients that match none of them, such as other mobile platforms and bots.
+func deviceClass(userAgent string) string {
+	ua := useragent.Parse(userAgent)
+
+	switch {
+	case ua.Bot:
+		return ""
+	case ua.IsIOS():
+		return models.DeviceIOS
+	case ua.IsAndroid():
+		return models.DeviceAndroid
+	case ua.Desktop:
+		return models.DeviceDesktop
+	default:
+		return ""
+	}
+}
+
+// selectDevice picks the device target of a redirect for the request.
+// Returns false when the redirect has no target for the client's device class.
+func selectDevice(w http.ResponseWriter, r *http.Request, redirect models.RedirectPath) (string, bool) {
+	if len(redirect.Devices) == 0 {
+		return "", false
+	}
+	w.Header().Add("Vary", "User-Agent")
+
+	target, ok := redirect.Devices[deviceClass(r.UserAgent())]
+	return target, ok
+}
+
+// Get device targets
+//
+//	@Summary	Get device targets
+//	@Schemes
+//	@Description	gets the per-device targets of a redirect
+//	@Tags			v1 devices
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Id"
+//	@Success		200	{object}	map[string]string
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/{id}/devices [get]
+//	@Security		AccessToken
+func GetDevicesRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		devices, err := GetDevices(rdb, id)
+		if err != nil {
+			rlog.Error("Failed to get device targets", err, rlog.String("id", id))
+			http.Error(w, "Failed to get device targets", errorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(devices); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Replace device targets
+//
+//	@Summary	Replace device targets
+//	@Schemes
+//	@Description	replaces all per-device targets of a redirect, keyed by device class (ios, android, desktop)
+//	@Tags			v1 devices
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string				true	"Id"
+//	@Param			query	body		map[string]string	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/devices [put]
+//	@Security		AccessToken
+func SetDevicesRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var devices map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&devices); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		for _, target := range devices {
+			if !IsDeviceTarget(target) {
+				http.Error(w, "Invalid URL or app link format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if ok, statusCode, msg := CheckURL(rdb, id); !ok {
+			http.Error(w, msg, statusCode)
+			return
+		}
+
+		if err := SetDevices(rdb, id, devices, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Device targets updated successfully")
+	}
+}
+
+// Set device target
+//
+//	@Summary	Set device target
+//	@Schemes
+//	@Description	adds or replaces the target for one device class of a redirect. App deep links are allowed.
+//	@Tags			v1 devices
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string				true	"Id"
+//	@Param			device	path		string				true	"Device class"
+//	@Param			query	body		models.DeviceTarget	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/devices/{device} [put]
+//	@Security		AccessToken
+func SetDeviceRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		params := mux.Vars(r)
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var target models.DeviceTarget
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if !IsDeviceTarget(target.URL) {
+			http.Error(w, "Invalid URL or app link format", http.StatusBadRequest)
+			return
+		}
+
+		if err := SetDevice(rdb, params["id"], params["device"], target.URL, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Device target updated successfully")
+	}
+}
+
+// Remove device target
+//
+//	@Summary	Remove device target
+//	@Schemes
+//	@Description	removes the target for one device class of a redirect
+//	@Tags			v1 devices
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string	true	"Id"
+//	@Param			device	path		string	true	"Device class"
+//	@Success		200		{object}	models.Response
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/devices/{device} [delete]
+//	@Security		AccessToken
+func RemoveDeviceRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		params := mux.Vars(r)
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		if err := RemoveDevice(rdb, params["id"], params["device"], user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Device target removed successfully")
+	}
+}
@@ -0,0 +1,98 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/spf13/viper"
+)
+
+const (
+	iPhoneUA  = "Mozilla/5.0 (iPhone; CPU iPhone OS 17_4 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.4 Mobile/15E148 Safari/604.1"
+	androidUA = "Mozilla/5.0 (Linux; Android 14; Pixel 8) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Mobile Safari/537.36"
+	desktopUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/124.0.0.0 Safari/537.36"
+	botUA     = "Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)"
+)
+
+func TestDeviceClass(t *testing.T) {
+	tests := []struct {
+		name      string
+		userAgent string
+		want      string
+	}{
+		{name: "iPhone", userAgent: iPhoneUA, want: models.DeviceIOS},
+		{name: "Android", userAgent: androidUA, want: models.DeviceAndroid},
+		{name: "Desktop", userAgent: desktopUA, want: models.DeviceDesktop},
+		{name: "Bot", userAgent: botUA, want: ""},
+		{name: "Empty", userAgent: "", want: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := deviceClass(tc.userAgent); got != tc.want {
+				t.Errorf("deviceClass() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestSelectDevice(t *testing.T) {
+	redirect := models.RedirectPath{
+		Path: "app",
+		URL:  "https://example.com",
+		Devices: map[string]string{
+			models.DeviceIOS:     "https://apps.apple.com/no/app/id123",
+			models.DeviceAndroid: "https://play.google.com/store/apps/details?id=no.example",
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/app", nil)
+	req.Header.Set("User-Agent", iPhoneUA)
+	if got, ok := selectDevice(httptest.NewRecorder(), req, redirect); !ok || got != redirect.Devices[models.DeviceIOS] {
+		t.Errorf("expected App Store target, got %q (ok=%v)", got, ok)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/app", nil)
+	req.Header.Set("User-Agent", desktopUA)
+	if got, ok := selectDevice(httptest.NewRecorder(), req, redirect); ok {
+		t.Errorf("expected fallback for desktop without target, got %q", got)
+	}
+}
+
+func TestIsDeviceTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		want   bool
+	}{
+		{target: "https://apps.apple.com/no/app/id123", want: true},
+		{target: "helsenorge://open/meldinger", want: true},
+		{target: "intent://scan/#Intent;scheme=zxing;package=com.google.zxing.client.android;end", want: true},
+		{target: "javascript:alert(1)", want: false},
+		{target: "data:text/html,hello", want: false},
+		{target: "file:///etc/passwd", want: false},
+		{target: "not a url", want: false},
+		{target: "", want: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.target, func(t *testing.T) {
+			if got := IsDeviceTarget(tc.target); got != tc.want {
+				t.Errorf("IsDeviceTarget(%q) = %v, want %v", tc.target, got, tc.want)
+			}
+		})
+	}
+
+	t.Run("Restricted schemes", func(t *testing.T) {
+		viper.Set("DEVICE_TARGET_SCHEMES", "helsenorge")
+		defer viper.Set("DEVICE_TARGET_SCHEMES", "")
+
+		if !IsDeviceTarget("helsenorge://open") {
+			t.Error("expected allowed scheme to be accepted")
+		}
+		if IsDeviceTarget("otherapp://open") {
+			t.Error("expected scheme outside the allow list to be rejected")
+		}
+	})
+}
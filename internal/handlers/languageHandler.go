@@ -0,0 +1,242 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"golang.org/x/text/language"
+)
+
+var (
+	GetLanguages   = redisdb.GetLanguages
+	SetLanguages   = redisdb.SetLanguages
+	SetLanguage    = redisdb.SetLanguage
+	RemoveLanguage = redisdb.RemoveLanguage
+)
+
+// selectLanguage picks the language variant of a redirect for the request.
+// An explicit ?lang= query parameter wins over the Accept-Language header.
+// Returns false when the redirect has no matching variant and the default target should be used.
+func selectLanguage(w http.ResponseWriter, r *http.Request, redirect models.RedirectPath) (string, bool) {
+	if len(redirect.Languages) == 0 {
+		return "", false
+	}
+	w.Header().Add("Vary", "Accept-Language")
+
+	tags := make([]language.Tag, 0, len(redirect.Languages))
+	targets := make([]string, 0, len(redirect.Languages))
+	for lang, target := range redirect.Languages {
+		tag, err := language.Parse(lang)
+		if err != nil {
+			continue
+		}
+		tags = append(tags, tag)
+		targets = append(targets, target)
+	}
+	if len(tags) == 0 {
+		return "", false
+	}
+	matcher := language.NewMatcher(tags)
+
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		if tag, err := language.Parse(lang); err == nil {
+			if _, i, confidence := matcher.Match(tag); confidence != language.No {
+				return targets[i], true
+			}
+		}
+	}
+
+	preferred, _, err := language.ParseAcceptLanguage(r.Header.Get("Accept-Language"))
+	if err != nil || len(preferred) == 0 {
+		return "", false
+	}
+
+	if _, i, confidence := matcher.Match(preferred...); confidence != language.No {
+		return targets[i], true
+	}
+	return "", false
+}
+
+// Get language variants
+//
+//	@Summary	Get language variants
+//	@Schemes
+//	@Description	gets the per-language targets of a redirect
+//	@Tags			v1 languages
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Id"
+//	@Success		200	{object}	map[string]string
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/{id}/languages [get]
+//	@Security		AccessToken
+func GetLanguagesRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		languages, err := GetLanguages(rdb, id)
+		if err != nil {
+			rlog.Error("Failed to get language variants", err, rlog.String("id", id))
+			http.Error(w, "Failed to get language variants", errorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(languages); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Replace language variants
+//
+//	@Summary	Replace language variants
+//	@Schemes
+//	@Description	replaces all per-language targets of a redirect, keyed by language tag (nb, nn, en, ...)
+//	@Tags			v1 languages
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string				true	"Id"
+//	@Param			query	body		map[string]string	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/languages [put]
+//	@Security		AccessToken
+func SetLanguagesRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var languages map[string]string
+		if err := json.NewDecoder(r.Body).Decode(&languages); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		for _, target := range languages {
+			if !IsURL(target) {
+				http.Error(w, "Invalid URL format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if ok, statusCode, msg := CheckURL(rdb, id); !ok {
+			http.Error(w, msg, statusCode)
+			return
+		}
+
+		if err := SetLanguages(rdb, id, languages, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Language variants updated successfully")
+	}
+}
+
+// Set language variant
+//
+//	@Summary	Set language variant
+//	@Schemes
+//	@Description	adds or replaces the target for one language of a redirect
+//	@Tags			v1 languages
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string					true	"Id"
+//	@Param			lang	path		string					true	"Language tag"
+//	@Param			query	body		models.LanguageTarget	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/languages/{lang} [put]
+//	@Security		AccessToken
+func SetLanguageRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		params := mux.Vars(r)
+		id := params["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var target models.LanguageTarget
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if !IsURL(target.URL) {
+			http.Error(w, "Invalid URL format", http.StatusBadRequest)
+			return
+		}
+
+		if err := SetLanguage(rdb, id, params["lang"], target.URL, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Language variant updated successfully")
+	}
+}
+
+// Remove language variant
+//
+//	@Summary	Remove language variant
+//	@Schemes
+//	@Description	removes the target for one language of a redirect
+//	@Tags			v1 languages
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string	true	"Id"
+//	@Param			lang	path		string	true	"Language tag"
+//	@Success		200		{object}	models.Response
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/languages/{lang} [delete]
+//	@Security		AccessToken
+func RemoveLanguageRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		params := mux.Vars(r)
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		if err := RemoveLanguage(rdb, params["id"], params["lang"], user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Language variant removed successfully")
+	}
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+)
+
+func TestSelectLanguage(t *testing.T) {
+	redirect := models.RedirectPath{
+		Path: "vaksine",
+		URL:  "https://example.com",
+		Languages: map[string]string{
+			"nb": "https://example.com/nb",
+			"nn": "https://example.com/nn",
+			"en": "https://example.com/en",
+		},
+	}
+
+	tests := []struct {
+		name           string
+		url            string
+		acceptLanguage string
+		want           string
+		wantOK         bool
+	}{
+		{
+			name:           "Accept-Language picks best variant",
+			url:            "/vaksine",
+			acceptLanguage: "nn-NO,nn;q=0.9,en;q=0.5",
+			want:           "https://example.com/nn",
+			wantOK:         true,
+		},
+		{
+			name:           "Norwegian without written standard matches bokmål",
+			url:            "/vaksine",
+			acceptLanguage: "no",
+			want:           "https://example.com/nb",
+			wantOK:         true,
+		},
+		{
+			name:           "Query override wins over header",
+			url:            "/vaksine?lang=en",
+			acceptLanguage: "nb",
+			want:           "https://example.com/en",
+			wantOK:         true,
+		},
+		{
+			name:           "Unsupported language falls back to default",
+			url:            "/vaksine",
+			acceptLanguage: "ja",
+			wantOK:         false,
+		},
+		{
+			name:   "Missing header falls back to default",
+			url:    "/vaksine",
+			wantOK: false,
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.url, nil)
+			if tc.acceptLanguage != "" {
+				req.Header.Set("Accept-Language", tc.acceptLanguage)
+			}
+			rr := httptest.NewRecorder()
+
+			got, ok := selectLanguage(rr, req, redirect)
+			if ok != tc.wantOK {
+				t.Fatalf("expected ok %v; got %v (%q)", tc.wantOK, ok, got)
+			}
+			if ok && got != tc.want {
+				t.Errorf("expected %q; got %q", tc.want, got)
+			}
+			if rr.Header().Get("Vary") != "Accept-Language" {
+				t.Errorf("expected Vary header to contain Accept-Language")
+			}
+		})
+	}
+
+	t.Run("Redirect without variants", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/plain", nil)
+		req.Header.Set("Accept-Language", "nb")
+		if _, ok := selectLanguage(httptest.NewRecorder(), req, models.RedirectPath{URL: "https://example.com"}); ok {
+			t.Error("expected no language variant")
+		}
+	})
+}
@@ -9,13 +9,14 @@ import (
 	"os"
 	"strings"
 
+	"github.com/NorskHelsenett/shorty/internal/config"
 	"github.com/NorskHelsenett/shorty/internal/media"
+	"github.com/NorskHelsenett/shorty/internal/middleware"
 	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
 
 	"github.com/NorskHelsenett/ror/pkg/rlog"
 	"github.com/go-redis/redis/v8"
 	"github.com/gorilla/mux"
-	"github.com/spf13/viper"
 	"github.com/yeqown/go-qrcode/v2"
 	"github.com/yeqown/go-qrcode/writer/standard"
 )
@@ -28,15 +29,6 @@ func (mwc *QrWriteCloser) Close() error {
 	return nil
 }
 
-// getBaseURL returns the base URL from environment variable or default
-func getBaseURL() string {
-	baseURL := viper.GetString("BASE_URL")
-	if baseURL == "" {
-		baseURL = "https://k.nhn.no"
-	}
-	return baseURL
-}
-
 // @Summary	Get qr-code by id
 // @Schemes
 // @Description	gets qrcode by id
@@ -58,17 +50,17 @@ func GenerateQRCode(rdb *redis.Client) http.HandlerFunc {
 		id := params["id"]
 		rlog.Debug("GenerateQRCode", rlog.Any("id", id))
 
-		path, err := redisdb.GetURL(rdb, id)
-		shorturl := path
-		if len(path) != 0 {
-			shorturl = fmt.Sprintf("%s/%s", getBaseURL(), id)
-		}
+		// Aliases resolve to their canonical redirect so printed codes follow the canonical link
+		redirect, err := redisdb.GetRedirect(rdb, id)
 		if err != nil {
-			rlog.Info("GenerateQRCode - Error in GetURL", rlog.Any("client", r.Host), rlog.Any("path", id), rlog.Any("to", path))
+			rlog.Info("GenerateQRCode - Error in GetRedirect", rlog.Any("client", r.Host), rlog.Any("path", id), rlog.Any("to", redirect.URL))
 			http.Error(w, "Could not fetch URL from database", http.StatusInternalServerError)
 			return
 		}
-		rlog.Info("GenerateQRCode", rlog.Any("id", id), rlog.Any("path:", path))
+		// src=qr lets the redirect tag scans with utm_medium=qr
+		domain := middleware.GetDomain(r)
+		shorturl := fmt.Sprintf("%s/%s?src=%s", domain.BaseURL, domain.LocalKey(redirect.Path), qrSource)
+		rlog.Info("GenerateQRCode", rlog.Any("id", id), rlog.Any("path:", redirect.URL))
 
 		handleQrImageCreation(shorturl, w)
 	}
@@ -145,14 +137,20 @@ func handleQrImageCreation(input string, w http.ResponseWriter) {
 		standard.WithBuiltinImageEncoder(standard.PNG_FORMAT),
 	}
 
-	// Add the NHN logo for NHN domains
-	if strings.HasSuffix(u.Host, "nhn.no") {
+	// Add the logo configured for our own domains, and the NHN logo for NHN domains
+	logo := ""
+	if domain, ok := config.LookupDomain(u.Hostname()); ok && domain.QRLogo != "" {
+		logo = domain.QRLogo
+	} else if strings.HasSuffix(u.Host, "nhn.no") {
+		logo = media.ImageFile
+	}
+	if logo != "" {
 		// Verify logo file exists before trying to use it
-		if _, err := os.Stat(media.ImageFile); err == nil {
+		if _, err := os.Stat(logo); err == nil {
 			opts = append(opts, standard.WithLogoSizeMultiplier(2))
-			opts = append(opts, standard.WithLogoImageFilePNG(media.ImageFile))
+			opts = append(opts, standard.WithLogoImageFilePNG(logo))
 		} else {
-			rlog.Warn("NHN logo file not found, generating QR code without logo", rlog.Any("logoPath", media.ImageFile))
+			rlog.Warn("Logo file not found, generating QR code without logo", rlog.Any("logoPath", logo))
 		}
 	}
 
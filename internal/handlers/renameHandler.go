@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+// maxReserveDays caps how long an old key can be kept reserved after a rename
+const maxReserveDays = 365
+
+var (
+	RenameKey  = redisdb.RenameKey
+	IsReserved = redisdb.IsReserved
+)
+
+// Rename redirect
+//
+//	@Summary	Rename redirect
+//	@Schemes
+//	@Description	moves a redirect to a new key, keeping its owner, history, targets and aliases. The old key can stay as an alias, or be reserved for a number of days. Aliases cannot be renamed
+//	@Tags			v1
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string					true	"Id"
+//	@Param			query	body		models.RedirectRename	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		409		{string}	Conflict
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/rename [post]
+//	@Security		AccessToken
+func RenameRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var rename models.RedirectRename
+		if err := json.NewDecoder(r.Body).Decode(&rename); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if rename.Path == "" || strings.Contains(rename.Path, ":") {
+			http.Error(w, "Missing or invalid path", http.StatusBadRequest)
+			return
+		}
+		if rename.ReserveDays < 0 || rename.ReserveDays > maxReserveDays {
+			http.Error(w, "reserveDays must be between 0 and 365", http.StatusBadRequest)
+			return
+		}
+
+		// The new key stays on the domain of the request
+		domain := middleware.GetDomain(r)
+		reserveFor := time.Duration(rename.ReserveDays) * 24 * time.Hour
+		key, err := RenameKey(rdb, id, domain.Key(rename.Path), user, rename.KeepAlias, reserveFor)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.Response{
+			Success:  true,
+			Message:  "Path renamed successfully",
+			Path:     domain.LocalKey(key),
+			ShortURL: domain.BaseURL + "/" + domain.LocalKey(key),
+		}); err != nil {
+			rlog.Error("Failed to encode response", err)
+		}
+	}
+}
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+func TestRenameRedirect(t *testing.T) {
+	original := RenameKey
+	t.Cleanup(func() { RenameKey = original })
+
+	var gotReserve time.Duration
+	RenameKey = func(rdb *redis.Client, key string, newKey string, user string, keepAlias bool, reserveFor time.Duration) (string, error) {
+		gotReserve = reserveFor
+		if newKey == "taken" {
+			return "", fmt.Errorf("%w: `%s`", redisdb.ErrKeyExists, newKey)
+		}
+		return newKey, nil
+	}
+
+	tests := []struct {
+		name        string
+		body        string
+		isOwner     bool
+		wantStatus  int
+		wantReserve time.Duration
+	}{
+		{name: "Owner renames", body: `{"path":"fresh","reserveDays":2}`, isOwner: true, wantStatus: http.StatusOK, wantReserve: 48 * time.Hour},
+		{name: "New key taken", body: `{"path":"taken"}`, isOwner: true, wantStatus: http.StatusConflict},
+		{name: "Namespace separator", body: `{"path":"hr:fresh"}`, isOwner: true, wantStatus: http.StatusBadRequest},
+		{name: "Reserve period too long", body: `{"path":"fresh","reserveDays":1000}`, isOwner: true, wantStatus: http.StatusBadRequest},
+		{name: "Not owner", body: `{"path":"fresh"}`, isOwner: false, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotReserve = 0
+			req := httptest.NewRequest(http.MethodPost, "/v1/old/rename", bytes.NewBufferString(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"id": "old"})
+			ctx := context.WithValue(req.Context(), middleware.IsOwnerKey, tc.isOwner)
+			ctx = context.WithValue(ctx, middleware.UserKey, "owner@example.com")
+
+			rr := httptest.NewRecorder()
+			RenameRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if gotReserve != tc.wantReserve {
+				t.Errorf("expected reserve period %v, got %v", tc.wantReserve, gotReserve)
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			var resp models.Response
+			if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+				t.Fatalf("failed to decode response: %v", err)
+			}
+			if resp.Path != "fresh" {
+				t.Errorf("expected new path in response, got %q", resp.Path)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// defaultStaleDays is how long a link must have been idle to be reported when no days are given
+	defaultStaleDays = 180
+	maxStaleDays     = 3650
+	// maxBulkLinks limits the number of links in one bulk action
+	maxBulkLinks = 500
+)
+
+// Bulk actions on links
+const (
+	actionConfirm = "confirm"
+	actionDelete  = "delete"
+	actionArchive = "archive"
+)
+
+var (
+	FindStaleLinks    = redisdb.FindStaleLinks
+	ConfirmLink       = redisdb.ConfirmLink
+	FindOrphanedLinks = redisdb.FindOrphanedLinks
+	ArchiveLink       = redisdb.ArchiveLink
+)
+
+// parseStaleDays reads the days query parameter, how long links must have been idle
+func parseStaleDays(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("days")
+	if value == "" {
+		return defaultStaleDays, true
+	}
+	days, err := strconv.Atoi(value)
+	if err != nil || days < 1 || days > maxStaleDays {
+		return 0, false
+	}
+	return days, true
+}
+
+// localStaleLinks keeps the links of the requested domain, with their keys as seen on that domain
+func localStaleLinks(r *http.Request, links []models.StaleLink) []models.StaleLink {
+	domain := middleware.GetDomain(r)
+	local := []models.StaleLink{}
+	for _, link := range links {
+		if domain.Owns(link.Path) {
+			link.Path = domain.LocalKey(link.Path)
+			local = append(local, link)
+		}
+	}
+	return local
+}
+
+// Get stale links
+//
+//	@Summary	Get stale links
+//	@Schemes
+//	@Description	lists links without clicks from people for at least the given number of days (default 180), counting from when they were created, last clicked or last confirmed as in use. Owners get their own links; admins get all links, or those of one owner. Longest idle first
+//	@Tags			v1 reports
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			days	query		int		false	"Minimum idle days, 1-3650"
+//	@Param			owner	query		string	false	"Owner, admins only"
+//	@Success		200		{array}		models.StaleLink
+//	@Failure		400		{string}	Bad	request
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/reports/stale [get]
+//	@Security		AccessToken
+func GetStaleLinksRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		days, ok := parseStaleDays(r)
+		if !ok {
+			http.Error(w, "days must be a number between 1 and 3650", http.StatusBadRequest)
+			return
+		}
+
+		owner, _ := r.Context().Value(middleware.UserKey).(string)
+		if isAdminOfDomain(r) {
+			owner = r.URL.Query().Get("owner")
+		}
+
+		links, err := FindStaleLinks(rdb, days, owner)
+		if err != nil {
+			rlog.Error("Failed to find stale links", err)
+			http.Error(w, "Failed to find stale links", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(localStaleLinks(r, links)); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Confirm or delete stale links
+//
+//	@Summary	Confirm or delete stale links
+//	@Schemes
+//	@Description	keeps stale links, which resets their idle time, and deletes others, in bulk. Only the owner of a link or an admin can act on it. Returns the outcome per link
+//	@Tags			v1 reports
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			query	body		models.StaleLinkActions	true	"Links to confirm and to delete"
+//	@Success		200		{array}		models.BulkResult
+//	@Failure		400		{string}	Bad	request
+//	@Failure		401		{string}	Unauthorized
+//	@Router			/v1/reports/stale [post]
+//	@Security		AccessToken
+func UpdateStaleLinksRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var actions models.StaleLinkActions
+		if err := json.NewDecoder(r.Body).Decode(&actions); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if len(actions.Confirm)+len(actions.Delete) > maxBulkLinks {
+			http.Error(w, "Too many links, at most 500 per request", http.StatusBadRequest)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+		domain := middleware.GetDomain(r)
+		isAdmin := isAdminOfDomain(r)
+
+		act := func(key string, action string) models.BulkResult {
+			result := models.BulkResult{Path: key, Action: action}
+			// Keys of other domains are not reachable through this one, as in NamespaceMiddleware
+			if strings.Contains(key, ":") {
+				result.Error = "Not found"
+				return result
+			}
+			id := domain.Key(key)
+			redirect, err := GetRedirect(rdb, id)
+			if err == nil && redirect.Owner != user && !isAdmin {
+				result.Error = "Forbidden: You must be an admin or the owner of this resource"
+				return result
+			}
+			if err == nil && action == actionConfirm {
+				err = ConfirmLink(rdb, redirect.Path, user)
+			} else if err == nil {
+				// Deleting an alias only removes the alias, not the link it points at
+				var alias bool
+				if alias, err = IsAlias(rdb, id); err == nil && alias {
+					err = RemoveAlias(rdb, redirect.Path, id, user)
+				} else if err == nil {
+					_, err = Delete(rdb, redirect.Path)
+				}
+			}
+			if err != nil {
+				rlog.Error("Bulk action on stale link failed", err, rlog.String("key", key), rlog.String("action", action))
+				result.Error = err.Error()
+			}
+			return result
+		}
+
+		results := []models.BulkResult{}
+		for _, key := range actions.Confirm {
+			results = append(results, act(key, actionConfirm))
+		}
+		for _, key := range actions.Delete {
+			results = append(results, act(key, actionDelete))
+		}
+		rlog.Info("Stale links handled", rlog.String("user", user),
+			rlog.Int("confirmed", len(actions.Confirm)), rlog.Int("deleted", len(actions.Delete)))
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Get orphaned links
+//
+//	@Summary	Get orphaned links
+//	@Schemes
+//	@Description	lists links whose owner no longer exists: owners who are not admin users and have not signed in for USER_INACTIVE_DAYS, admin only
+//	@Tags			v1 reports
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{array}		models.StaleLink
+//	@Failure		403	{string}	Forbidden
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/reports/orphans [get]
+//	@Security		AccessToken
+func GetOrphanedLinksRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminOfDomain(r) {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		links, err := FindOrphanedLinks(rdb)
+		if err != nil {
+			rlog.Error("Failed to find orphaned links", err)
+			http.Error(w, "Failed to find orphaned links", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(localStaleLinks(r, links)); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Archive orphaned links
+//
+//	@Summary	Archive orphaned links
+//	@Schemes
+//	@Description	archives links whose owner no longer exists, admin only. Archived links stop resolving, and their aliases and statistics are removed. Links whose owner still exists are left alone. Returns the outcome per link
+//	@Tags			v1 reports
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			query	body		models.LinkArchive	true	"Links to archive"
+//	@Success		200		{array}		models.BulkResult
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/reports/orphans/archive [post]
+//	@Security		AccessToken
+func ArchiveOrphanedLinksRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !isAdminOfDomain(r) {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		var archive models.LinkArchive
+		if err := json.NewDecoder(r.Body).Decode(&archive); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if len(archive.Paths) > maxBulkLinks {
+			http.Error(w, "Too many links, at most 500 per request", http.StatusBadRequest)
+			return
+		}
+
+		orphaned, err := FindOrphanedLinks(rdb)
+		if err != nil {
+			rlog.Error("Failed to find orphaned links", err)
+			http.Error(w, "Failed to find orphaned links", http.StatusInternalServerError)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+		domain := middleware.GetDomain(r)
+		results := []models.BulkResult{}
+		for _, key := range archive.Paths {
+			result := models.BulkResult{Path: key, Action: actionArchive}
+			stored := domain.Key(key)
+			isOrphaned := slices.ContainsFunc(orphaned, func(link models.StaleLink) bool { return link.Path == stored })
+			if !isOrphaned {
+				result.Error = "Not an orphaned link"
+			} else if err := ArchiveLink(rdb, stored, user); err != nil {
+				result.Error = err.Error()
+			}
+			results = append(results, result)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(results); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
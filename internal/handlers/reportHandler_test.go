@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestGetStaleLinksRedirect(t *testing.T) {
+	original := FindStaleLinks
+	t.Cleanup(func() { FindStaleLinks = original })
+
+	var gotDays int
+	var gotOwner string
+	FindStaleLinks = func(rdb *redis.Client, days int, owner string) ([]models.StaleLink, error) {
+		gotDays, gotOwner = days, owner
+		return []models.StaleLink{{Path: "onboarding", Owner: "owner@example.com", IdleDays: 200}}, nil
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		isAdmin    bool
+		wantStatus int
+		wantDays   int
+		wantOwner  string
+	}{
+		{name: "Own links by default", query: "", wantStatus: http.StatusOK, wantDays: defaultStaleDays, wantOwner: "user@example.com"},
+		{name: "Owner filter ignored for users", query: "?days=30&owner=other@example.com", wantStatus: http.StatusOK, wantDays: 30, wantOwner: "user@example.com"},
+		{name: "Admin sees all links", query: "?days=30", isAdmin: true, wantStatus: http.StatusOK, wantDays: 30, wantOwner: ""},
+		{name: "Admin filters on owner", query: "?owner=other@example.com", isAdmin: true, wantStatus: http.StatusOK, wantDays: defaultStaleDays, wantOwner: "other@example.com"},
+		{name: "Invalid days", query: "?days=never", wantStatus: http.StatusBadRequest},
+		{name: "Too many days", query: "?days=5000", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			gotDays, gotOwner = 0, "-"
+			req := httptest.NewRequest(http.MethodGet, "/v1/reports/stale"+tc.query, nil)
+			ctx := context.WithValue(req.Context(), middleware.UserKey, "user@example.com")
+			ctx = context.WithValue(ctx, middleware.IsAdminKey, tc.isAdmin)
+
+			rr := httptest.NewRecorder()
+			GetStaleLinksRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+			if gotDays != tc.wantDays || gotOwner != tc.wantOwner {
+				t.Errorf("expected days %d and owner %q, got %d and %q", tc.wantDays, tc.wantOwner, gotDays, gotOwner)
+			}
+			if !strings.Contains(rr.Body.String(), `"idleDays":200`) {
+				t.Errorf("expected stale links in body, got %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestUpdateStaleLinksRedirect(t *testing.T) {
+	originalGet, originalConfirm, originalDelete := GetRedirect, ConfirmLink, Delete
+	originalIsAlias, originalRemoveAlias := IsAlias, RemoveAlias
+	t.Cleanup(func() {
+		GetRedirect, ConfirmLink, Delete = originalGet, originalConfirm, originalDelete
+		IsAlias, RemoveAlias = originalIsAlias, originalRemoveAlias
+	})
+
+	GetRedirect = func(rdb *redis.Client, key string) (models.RedirectPath, error) {
+		switch key {
+		case "mine", "onboard", "go:mine":
+			return models.RedirectPath{Path: "mine", Owner: "user@example.com"}, nil
+		case "theirs":
+			return models.RedirectPath{Path: "theirs", Owner: "other@example.com"}, nil
+		}
+		return models.RedirectPath{}, redisdb.ErrURLNotFound
+	}
+
+	IsAlias = func(rdb *redis.Client, key string) (bool, error) {
+		return key == "onboard", nil
+	}
+
+	var confirmed, deleted, removedAliases []string
+	RemoveAlias = func(rdb *redis.Client, key string, alias string, user string) error {
+		removedAliases = append(removedAliases, key+">"+alias)
+		return nil
+	}
+	ConfirmLink = func(rdb *redis.Client, key string, user string) error {
+		confirmed = append(confirmed, key)
+		return nil
+	}
+	Delete = func(rdb *redis.Client, key string) (bool, error) {
+		deleted = append(deleted, key)
+		return true, nil
+	}
+
+	tests := []struct {
+		name          string
+		body          string
+		isAdmin       bool
+		wantStatus    int
+		wantErrors    int
+		wantConfirmed []string
+		wantDeleted   []string
+		wantRemoved   []string
+	}{
+		{name: "Owner confirms and deletes", body: `{"confirm":["onboard"],"delete":["mine"]}`, wantStatus: http.StatusOK,
+			wantConfirmed: []string{"mine"}, wantDeleted: []string{"mine"}},
+		{name: "Links of others are refused", body: `{"delete":["theirs","missing"]}`, wantStatus: http.StatusOK, wantErrors: 2},
+		{name: "Deleting an alias keeps its link", body: `{"delete":["onboard"]}`, wantStatus: http.StatusOK, wantRemoved: []string{"mine>onboard"}},
+		{name: "Keys of other domains are refused", body: `{"confirm":["go:mine"],"delete":["go:mine"]}`, isAdmin: true, wantStatus: http.StatusOK, wantErrors: 2},
+		{name: "Admin acts on any link", body: `{"confirm":["theirs"]}`, isAdmin: true, wantStatus: http.StatusOK, wantConfirmed: []string{"theirs"}},
+		{name: "Invalid body", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "Too many links", body: `{"confirm":[` + strings.Repeat(`"a",`, maxBulkLinks) + `"a"]}`, wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			confirmed, deleted, removedAliases = nil, nil, nil
+			req := httptest.NewRequest(http.MethodPost, "/v1/reports/stale", strings.NewReader(tc.body))
+			ctx := context.WithValue(req.Context(), middleware.UserKey, "user@example.com")
+			ctx = context.WithValue(ctx, middleware.IsAdminKey, tc.isAdmin)
+
+			rr := httptest.NewRecorder()
+			UpdateStaleLinksRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tc.wantStatus != http.StatusOK {
+				return
+			}
+
+			var results []models.BulkResult
+			if err := json.NewDecoder(rr.Body).Decode(&results); err != nil {
+				t.Fatalf("failed to decode results: %v", err)
+			}
+			failed := 0
+			for _, result := range results {
+				if result.Error != "" {
+					failed++
+				}
+			}
+			if failed != tc.wantErrors {
+				t.Errorf("expected %d failed links, got %+v", tc.wantErrors, results)
+			}
+			if strings.Join(confirmed, ",") != strings.Join(tc.wantConfirmed, ",") || strings.Join(deleted, ",") != strings.Join(tc.wantDeleted, ",") {
+				t.Errorf("expected confirmed %v and deleted %v, got %v and %v", tc.wantConfirmed, tc.wantDeleted, confirmed, deleted)
+			}
+			if strings.Join(removedAliases, ",") != strings.Join(tc.wantRemoved, ",") {
+				t.Errorf("expected removed aliases %v, got %v", tc.wantRemoved, removedAliases)
+			}
+		})
+	}
+}
+
+func TestArchiveOrphanedLinksRedirect(t *testing.T) {
+	originalFind, originalArchive := FindOrphanedLinks, ArchiveLink
+	t.Cleanup(func() { FindOrphanedLinks, ArchiveLink = originalFind, originalArchive })
+
+	var findErr error
+	FindOrphanedLinks = func(rdb *redis.Client) ([]models.StaleLink, error) {
+		return []models.StaleLink{{Path: "gone", Owner: "left@example.com"}}, findErr
+	}
+	var archived []string
+	ArchiveLink = func(rdb *redis.Client, key string, user string) error {
+		archived = append(archived, key)
+		return nil
+	}
+
+	tests := []struct {
+		name         string
+		isAdmin      bool
+		err          error
+		wantStatus   int
+		wantArchived string
+	}{
+		{name: "Only orphaned links are archived", isAdmin: true, wantStatus: http.StatusOK, wantArchived: "gone"},
+		{name: "Lookup failure", isAdmin: true, err: errors.New("redis down"), wantStatus: http.StatusInternalServerError},
+		{name: "Not admin", wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			findErr, archived = tc.err, nil
+			req := httptest.NewRequest(http.MethodPost, "/v1/reports/orphans/archive", strings.NewReader(`{"paths":["gone","active"]}`))
+			ctx := context.WithValue(req.Context(), middleware.UserKey, "admin@example.com")
+			ctx = context.WithValue(ctx, middleware.IsAdminKey, tc.isAdmin)
+
+			rr := httptest.NewRecorder()
+			ArchiveOrphanedLinksRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if strings.Join(archived, ",") != tc.wantArchived {
+				t.Errorf("expected %q archived, got %v", tc.wantArchived, archived)
+			}
+		})
+	}
+}
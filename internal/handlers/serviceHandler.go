@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+var (
+	GetServiceAccounts   = redisdb.GetServiceAccounts
+	SetServiceAccount    = redisdb.SetServiceAccount
+	DeleteServiceAccount = redisdb.DeleteServiceAccount
+	SetServiceOwner      = redisdb.SetServiceOwner
+)
+
+// Get service accounts
+//
+//	@Summary	Get service accounts
+//	@Schemes
+//	@Description	lists all service accounts, admin only
+//	@Tags			v1 services
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{array}		models.ServiceAccount
+//	@Failure		403	{string}	Forbidden
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/admin/services [get]
+//	@Security		AccessToken
+func GetServiceAccountsRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		accounts, err := GetServiceAccounts(rdb)
+		if err != nil {
+			rlog.Error("Failed to get service accounts", err)
+			http.Error(w, "Failed to get service accounts", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(accounts); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Set service account
+//
+//	@Summary	Set service account
+//	@Schemes
+//	@Description	registers or replaces a service account, recognised from the client id (client_id or azp claim) or the subject of its tokens. Scopes are read, write and admin; dailyLinkLimit defaults to 100. Admin only
+//	@Tags			v1 services
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			name	path		string					true	"Name"
+//	@Param			query	body		models.ServiceAccount	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/admin/services/{name} [put]
+//	@Security		AccessToken
+func SetServiceAccountRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		var account models.ServiceAccount
+		if err := json.NewDecoder(r.Body).Decode(&account); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+		account.Name = mux.Vars(r)["name"]
+
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+		if err := SetServiceAccount(rdb, account, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Service account updated successfully")
+	}
+}
+
+// Delete service account
+//
+//	@Summary	Delete service account
+//	@Schemes
+//	@Description	deletes a service account, so its tokens are no longer accepted. Its links are kept. Admin only
+//	@Tags			v1 services
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			name	path		string	true	"Name"
+//	@Success		200		{object}	models.Response
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/admin/services/{name} [delete]
+//	@Security		AccessToken
+func DeleteServiceAccountRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+		if err := DeleteServiceAccount(rdb, mux.Vars(r)["name"], user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Service account deleted successfully")
+	}
+}
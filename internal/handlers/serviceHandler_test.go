@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+func TestSetServiceAccountRedirect(t *testing.T) {
+	original := SetServiceAccount
+	t.Cleanup(func() { SetServiceAccount = original })
+
+	var got models.ServiceAccount
+	SetServiceAccount = func(rdb *redis.Client, account models.ServiceAccount, user string) error {
+		got = account
+		if account.Owner == "" {
+			return redisdb.ErrInvalidServiceAccount
+		}
+		return nil
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		isAdmin    bool
+		wantStatus int
+	}{
+		{name: "Register service account", body: `{"name":"ignored","owner":"team@example.com","clientId":"ci-client","scopes":["write"],"dailyLinkLimit":50}`, isAdmin: true, wantStatus: http.StatusOK},
+		{name: "Invalid service account", body: `{"clientId":"ci-client"}`, isAdmin: true, wantStatus: http.StatusBadRequest},
+		{name: "Invalid body", body: `{`, isAdmin: true, wantStatus: http.StatusBadRequest},
+		{name: "Not admin", body: `{}`, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got = models.ServiceAccount{}
+			req := httptest.NewRequest(http.MethodPut, "/v1/admin/services/ci", strings.NewReader(tc.body))
+			req = mux.SetURLVars(req, map[string]string{"name": "ci"})
+			ctx := context.WithValue(req.Context(), middleware.IsAdminKey, tc.isAdmin)
+
+			rr := httptest.NewRecorder()
+			SetServiceAccountRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tc.wantStatus == http.StatusOK && (got.Name != "ci" || got.DailyLinkLimit != 50) {
+				t.Errorf("expected the account named from the path, got %+v", got)
+			}
+		})
+	}
+}
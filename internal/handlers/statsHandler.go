@@ -0,0 +1,225 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/events"
+	"github.com/NorskHelsenett/shorty/internal/geoip"
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/mileusna/useragent"
+)
+
+const (
+	// defaultStatsDays is the period returned when no from date is given
+	defaultStatsDays = 30
+	// defaultBreakdownTop and maxBreakdownTop limit the number of values returned per click dimension
+	defaultBreakdownTop = 10
+	maxBreakdownTop     = 50
+)
+
+// Values of click dimensions used when the real value is unknown
+const (
+	referrerDirect = "direct"
+	unknownValue   = "unknown"
+	otherValue     = "other"
+)
+
+var (
+	RecordClick    = redisdb.RecordClick
+	RecordNotFound = redisdb.RecordNotFound
+	GetStats       = redisdb.GetStats
+	VisitorID      = redisdb.VisitorID
+)
+
+// Clicks queues clicks for the background workers. Without a queue clicks are written during the redirect.
+var Clicks *events.Queue
+
+// recordClick counts a redirect to target in the statistics of a link. Failures are logged and never stop the redirect.
+func recordClick(rdb *redis.Client, r *http.Request, key string, target string) {
+	click := newClick(rdb, r)
+	click.Target = target
+	if Clicks != nil {
+		Clicks.Enqueue(models.ClickEvent{Key: key, Click: click})
+		return
+	}
+	if err := RecordClick(rdb, key, click); err != nil {
+		rlog.Error("Failed to record click", err, rlog.String("key", key))
+	}
+}
+
+// recordNotFound counts a request from a person for a key without a link. Failures are logged and never stop the redirect.
+func recordNotFound(rdb *redis.Client, r *http.Request, key string) {
+	if middleware.BotReason(r) != "" || key == "" {
+		return
+	}
+	if Clicks != nil {
+		Clicks.Enqueue(models.ClickEvent{Key: key, NotFound: true})
+		return
+	}
+	if err := RecordNotFound(rdb, key); err != nil {
+		rlog.Error("Failed to record not found key", err, rlog.String("key", key))
+	}
+}
+
+// newClick describes a redirect request for the statistics: an anonymous visitor id for the
+// unique visitor estimate, and the referrer domain, device class, browser and country.
+// The client address is only used for the visitor id and the country lookup.
+// Requests from bots are only marked as such.
+func newClick(rdb *redis.Client, r *http.Request) models.Click {
+	if bot := middleware.BotReason(r); bot != "" {
+		return models.Click{Time: time.Now(), Bot: bot}
+	}
+
+	ip := middleware.ClientIP(r)
+	ua := useragent.Parse(r.UserAgent())
+	click := models.Click{
+		Time:     time.Now(),
+		Referrer: referrerDomain(r),
+		Device:   clickDevice(ua),
+		Browser:  otherValue,
+		Country:  geoip.Country(ip),
+	}
+	if ua.Name != "" {
+		click.Browser = ua.Name
+	}
+	if click.Country == "" {
+		click.Country = unknownValue
+	}
+
+	visitor, err := VisitorID(rdb, ip, r.UserAgent(), click.Time)
+	if err != nil {
+		// Count the click even when the visitor cannot be identified
+		rlog.Error("Failed to create visitor id", err)
+	}
+	click.Visitor = visitor
+	return click
+}
+
+// referrerDomain returns the host name of the page the click came from. QR code scans
+// count as "qr", and clicks without a referrer, such as from e-mail or apps, as "direct".
+func referrerDomain(r *http.Request) string {
+	if u, err := url.Parse(r.Referer()); err == nil && u.Hostname() != "" {
+		return strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	}
+	if r.URL.Query().Get("src") == qrSource {
+		return qrSource
+	}
+	return referrerDirect
+}
+
+// clickDevice returns the coarse device class of a client: mobile, tablet, desktop or other
+func clickDevice(ua useragent.UserAgent) string {
+	switch {
+	case ua.Tablet:
+		return "tablet"
+	case ua.Mobile:
+		return "mobile"
+	case ua.Desktop:
+		return "desktop"
+	default:
+		return otherValue
+	}
+}
+
+// parseBreakdownTop reads the top query parameter, the number of values returned per click dimension
+func parseBreakdownTop(r *http.Request) (int, bool) {
+	value := r.URL.Query().Get("top")
+	if value == "" {
+		return defaultBreakdownTop, true
+	}
+	top, err := strconv.Atoi(value)
+	if err != nil || top < 0 || top > maxBreakdownTop {
+		return 0, false
+	}
+	return top, true
+}
+
+// parseStatsPeriod reads the from and to query parameters (YYYY-MM-DD). The period
+// defaults to the last 30 days up to today.
+func parseStatsPeriod(r *http.Request) (time.Time, time.Time, bool) {
+	to := time.Now().UTC()
+	if value := r.URL.Query().Get("to"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, -(defaultStatsDays - 1))
+	if value := r.URL.Query().Get("from"); value != "" {
+		parsed, err := time.Parse(time.DateOnly, value)
+		if err != nil {
+			return time.Time{}, time.Time{}, false
+		}
+		from = parsed
+	}
+	return from, to, true
+}
+
+// Get statistics
+//
+//	@Summary	Get statistics
+//	@Schemes
+//	@Description	gets the daily clicks and estimated unique visitors of a redirect for a period (UTC days, at most 366). Defaults to the last 30 days. Visitors are counted once per day. Breakdowns by referrer, device, browser, country and target cover all clicks. Requests from bots, link previewers and scanners are counted separately
+//	@Tags			v1 stats
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string	true	"Id"
+//	@Param			from	query		string	false	"First day, YYYY-MM-DD"
+//	@Param			to		query		string	false	"Last day, YYYY-MM-DD"
+//	@Param			top		query		int		false	"Values per referrer, device, browser, country and target breakdown, default 10"
+//	@Success		200		{object}	models.LinkStats
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/stats [get]
+//	@Security		AccessToken
+func GetStatsRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+
+		from, to, ok := parseStatsPeriod(r)
+		if !ok {
+			http.Error(w, "Invalid date, use YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		top, ok := parseBreakdownTop(r)
+		if !ok {
+			http.Error(w, "top must be a number between 0 and 50", http.StatusBadRequest)
+			return
+		}
+
+		stats, err := GetStats(rdb, id, from, to, top)
+		if err != nil {
+			rlog.Error("Failed to get statistics", err, rlog.String("id", id))
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		stats.Path = middleware.GetDomain(r).LocalKey(stats.Path)
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(stats); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
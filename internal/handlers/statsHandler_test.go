@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+	"github.com/mileusna/useragent"
+)
+
+func TestGetStatsRedirect(t *testing.T) {
+	original := GetStats
+	t.Cleanup(func() { GetStats = original })
+
+	var gotFrom, gotTo time.Time
+	GetStats = func(rdb *redis.Client, key string, from time.Time, to time.Time, top int) (models.LinkStats, error) {
+		gotFrom, gotTo = from, to
+		return models.LinkStats{Path: key}, nil
+	}
+
+	tests := []struct {
+		name       string
+		query      string
+		isOwner    bool
+		wantStatus int
+		wantFrom   string
+		wantTo     string
+	}{
+		{name: "Owner with period", query: "?from=2025-01-01&to=2025-01-31", isOwner: true, wantStatus: http.StatusOK, wantFrom: "2025-01-01", wantTo: "2025-01-31"},
+		{name: "Default period ends on to", query: "?to=2025-01-31", isOwner: true, wantStatus: http.StatusOK, wantFrom: "2025-01-02", wantTo: "2025-01-31"},
+		{name: "Invalid date", query: "?from=01.01.2025", isOwner: true, wantStatus: http.StatusBadRequest},
+		{name: "Invalid top", query: "?top=500", isOwner: true, wantStatus: http.StatusBadRequest},
+		{name: "Not owner", query: "", isOwner: false, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/v1/onboarding/stats"+tc.query, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": "onboarding"})
+			ctx := context.WithValue(req.Context(), middleware.IsOwnerKey, tc.isOwner)
+
+			rr := httptest.NewRecorder()
+			GetStatsRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rr.Code)
+			}
+			if tc.wantFrom == "" {
+				return
+			}
+			if got := gotFrom.Format(time.DateOnly); got != tc.wantFrom {
+				t.Errorf("expected from %s, got %s", tc.wantFrom, got)
+			}
+			if got := gotTo.Format(time.DateOnly); got != tc.wantTo {
+				t.Errorf("expected to %s, got %s", tc.wantTo, got)
+			}
+		})
+	}
+}
+
+func TestReferrerDomain(t *testing.T) {
+	tests := []struct {
+		name    string
+		target  string
+		referer string
+		want    string
+	}{
+		{name: "Referring page", target: "/ab", referer: "https://www.Intranett.nhn.no/nyheter", want: "intranett.nhn.no"},
+		{name: "QR code scan", target: "/ab?src=qr", want: "qr"},
+		{name: "No referrer", target: "/ab", want: "direct"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.referer != "" {
+				req.Header.Set("Referer", tc.referer)
+			}
+			if got := referrerDomain(req); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestClickDevice(t *testing.T) {
+	tests := []struct {
+		userAgent string
+		want      string
+	}{
+		{userAgent: "Mozilla/5.0 (iPhone; CPU iPhone OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", want: "mobile"},
+		{userAgent: "Mozilla/5.0 (iPad; CPU OS 17_0 like Mac OS X) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/17.0 Mobile/15E148 Safari/604.1", want: "tablet"},
+		{userAgent: "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/120.0.0.0 Safari/537.36", want: "desktop"},
+		{userAgent: "", want: "other"},
+	}
+
+	for _, tc := range tests {
+		if got := clickDevice(useragent.Parse(tc.userAgent)); got != tc.want {
+			t.Errorf("clickDevice(%q) = %q, want %q", tc.userAgent, got, tc.want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+// defaultPausedMessage is shown on the maintenance page when a paused redirect has no message
+const defaultPausedMessage = "This link is temporarily unavailable."
+
+var pausedPage = template.Must(template.New("paused").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Link unavailable</title>
+</head>
+<body style="font-family: sans-serif; max-width: 40em; margin: 4em auto; padding: 0 1em;">
+<h1>Link unavailable</h1>
+<p>{{.Message}}</p>
+</body>
+</html>
+`))
+
+var (
+	GetStatus = redisdb.GetStatus
+	SetStatus = redisdb.SetStatus
+)
+
+// servePaused answers a request for a paused redirect, either by redirecting to the
+// alternate target or by rendering the maintenance page.
+func servePaused(w http.ResponseWriter, r *http.Request, status models.RedirectStatus) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if status.Target != "" {
+		http.Redirect(w, r, status.Target, http.StatusFound)
+		return
+	}
+
+	message := status.Message
+	if message == "" {
+		message = defaultPausedMessage
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusServiceUnavailable)
+	if err := pausedPage.Execute(w, struct{ Message string }{message}); err != nil {
+		rlog.Error("Failed to render maintenance page", err)
+	}
+}
+
+// Get status
+//
+//	@Summary	Get status
+//	@Schemes
+//	@Description	gets whether a redirect is enabled, and the message and alternate target used while it is paused
+//	@Tags			v1 status
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Id"
+//	@Success		200	{object}	models.RedirectStatus
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/{id}/status [get]
+//	@Security		AccessToken
+func GetStatusRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		status, err := GetStatus(rdb, id)
+		if err != nil {
+			rlog.Error("Failed to get status", err, rlog.String("id", id))
+			http.Error(w, "Failed to get status", errorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(status); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Set status
+//
+//	@Summary	Set status
+//	@Schemes
+//	@Description	enables or pauses a redirect without deleting it. A paused redirect serves the alternate target if set, otherwise a maintenance page with the message
+//	@Tags			v1 status
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string					true	"Id"
+//	@Param			query	body		models.RedirectStatus	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/status [put]
+//	@Security		AccessToken
+func SetStatusRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var status models.RedirectStatus
+		if err := json.NewDecoder(r.Body).Decode(&status); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if !status.Enabled && status.Target != "" && !IsURL(status.Target) {
+			http.Error(w, "Invalid URL format", http.StatusBadRequest)
+			return
+		}
+
+		if err := SetStatus(rdb, id, status, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		if status.Enabled {
+			writeResponse(w, http.StatusOK, "Redirect enabled")
+			return
+		}
+		writeResponse(w, http.StatusOK, "Redirect paused")
+	}
+}
@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+)
+
+func TestServePaused(t *testing.T) {
+	t.Run("Alternate target", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		servePaused(rr, httptest.NewRequest(http.MethodGet, "/ab", nil), models.RedirectStatus{Target: "https://status.example.com"})
+
+		if rr.Code != http.StatusFound {
+			t.Fatalf("expected status %d, got %d", http.StatusFound, rr.Code)
+		}
+		if got := rr.Header().Get("Location"); got != "https://status.example.com" {
+			t.Errorf("expected alternate target, got %q", got)
+		}
+	})
+
+	t.Run("Maintenance page escapes message", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		servePaused(rr, httptest.NewRequest(http.MethodGet, "/ab", nil), models.RedirectStatus{Message: "<b>Under review</b>"})
+
+		if rr.Code != http.StatusServiceUnavailable {
+			t.Fatalf("expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+		}
+		if body := rr.Body.String(); !strings.Contains(body, "&lt;b&gt;Under review&lt;/b&gt;") {
+			t.Errorf("expected escaped message in page, got %q", body)
+		}
+	})
+
+	t.Run("Default message", func(t *testing.T) {
+		rr := httptest.NewRecorder()
+		servePaused(rr, httptest.NewRequest(http.MethodGet, "/ab", nil), models.RedirectStatus{})
+
+		if !strings.Contains(rr.Body.String(), defaultPausedMessage) {
+			t.Errorf("expected default message in page, got %q", rr.Body.String())
+		}
+	})
+}
@@ -0,0 +1,313 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"math/rand/v2"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+// stickyCookieMaxAge is how long a client stays on the same target in sticky mode
+const stickyCookieMaxAge = 30 * 24 * time.Hour
+
+var (
+	GetTargets         = redisdb.GetTargets
+	SetTargets         = redisdb.SetTargets
+	AddTarget          = redisdb.AddTarget
+	UpdateTargetWeight = redisdb.UpdateTargetWeight
+	RemoveTarget       = redisdb.RemoveTarget
+)
+
+// selectTarget picks the destination for a redirect with weighted targets.
+// Redirects without targets (or where every weight is zero) use the primary URL.
+func selectTarget(w http.ResponseWriter, r *http.Request, redirect models.RedirectPath) string {
+	total := 0
+	for _, target := range redirect.Targets {
+		total += target.Weight
+	}
+	if total == 0 {
+		return redirect.URL
+	}
+
+	// Namespaced keys contain a colon, which is not allowed in cookie names
+	cookieName := "shorty_target_" + strings.ReplaceAll(redirect.Path, ":", ".")
+	if redirect.TargetMode == models.TargetModeSticky {
+		if cookie, err := r.Cookie(cookieName); err == nil {
+			for _, target := range redirect.Targets {
+				if target.Weight > 0 && targetID(target.URL) == cookie.Value {
+					return target.URL
+				}
+			}
+		}
+	}
+
+	chosen := redirect.URL
+	pick := rand.IntN(total)
+	for _, target := range redirect.Targets {
+		if pick < target.Weight {
+			chosen = target.URL
+			break
+		}
+		pick -= target.Weight
+	}
+
+	// The cookie is scoped to the path the visitor requested, which differs from the stored key for aliases
+	if redirect.TargetMode == models.TargetModeSticky {
+		http.SetCookie(w, &http.Cookie{
+			Name:     cookieName,
+			Value:    targetID(chosen),
+			Path:     r.URL.Path,
+			MaxAge:   int(stickyCookieMaxAge.Seconds()),
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		})
+	}
+
+	return chosen
+}
+
+// targetID returns a short stable identifier for a target URL, used in sticky cookies
+func targetID(targetURL string) string {
+	sum := sha256.Sum256([]byte(targetURL))
+	return hex.EncodeToString(sum[:6])
+}
+
+// Get targets
+//
+//	@Summary	Get targets
+//	@Schemes
+//	@Description	gets the weighted targets of a redirect
+//	@Tags			v1 targets
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Id"
+//	@Success		200	{object}	models.RedirectTargets
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/{id}/targets [get]
+//	@Security		AccessToken
+func GetTargetsRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		targets, err := GetTargets(rdb, id)
+		if err != nil {
+			rlog.Error("Failed to get targets", err, rlog.String("id", id))
+			http.Error(w, "Failed to get targets", errorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(targets); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Replace targets
+//
+//	@Summary	Replace targets
+//	@Schemes
+//	@Description	replaces all weighted targets and the selection mode (random or sticky) of a redirect
+//	@Tags			v1 targets
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string					true	"Id"
+//	@Param			query	body		models.RedirectTargets	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		409		{string}	Conflict
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/targets [put]
+//	@Security		AccessToken
+func SetTargetsRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var targets models.RedirectTargets
+		if err := json.NewDecoder(r.Body).Decode(&targets); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		for _, target := range targets.Targets {
+			if !IsURL(target.URL) {
+				http.Error(w, "Invalid URL format", http.StatusBadRequest)
+				return
+			}
+		}
+
+		if ok, statusCode, msg := CheckURL(rdb, id); !ok {
+			http.Error(w, msg, statusCode)
+			return
+		}
+
+		if err := SetTargets(rdb, id, targets, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Targets updated successfully")
+	}
+}
+
+// Add target
+//
+//	@Summary	Add target
+//	@Schemes
+//	@Description	adds a weighted target to a redirect
+//	@Tags			v1 targets
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string			true	"Id"
+//	@Param			query	body		models.Target	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		409		{string}	Conflict
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/targets [post]
+//	@Security		AccessToken
+func AddTargetRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var target models.Target
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if !IsURL(target.URL) {
+			http.Error(w, "Invalid URL format", http.StatusBadRequest)
+			return
+		}
+
+		if err := AddTarget(rdb, id, target, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Target added successfully")
+	}
+}
+
+// Reweight target
+//
+//	@Summary	Reweight target
+//	@Schemes
+//	@Description	changes the weight of an existing target of a redirect
+//	@Tags			v1 targets
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string			true	"Id"
+//	@Param			query	body		models.Target	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		409		{string}	Conflict
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/targets [patch]
+//	@Security		AccessToken
+func UpdateTargetRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var target models.Target
+		if err := json.NewDecoder(r.Body).Decode(&target); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := UpdateTargetWeight(rdb, id, target.URL, target.Weight, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Target updated successfully")
+	}
+}
+
+// Remove target
+//
+//	@Summary	Remove target
+//	@Schemes
+//	@Description	removes a target from a redirect
+//	@Tags			v1 targets
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Id"
+//	@Param			url	query		string	true	"Target URL"
+//	@Success		200	{object}	models.Response
+//	@Failure		403	{string}	Forbidden
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		409	{string}	Conflict
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/{id}/targets [delete]
+//	@Security		AccessToken
+func RemoveTargetRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		targetURL := r.URL.Query().Get("url")
+		if targetURL == "" {
+			http.Error(w, "Missing query parameter 'url'", http.StatusBadRequest)
+			return
+		}
+
+		if err := RemoveTarget(rdb, id, targetURL, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Target removed successfully")
+	}
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+)
+
+func TestSelectTarget(t *testing.T) {
+	t.Run("No targets uses primary URL", func(t *testing.T) {
+		redirect := models.RedirectPath{Path: "ab", URL: "https://example.com"}
+		req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+
+		if got := selectTarget(httptest.NewRecorder(), req, redirect); got != redirect.URL {
+			t.Errorf("expected %q, got %q", redirect.URL, got)
+		}
+	})
+
+	t.Run("Zero weights use primary URL", func(t *testing.T) {
+		redirect := models.RedirectPath{
+			Path:    "ab",
+			URL:     "https://example.com",
+			Targets: []models.Target{{URL: "https://a.example.com", Weight: 0}},
+		}
+		req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+
+		if got := selectTarget(httptest.NewRecorder(), req, redirect); got != redirect.URL {
+			t.Errorf("expected %q, got %q", redirect.URL, got)
+		}
+	})
+
+	t.Run("Only weighted targets are chosen", func(t *testing.T) {
+		redirect := models.RedirectPath{
+			Path: "ab",
+			URL:  "https://example.com",
+			Targets: []models.Target{
+				{URL: "https://a.example.com", Weight: 0},
+				{URL: "https://b.example.com", Weight: 4},
+			},
+		}
+
+		for range 20 {
+			req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+			if got := selectTarget(httptest.NewRecorder(), req, redirect); got != "https://b.example.com" {
+				t.Fatalf("expected %q, got %q", "https://b.example.com", got)
+			}
+		}
+	})
+
+	t.Run("Sticky mode sets and honours cookie", func(t *testing.T) {
+		redirect := models.RedirectPath{
+			Path:       "ab",
+			URL:        "https://example.com",
+			TargetMode: models.TargetModeSticky,
+			Targets: []models.Target{
+				{URL: "https://a.example.com", Weight: 1},
+				{URL: "https://b.example.com", Weight: 1},
+			},
+		}
+
+		rr := httptest.NewRecorder()
+		first := selectTarget(rr, httptest.NewRequest(http.MethodGet, "/ab", nil), redirect)
+
+		cookies := rr.Result().Cookies()
+		if len(cookies) != 1 || cookies[0].Name != "shorty_target_ab" {
+			t.Fatalf("expected sticky cookie, got %v", cookies)
+		}
+
+		for range 20 {
+			req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+			req.AddCookie(cookies[0])
+			if got := selectTarget(httptest.NewRecorder(), req, redirect); got != first {
+				t.Fatalf("expected sticky target %q, got %q", first, got)
+			}
+		}
+	})
+	t.Run("Sticky cookie is sent back through an alias", func(t *testing.T) {
+		redirect := models.RedirectPath{
+			Path:       "onboarding",
+			URL:        "https://example.com",
+			TargetMode: models.TargetModeSticky,
+			Targets: []models.Target{
+				{URL: "https://a.example.com", Weight: 1},
+				{URL: "https://b.example.com", Weight: 1},
+			},
+		}
+		jar, err := cookiejar.New(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		alias, _ := url.Parse("https://k.nhn.no/intro")
+
+		rr := httptest.NewRecorder()
+		first := selectTarget(rr, httptest.NewRequest(http.MethodGet, alias.String(), nil), redirect)
+		jar.SetCookies(alias, rr.Result().Cookies())
+
+		sent := jar.Cookies(alias)
+		if len(sent) != 1 {
+			t.Fatalf("expected the sticky cookie to be sent on the next visit, got %v", sent)
+		}
+		for range 20 {
+			req := httptest.NewRequest(http.MethodGet, alias.String(), nil)
+			req.AddCookie(sent[0])
+			if got := selectTarget(httptest.NewRecorder(), req, redirect); got != first {
+				t.Fatalf("expected sticky target %q, got %q", first, got)
+			}
+		}
+	})
+}
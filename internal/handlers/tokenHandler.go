@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+var (
+	CreateAccessToken = redisdb.CreateAccessToken
+	GetAccessTokens   = redisdb.GetAccessTokens
+	GetAccessToken    = redisdb.GetAccessToken
+	RevokeAccessToken = redisdb.RevokeAccessToken
+)
+
+// Get own access tokens
+//
+//	@Summary	Get own access tokens
+//	@Schemes
+//	@Description	lists the personal access tokens of the signed-in user, without their secrets
+//	@Tags			v1 tokens
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{array}		models.AccessToken
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/tokens [get]
+//	@Security		AccessToken
+func GetAccessTokensRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		tokens, err := GetAccessTokens(rdb, user)
+		if err != nil {
+			rlog.Error("Failed to get access tokens", err, rlog.String("user", user))
+			http.Error(w, "Failed to get access tokens", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(tokens); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Create access token
+//
+//	@Summary	Create access token
+//	@Schemes
+//	@Description	creates a named personal access token for scripts and CI, with the scopes read, write and admin, valid for expiresInDays (default 90). The token is only returned once. Access tokens cannot create other access tokens
+//	@Tags			v1 tokens
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			query	body		models.AccessTokenRequest	true	"Query"
+//	@Success		201		{object}	models.CreatedAccessToken
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/tokens [post]
+//	@Security		AccessToken
+func CreateAccessTokenRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// A leaked token must not be able to mint new ones
+		if _, ok := r.Context().Value(middleware.TokenScopesKey).([]string); ok {
+			http.Error(w, "Forbidden: Access tokens cannot create access tokens", http.StatusForbidden)
+			return
+		}
+
+		var request models.AccessTokenRequest
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if slices.Contains(request.Scopes, models.ScopeAdmin) && !isAdminOfDomain(r) {
+			http.Error(w, "Forbidden: Only admin users can create tokens with the admin scope", http.StatusForbidden)
+			return
+		}
+		// Tokens carry no groups, so admin rights from groups alone would leave the admin scope without effect
+		grants, _ := r.Context().Value(middleware.AdminGrantsKey).([]string)
+		if slices.Contains(request.Scopes, models.ScopeAdmin) && len(grants) > 0 && !slices.ContainsFunc(grants, func(grant string) bool {
+			return !middleware.IsGroupGrant(grant)
+		}) {
+			http.Error(w, "Forbidden: Admin rights from groups do not apply to access tokens, only admins in the admin list can create tokens with the admin scope", http.StatusForbidden)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+		token, err := CreateAccessToken(rdb, user, request)
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		if err := json.NewEncoder(w).Encode(token); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Revoke access token
+//
+//	@Summary	Revoke access token
+//	@Schemes
+//	@Description	revokes a personal access token. Users can revoke their own tokens, admins any token
+//	@Tags			v1 tokens
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Token id"
+//	@Success		200	{object}	models.Response
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/tokens/{id} [delete]
+//	@Security		AccessToken
+func RevokeAccessTokenRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+
+		// Tokens of other users are reported as missing, so their ids are not confirmed
+		token, err := GetAccessToken(rdb, id)
+		if err == nil && token.Owner != user && !isAdmin {
+			err = redisdb.ErrTokenNotFound
+		}
+		if err == nil {
+			err = RevokeAccessToken(rdb, id, user)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Token revoked successfully")
+	}
+}
+
+// Get all access tokens
+//
+//	@Summary	Get all access tokens
+//	@Schemes
+//	@Description	lists the personal access tokens of every user, or of one owner, without their secrets, admin only
+//	@Tags			v1 tokens
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			owner	query		string	false	"Owner"
+//	@Success		200		{array}		models.AccessToken
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/admin/tokens [get]
+//	@Security		AccessToken
+func GetAllAccessTokensRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		tokens, err := GetAccessTokens(rdb, r.URL.Query().Get("owner"))
+		if err != nil {
+			rlog.Error("Failed to get access tokens", err)
+			http.Error(w, "Failed to get access tokens", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(tokens); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+func TestCreateAccessTokenRedirect(t *testing.T) {
+	original := CreateAccessToken
+	t.Cleanup(func() { CreateAccessToken = original })
+
+	CreateAccessToken = func(rdb *redis.Client, owner string, request models.AccessTokenRequest) (models.CreatedAccessToken, error) {
+		if request.Name == "" {
+			return models.CreatedAccessToken{}, redisdb.ErrInvalidTokenRequest
+		}
+		return models.CreatedAccessToken{AccessToken: models.AccessToken{ID: "abc", Name: request.Name, Owner: owner}, Token: "shorty_pat_abc_secret"}, nil
+	}
+
+	tests := []struct {
+		name       string
+		body       string
+		isAdmin    bool
+		grants     []string
+		withToken  bool
+		wantStatus int
+	}{
+		{name: "Create token", body: `{"name":"ci","scopes":["write"]}`, wantStatus: http.StatusCreated},
+		{name: "Admin scope for admins", body: `{"name":"ci","scopes":["admin"]}`, isAdmin: true, wantStatus: http.StatusCreated},
+		{name: "Admin scope for users", body: `{"name":"ci","scopes":["admin"]}`, wantStatus: http.StatusForbidden},
+		{name: "Admin scope for admins in the admin list", body: `{"name":"ci","scopes":["admin"]}`, isAdmin: true,
+			grants: []string{"redis", "group:shorty-admins"}, wantStatus: http.StatusCreated},
+		{name: "Admin scope for admins by group only", body: `{"name":"ci","scopes":["admin"]}`, isAdmin: true,
+			grants: []string{"group:shorty-admins"}, wantStatus: http.StatusForbidden},
+		{name: "Invalid request", body: `{"scopes":["read"]}`, wantStatus: http.StatusBadRequest},
+		{name: "Invalid body", body: `{`, wantStatus: http.StatusBadRequest},
+		{name: "Access tokens cannot create tokens", body: `{"name":"ci","scopes":["read"]}`, withToken: true, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/tokens", strings.NewReader(tc.body))
+			ctx := context.WithValue(req.Context(), middleware.UserKey, "user@example.com")
+			ctx = context.WithValue(ctx, middleware.IsAdminKey, tc.isAdmin)
+			if tc.grants != nil {
+				ctx = context.WithValue(ctx, middleware.AdminGrantsKey, tc.grants)
+			}
+			if tc.withToken {
+				ctx = context.WithValue(ctx, middleware.TokenScopesKey, []string{models.ScopeWrite})
+			}
+
+			rr := httptest.NewRecorder()
+			CreateAccessTokenRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if tc.wantStatus == http.StatusCreated && !strings.Contains(rr.Body.String(), `"token":"shorty_pat_abc_secret"`) {
+				t.Errorf("expected the token in the body, got %q", rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestRevokeAccessTokenRedirect(t *testing.T) {
+	originalGet, originalRevoke := GetAccessToken, RevokeAccessToken
+	t.Cleanup(func() { GetAccessToken, RevokeAccessToken = originalGet, originalRevoke })
+
+	GetAccessToken = func(rdb *redis.Client, id string) (models.AccessToken, error) {
+		if id == "gone" {
+			return models.AccessToken{}, redisdb.ErrTokenNotFound
+		}
+		return models.AccessToken{ID: id, Owner: "owner@example.com"}, nil
+	}
+	var revoked []string
+	RevokeAccessToken = func(rdb *redis.Client, id string, user string) error {
+		revoked = append(revoked, id)
+		return nil
+	}
+
+	tests := []struct {
+		name        string
+		id          string
+		user        string
+		isAdmin     bool
+		wantStatus  int
+		wantRevoked bool
+	}{
+		{name: "Owner revokes own token", id: "abc", user: "owner@example.com", wantStatus: http.StatusOK, wantRevoked: true},
+		{name: "Admin revokes any token", id: "abc", user: "admin@example.com", isAdmin: true, wantStatus: http.StatusOK, wantRevoked: true},
+		{name: "Tokens of others are not found", id: "abc", user: "other@example.com", wantStatus: http.StatusNotFound},
+		{name: "Unknown token", id: "gone", user: "owner@example.com", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			revoked = nil
+			req := httptest.NewRequest(http.MethodDelete, "/v1/tokens/"+tc.id, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tc.id})
+			ctx := context.WithValue(req.Context(), middleware.UserKey, tc.user)
+			ctx = context.WithValue(ctx, middleware.IsAdminKey, tc.isAdmin)
+
+			rr := httptest.NewRecorder()
+			RevokeAccessTokenRedirect(nil)(rr, req.WithContext(ctx))
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if got := len(revoked) == 1; got != tc.wantRevoked {
+				t.Errorf("expected revoked %t, got %v", tc.wantRevoked, revoked)
+			}
+		})
+	}
+}
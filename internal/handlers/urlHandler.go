@@ -3,13 +3,17 @@ package handlers
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
 	"net/url"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/NorskHelsenett/shorty/internal/config"
+	"github.com/NorskHelsenett/shorty/internal/keygen"
 	"github.com/NorskHelsenett/shorty/internal/metrics"
 	"github.com/NorskHelsenett/shorty/internal/middleware"
 	"github.com/NorskHelsenett/shorty/internal/models"
@@ -20,12 +24,17 @@ import (
 	"github.com/gorilla/mux"
 )
 
+// blockedSchemes are never allowed as redirect targets, not even as app deep links
+var blockedSchemes = []string{"javascript", "data", "file", "vbscript", "blob", "about"}
+
 var (
 	URLExists          = redisdb.URLExists
 	GetURL             = redisdb.GetURL
+	GetRedirect        = redisdb.GetRedirect
 	Delete             = redisdb.Delete
 	UpdateOrCreatePath = redisdb.UpdateOrCreatePath
 	GetAll             = redisdb.GetAll
+	GenerateKey        = keygen.Generate
 )
 
 // CheckURL validates if a URL with the given ID exists
@@ -56,36 +65,79 @@ func CheckURL(rdb *redis.Client, id string) (bool, int, string) {
 //	@Accept			text/html
 //	@Produce		text/html
 //	@Param			path	path		string	true	"Path"
+//	@Param			lang	query		string	false	"Language override"
+//	@Param			src		query		string	false	"Set to qr by generated QR codes"
 //	@Success		302		{string}	Redirecting
 //	@Failure		403		{string}	Forbidden
 //	@Failure		401		{string}	Unauthorized
 //	@Failure		500		{string}	Failure	message
+//	@Failure		503		{string}	Link	paused
 //	@Router			/{path} [get]
 func Redirect(rdb *redis.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		params := mux.Vars(r)
 		id := params["id"]
 
-		path, err := GetURL(rdb, id)
+		redirect, err := GetRedirect(rdb, id)
 
 		rlog.Info("Redirect", rlog.Any("id", id))
 
 		if err != nil {
-			path = "https://nhn.no"
+			path := middleware.GetDomain(r).FallbackURL
 			rlog.Info("Default redirect, path not found", rlog.Any("client", r.Host), rlog.Any("path", r.RequestURI), rlog.Any("to", path))
+			countRedirect(r, http.StatusFound, metrics.ResultNotFound, metrics.ModeNone)
+			if errors.Is(err, redisdb.ErrURLNotFound) {
+				recordNotFound(rdb, r, id)
+			}
 			http.Redirect(w, r, path, http.StatusFound)
 			return
 		}
+
+		if redirect.Status != nil && !redirect.Status.Enabled {
+			rlog.Info("Redirect is paused", rlog.Any("client", r.Host), rlog.Any("path", r.RequestURI), rlog.Any("to", redirect.Status.Target))
+			status := http.StatusServiceUnavailable
+			if redirect.Status.Target != "" {
+				status = http.StatusFound
+			}
+			countRedirect(r, status, metrics.ResultPaused, metrics.ModeNone)
+			servePaused(w, r, *redirect.Status)
+			return
+		}
+
+		mode := metrics.ModeDevice
+		path, ok := selectDevice(w, r, redirect)
+		if !ok {
+			mode = metrics.ModeLanguage
+			path, ok = selectLanguage(w, r, redirect)
+		}
+		if !ok {
+			mode = metrics.ModeSingle
+			if len(redirect.Targets) > 0 {
+				mode = metrics.ModeTargets
+			}
+			path = selectTarget(w, r, redirect)
+		}
+		target := path
+		path = applyUTM(rdb, r, redirect, path)
 		rlog.Info("Redirecting", rlog.Any("client", r.Host), rlog.Any("path", r.RequestURI), rlog.Any("to", path))
 
-		// increment httpRequest metric on path
-		currentYearMonth := time.Now().Format("2006-01")
-		metrics.RequestCount.WithLabelValues(r.URL.Path, currentYearMonth).Inc()
+		// Clicks per link and target go to the link statistics of the canonical path, so aliases count towards their link
+		countRedirect(r, http.StatusFound, metrics.ResultFound, mode)
+		recordClick(rdb, r, redirect.Path, target)
 
 		http.Redirect(w, r, path, http.StatusFound)
 	}
 }
 
+// countRedirect increments the redirect metric. Bots, previewers and scanners are counted apart from people.
+func countRedirect(r *http.Request, status int, result string, mode string) {
+	if bot := middleware.BotReason(r); bot != "" {
+		metrics.BotRequestCount.WithLabelValues(bot).Inc()
+		return
+	}
+	metrics.RequestCount.WithLabelValues(metrics.StatusClass(status), result, mode).Inc()
+}
+
 // Delete redirect
 //
 //	@Summary	Delete redirect
@@ -111,7 +163,7 @@ func DeleteRedirect(rdb *redis.Client) http.HandlerFunc {
 		rlog.Debug("isOwnerOrAdmin", rlog.Any("isAdminUser:", isAdmin))
 		rlog.Debug("isOwnerOrAdmin", rlog.Any("isOwner", isOwner))
 
-		if !isAdmin && !isOwner {
+		if !canModify(r) {
 			w.WriteHeader(http.StatusForbidden)
 			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
 			return
@@ -125,6 +177,21 @@ func DeleteRedirect(rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 
+		// Deleting an alias only removes the alias, not the link it points at
+		if alias, err := IsAlias(rdb, id); err != nil || alias {
+			if err == nil {
+				user, _ := r.Context().Value(middleware.UserKey).(string)
+				err = removeAlias(rdb, id, user)
+			}
+			if err != nil {
+				rlog.Error("Failed to delete alias", err, rlog.String("id", id))
+				http.Error(w, "Failed to delete alias", errorStatus(err))
+				return
+			}
+			writeResponse(w, http.StatusOK, "Alias deleted successfully")
+			return
+		}
+
 		success, err := Delete(rdb, id)
 		if !success || err != nil {
 			rlog.Error("Failed to delete URl", err)
@@ -153,6 +220,7 @@ func DeleteRedirect(rdb *redis.Client) http.HandlerFunc {
 //	@Param			query	body		models.Redirect	true	"Query"
 //	@Param			id		path		string			true	"Id"
 //	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Redirect	loop
 //	@Failure		403		{string}	Forbidden
 //	@Failure		401		{string}	Unauthorized
 //	@Failure		500		{string}	Failure	message
@@ -168,7 +236,7 @@ func UpdateRedirect(rdb *redis.Client) http.HandlerFunc {
 		rlog.Debug("isOwnerOrAdmin", rlog.Any("isAdminUser:", isAdmin))
 		rlog.Debug("isOwnerOrAdmin", rlog.Any("isOwner", isOwner))
 
-		if !isAdmin && !isOwner {
+		if !canModify(r) {
 			w.WriteHeader(http.StatusForbidden)
 			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
 			return
@@ -197,8 +265,19 @@ func UpdateRedirect(rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 
+		// aliases update the canonical redirect they point at
+		redirect, err := GetRedirect(rdb, id)
+		if err != nil {
+			http.Error(w, "Failed to update URL", errorStatus(err))
+			return
+		}
+
 		// update URL in Redis
-		_, err := UpdateOrCreatePath(rdb, id, update.URL, lastEditedBy)
+		_, err = UpdateOrCreatePath(rdb, redirect.Path, update.URL, lastEditedBy)
+		if isChainError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "Failed to update URL", http.StatusInternalServerError)
 			return
@@ -216,12 +295,13 @@ func UpdateRedirect(rdb *redis.Client) http.HandlerFunc {
 //
 //	@Summary	Add redirect
 //	@Schemes
-//	@Description	adds a redirect to url
+//	@Description	adds a redirect to url; a random key is generated when no path is given
 //	@Tags			v1
 //	@Accept			application/json
 //	@Produce		application/json
 //	@Param			query	body		models.Redirect	true	"Query"
 //	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Redirect	loop
 //	@Failure		403	{string}	Forbidden
 //	@Failure		401	{string}	Unauthorized
 //	@Failure		409		{string}	Conflict
@@ -247,11 +327,25 @@ func AddRedirect(rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 
-		if redirect.Path == "" {
-			rlog.Info("Path is empty")
-			http.Error(w, "URL cannot be empty", http.StatusBadRequest)
+		// Keys are created in the namespace of the requested domain
+		if strings.Contains(redirect.Path, ":") {
+			rlog.Info("Path contains namespace separator", rlog.Any("path", redirect.Path))
+			http.Error(w, "Invalid path", http.StatusBadRequest)
 			return
 		}
+		domain := middleware.GetDomain(r)
+
+		// Generate a key when none is supplied
+		if redirect.Path == "" {
+			key, err := generateKey(rdb, domain)
+			if err != nil {
+				rlog.Error("Failed to generate key", err)
+				http.Error(w, "Failed to generate key", http.StatusInternalServerError)
+				return
+			}
+			redirect.Path = key
+		}
+		redirect.Path = domain.Key(redirect.Path)
 
 		// Basic URL format check (fast fail)
 		if !IsURL(redirect.URL) {
@@ -274,7 +368,7 @@ func AddRedirect(rdb *redis.Client) http.HandlerFunc {
 			w.Header().Set("Content-Type", "application/json")
 			err = json.NewEncoder(w).Encode(models.Response{
 				Success: false,
-				Message: fmt.Sprintf("Path already exists: %s", redirect.Path),
+				Message: fmt.Sprintf("Path already exists: %s", domain.LocalKey(redirect.Path)),
 			})
 
 			if err != nil {
@@ -293,10 +387,17 @@ func AddRedirect(rdb *redis.Client) http.HandlerFunc {
 			return
 		}
 
+		// Service accounts have their own daily quota
+		account, isService := r.Context().Value(middleware.ServiceAccountKey).(models.ServiceAccount)
+
 		count, err := getUserRedirectCountToday(rdb, userEmail)
 		if err != nil {
 			rlog.Error("Failed to check user redirect count", err)
-		} else if count > 3 {
+		} else if isService && count >= account.DailyLinkLimit {
+			rlog.Warn("service account exceeded daily link quota", rlog.String("service", account.Name))
+			http.Error(w, "Daily link quota of service account exceeded", http.StatusTooManyRequests)
+			return
+		} else if !isService && count > 3 {
 			rlog.Warn("user exceeded daily redirect limit", rlog.String("user", userEmail))
 			http.Error(w, "Daily redirect limit exceeded", http.StatusTooManyRequests)
 			return
@@ -304,6 +405,14 @@ func AddRedirect(rdb *redis.Client) http.HandlerFunc {
 
 		// Create the redirect
 		message, err := UpdateOrCreatePath(rdb, redirect.Path, redirect.URL, userEmail)
+		if isChainError(err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if errors.Is(err, redisdb.ErrKeyReserved) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		if err != nil {
 			rlog.Error("Failed to create redirect", err)
 			http.Error(w, "Failed to create redirect", http.StatusInternalServerError)
@@ -314,12 +423,20 @@ func AddRedirect(rdb *redis.Client) http.HandlerFunc {
 			rlog.Error("failed to increment user redirect count", err)
 		}
 
+		if isService {
+			if err := SetServiceOwner(rdb, redirect.Path, account.Owner); err != nil {
+				rlog.Error("Failed to save service owner", err, rlog.String("path", redirect.Path))
+			}
+		}
+
 		// Send successful response
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusOK)
 		if err := json.NewEncoder(w).Encode(models.Response{
-			Success: true,
-			Message: message,
+			Success:  true,
+			Message:  message,
+			Path:     domain.LocalKey(redirect.Path),
+			ShortURL: domain.BaseURL + "/" + domain.LocalKey(redirect.Path),
 		}); err != nil {
 			rlog.Error("Failed to encode response", err)
 		}
@@ -344,7 +461,8 @@ func GetAllRedirects(rdb *redis.Client) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 
 		user, _ := r.Context().Value(middleware.UserKey).(string)
-		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		isAdmin := isAdminOfDomain(r)
+		domain := middleware.GetDomain(r)
 
 		redirects, err := GetAll(rdb, "path")
 		if err != nil {
@@ -360,15 +478,27 @@ func GetAllRedirects(rdb *redis.Client) http.HandlerFunc {
 		var redirectsMap []models.RedirectAllPaths
 
 		for _, redirect := range redirects {
+			// Only list the links of the requested domain
+			if !domain.Owns(redirect.Path) {
+				continue
+			}
 
 			isOwner := redirect.Owner == user
 			canModify := isOwner || isAdmin
 
 			redirectsMap = append(redirectsMap, models.RedirectAllPaths{
-				Path:   redirect.Path,
-				URL:    redirect.URL,
-				Owner:  redirect.Owner,
-				Modify: canModify,
+				Path:         domain.LocalKey(redirect.Path),
+				URL:          redirect.URL,
+				Owner:        redirect.Owner,
+				ServiceOwner: redirect.ServiceOwner,
+				Targets:      redirect.Targets,
+				TargetMode:   redirect.TargetMode,
+				Languages:    redirect.Languages,
+				Devices:      redirect.Devices,
+				Aliases:      localKeys(domain, redirect.Aliases),
+				Status:       redirect.Status,
+				UTM:          redirect.UTM,
+				Modify:       canModify,
 			})
 		}
 
@@ -428,6 +558,126 @@ func IsURL(str string) bool {
 	return true
 }
 
+// canModify reports whether the current user is an admin or the owner of the requested resource
+func canModify(r *http.Request) bool {
+	isOwner, _ := r.Context().Value(middleware.IsOwnerKey).(bool)
+	return isAdminOfDomain(r) || isOwner
+}
+
+// isAdminOfDomain reports whether the user is an admin, or an admin of the requested domain
+func isAdminOfDomain(r *http.Request) bool {
+	isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+	isDomainAdmin, _ := r.Context().Value(middleware.IsDomainAdminKey).(bool)
+	return isAdmin || isDomainAdmin
+}
+
+// errorStatus maps errors from the redirect store to HTTP status codes
+func errorStatus(err error) int {
+	switch {
+	case errors.Is(err, redisdb.ErrURLNotFound),
+		errors.Is(err, redisdb.ErrTargetNotFound),
+		errors.Is(err, redisdb.ErrLanguageNotFound),
+		errors.Is(err, redisdb.ErrDeviceNotFound),
+		errors.Is(err, redisdb.ErrAliasNotFound),
+		errors.Is(err, redisdb.ErrCampaignNotFound),
+		errors.Is(err, redisdb.ErrTokenNotFound),
+		errors.Is(err, redisdb.ErrServiceAccountNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, redisdb.ErrTargetExists),
+		errors.Is(err, redisdb.ErrKeyExists),
+		errors.Is(err, redisdb.ErrKeyReserved),
+		errors.Is(err, redisdb.ErrRenameConflict),
+		errors.Is(err, redisdb.ErrTargetConflict):
+		return http.StatusConflict
+	case errors.Is(err, redisdb.ErrInvalidKey),
+		errors.Is(err, redisdb.ErrInvalidValue),
+		errors.Is(err, redisdb.ErrSameKeyValue),
+		errors.Is(err, redisdb.ErrRenameAlias),
+		errors.Is(err, redisdb.ErrInvalidWeight),
+		errors.Is(err, redisdb.ErrInvalidTargetMode),
+		errors.Is(err, redisdb.ErrInvalidLanguage),
+		errors.Is(err, redisdb.ErrInvalidDevice),
+		errors.Is(err, redisdb.ErrInvalidCampaign),
+		errors.Is(err, redisdb.ErrInvalidStatsRange),
+		errors.Is(err, redisdb.ErrInvalidTokenRequest),
+		errors.Is(err, redisdb.ErrInvalidServiceAccount),
+		isChainError(err):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// localKeys returns stored keys as they are used on the domain, without the namespace
+func localKeys(domain config.Domain, keys []string) []string {
+	if len(keys) == 0 {
+		return keys
+	}
+	local := make([]string, len(keys))
+	for i, key := range keys {
+		local[i] = domain.LocalKey(key)
+	}
+	return local
+}
+
+// isChainError reports whether a target was rejected because it loops or chains through our own short links
+func isChainError(err error) bool {
+	return errors.Is(err, redisdb.ErrRedirectLoop) || errors.Is(err, redisdb.ErrRedirectChainTooLong)
+}
+
+// generateKey returns a random key that is free on the domain
+func generateKey(rdb *redis.Client, domain config.Domain) (string, error) {
+	return GenerateKey(keygen.OptionsFromConfig(), func(key string) (bool, error) {
+		if redisdb.ValidateKey(key) != nil {
+			return true, nil
+		}
+		if reserved, err := IsReserved(rdb, domain.Key(key)); err != nil || reserved {
+			return true, err
+		}
+		return URLExists(rdb, domain.Key(key))
+	})
+}
+
+// writeResponse writes a standard JSON response with the given status code
+func writeResponse(w http.ResponseWriter, statusCode int, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	if err := json.NewEncoder(w).Encode(models.Response{
+		Success: statusCode < http.StatusBadRequest,
+		Message: message,
+	}); err != nil {
+		rlog.Error("Failed to encode response", err)
+	}
+}
+
+// IsDeviceTarget validates a device target, which is either a URL accepted by
+// IsURL or an app deep link such as `myapp://open/page`. Deep link schemes can
+// be restricted with DEVICE_TARGET_SCHEMES.
+func IsDeviceTarget(str string) bool {
+	if IsURL(str) {
+		return true
+	}
+
+	u, err := url.Parse(str)
+	if err != nil || u.Scheme == "" || (u.Host == "" && u.Path == "" && u.Opaque == "") {
+		rlog.Info("Invalid app link format", rlog.String("target", str))
+		return false
+	}
+
+	scheme := strings.ToLower(u.Scheme)
+	if scheme == "http" || scheme == "https" || slices.Contains(blockedSchemes, scheme) {
+		rlog.Info("invalid app link scheme", rlog.String("scheme", scheme))
+		return false
+	}
+
+	if allowed := config.GetList("DEVICE_TARGET_SCHEMES"); len(allowed) > 0 && !slices.Contains(allowed, scheme) {
+		rlog.Info("app link scheme not allowed", rlog.String("scheme", scheme))
+		return false
+	}
+
+	return true
+}
+
 func getUserRedirectCountToday(rdb *redis.Client, userEmail string) (int, error) {
 	today := time.Now().Format("2006-01-02")
 	key := fmt.Sprintf("user:%s:count:%s", userEmail, today)
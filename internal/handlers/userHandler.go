@@ -300,3 +300,42 @@ func CheckUserEmailRedirect(rdb *redis.Client) http.HandlerFunc {
 		}
 	}
 }
+
+// Get current user
+//
+//	@Summary	Get current user
+//	@Schemes
+//	@Description	returns the authenticated user, their groups and the effective source of each admin grant
+//	@Tags			v1 user
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{object}	models.CurrentUser
+//	@Failure		401	{string}	Unauthorized
+//	@Router			/v1/me [get]
+//	@Security		AccessToken
+func GetCurrentUserRedirect() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		email, _ := r.Context().Value(middleware.UserKey).(string)
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		isDomainAdmin, _ := r.Context().Value(middleware.IsDomainAdminKey).(bool)
+		grants, _ := r.Context().Value(middleware.AdminGrantsKey).([]string)
+		groups, _ := r.Context().Value(middleware.GroupsKey).([]string)
+		if grants == nil {
+			grants = []string{}
+		}
+		if groups == nil {
+			groups = []string{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(models.CurrentUser{
+			Email:       email,
+			Admin:       isAdmin,
+			DomainAdmin: isDomainAdmin,
+			AdminGrants: grants,
+			Groups:      groups,
+		}); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
@@ -354,3 +354,30 @@ func TestCheckUserEmailRedirect(t *testing.T) {
 		})
 	}
 }
+
+// --- Test for GetCurrentUserRedirect ---
+func TestGetCurrentUserRedirect(t *testing.T) {
+	ctx := context.WithValue(context.Background(), middleware.UserKey, "user@example.com")
+	ctx = context.WithValue(ctx, middleware.IsAdminKey, true)
+	ctx = context.WithValue(ctx, middleware.AdminGrantsKey, []string{"redis", "group:shorty-admins"})
+	ctx = context.WithValue(ctx, middleware.GroupsKey, []string{"shorty-admins"})
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/me", nil).WithContext(ctx)
+	rr := httptest.NewRecorder()
+	GetCurrentUserRedirect().ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d; got %d", http.StatusOK, rr.Code)
+	}
+
+	var user models.CurrentUser
+	if err := json.NewDecoder(rr.Body).Decode(&user); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	if user.Email != "user@example.com" || !user.Admin || user.DomainAdmin {
+		t.Errorf("unexpected user: %+v", user)
+	}
+	if len(user.AdminGrants) != 2 || user.AdminGrants[1] != "group:shorty-admins" {
+		t.Errorf("expected the grants of the user, got %v", user.AdminGrants)
+	}
+}
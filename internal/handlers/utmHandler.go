@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/middleware"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"github.com/gorilla/mux"
+)
+
+const (
+	// qrSource is the value of the src query parameter added to short URLs in generated QR codes
+	qrSource = "qr"
+	// campaignCacheTTL is how long campaign presets are served from memory on redirects
+	campaignCacheTTL = 30 * time.Second
+)
+
+var (
+	GetUTM         = redisdb.GetUTM
+	SetUTM         = redisdb.SetUTM
+	GetCampaigns   = redisdb.GetCampaigns
+	GetCampaign    = redisdb.GetCampaign
+	SetCampaign    = redisdb.SetCampaign
+	DeleteCampaign = redisdb.DeleteCampaign
+)
+
+// campaignCache holds the campaign presets used by redirects, so tagged links do not read redis on every click.
+// Missing presets are cached as well. Entries live for campaignCacheTTL, so a preset changed or deleted on
+// another instance reaches the redirects of this one within 30 seconds; changes made here apply right away.
+var campaignCache struct {
+	sync.Mutex
+	entries map[string]cachedCampaign
+}
+
+type cachedCampaign struct {
+	campaign models.Campaign
+	err      error
+	expires  time.Time
+}
+
+// cachedCampaignPreset returns a campaign preset from the cache, reading it from redis when missing or expired.
+// Redis failures are not cached.
+func cachedCampaignPreset(rdb *redis.Client, name string) (models.Campaign, error) {
+	name = strings.ToLower(name)
+
+	campaignCache.Lock()
+	entry, ok := campaignCache.entries[name]
+	campaignCache.Unlock()
+	if ok && time.Now().Before(entry.expires) {
+		return entry.campaign, entry.err
+	}
+
+	campaign, err := GetCampaign(rdb, name)
+	if err != nil && !errors.Is(err, redisdb.ErrCampaignNotFound) {
+		return campaign, err
+	}
+
+	campaignCache.Lock()
+	if campaignCache.entries == nil {
+		campaignCache.entries = make(map[string]cachedCampaign)
+	}
+	campaignCache.entries[name] = cachedCampaign{campaign: campaign, err: err, expires: time.Now().Add(campaignCacheTTL)}
+	campaignCache.Unlock()
+	return campaign, err
+}
+
+// forgetCampaign drops a campaign preset from the cache after it is changed on this instance
+func forgetCampaign(name string) {
+	campaignCache.Lock()
+	delete(campaignCache.entries, strings.ToLower(name))
+	campaignCache.Unlock()
+}
+
+// applyUTM adds the UTM parameters of a redirect to the chosen target.
+// Parameters from the campaign preset are overridden by the ones set on the redirect,
+// and scans of generated QR codes are tagged with utm_medium=qr.
+func applyUTM(rdb *redis.Client, r *http.Request, redirect models.RedirectPath, target string) string {
+	if redirect.UTM == nil {
+		return target
+	}
+
+	var params models.UTMParams
+	if redirect.UTM.Preset != "" {
+		campaign, err := cachedCampaignPreset(rdb, redirect.UTM.Preset)
+		if err != nil {
+			rlog.Error("Failed to get campaign preset", err, rlog.String("path", redirect.Path), rlog.String("preset", redirect.UTM.Preset))
+		} else {
+			params = campaign.UTMParams
+		}
+	}
+	params = overrideUTM(params, redirect.UTM.UTMParams)
+
+	if r.URL.Query().Get("src") == qrSource {
+		params.Medium = qrSource
+	}
+
+	return mergeUTM(target, params)
+}
+
+// overrideUTM returns base with every non-empty parameter of override applied
+func overrideUTM(base, override models.UTMParams) models.UTMParams {
+	if override.Source != "" {
+		base.Source = override.Source
+	}
+	if override.Medium != "" {
+		base.Medium = override.Medium
+	}
+	if override.Campaign != "" {
+		base.Campaign = override.Campaign
+	}
+	if override.Term != "" {
+		base.Term = override.Term
+	}
+	if override.Content != "" {
+		base.Content = override.Content
+	}
+	return base
+}
+
+// mergeUTM adds UTM parameters to the query string of a web target.
+// Parameters already present on the target are left untouched, and deep links are not tagged.
+func mergeUTM(target string, params models.UTMParams) string {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return target
+	}
+
+	query := u.Query()
+	changed := false
+	for name, value := range map[string]string{
+		"utm_source":   params.Source,
+		"utm_medium":   params.Medium,
+		"utm_campaign": params.Campaign,
+		"utm_term":     params.Term,
+		"utm_content":  params.Content,
+	} {
+		if value != "" && !query.Has(name) {
+			query.Set(name, value)
+			changed = true
+		}
+	}
+	if !changed {
+		return target
+	}
+
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Get UTM settings
+//
+//	@Summary	Get UTM settings
+//	@Schemes
+//	@Description	gets the UTM parameters and campaign preset added to the target of a redirect
+//	@Tags			v1 utm
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id	path		string	true	"Id"
+//	@Success		200	{object}	models.UTM
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		404	{string}	Not	found
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/{id}/utm [get]
+//	@Security		AccessToken
+func GetUTMRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		utm, err := GetUTM(rdb, id)
+		if err != nil {
+			rlog.Error("Failed to get UTM settings", err, rlog.String("id", id))
+			http.Error(w, "Failed to get UTM settings", errorStatus(err))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(utm); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Set UTM settings
+//
+//	@Summary	Set UTM settings
+//	@Schemes
+//	@Description	replaces the UTM parameters of a redirect. Parameters set here override the campaign preset, and an empty body turns tagging off
+//	@Tags			v1 utm
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			id		path		string		true	"Id"
+//	@Param			query	body		models.UTM	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/{id}/utm [put]
+//	@Security		AccessToken
+func SetUTMRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !canModify(r) {
+			http.Error(w, "Forbidden: You must be an admin or the owner of this resource", http.StatusForbidden)
+			return
+		}
+
+		id := mux.Vars(r)["id"]
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+
+		var utm models.UTM
+		if err := json.NewDecoder(r.Body).Decode(&utm); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := SetUTM(rdb, id, utm, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "UTM settings updated successfully")
+	}
+}
+
+// Get campaigns
+//
+//	@Summary	Get campaigns
+//	@Schemes
+//	@Description	gets all UTM campaign presets
+//	@Tags			v1 campaigns
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Success		200	{array}		models.Campaign
+//	@Failure		401	{string}	Unauthorized
+//	@Failure		500	{string}	Failure	message
+//	@Router			/v1/campaigns [get]
+//	@Security		AccessToken
+func GetCampaignsRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		campaigns, err := GetCampaigns(rdb)
+		if err != nil {
+			rlog.Error("Failed to get campaigns", err)
+			http.Error(w, "Failed to get campaigns", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if err := json.NewEncoder(w).Encode(campaigns); err != nil {
+			rlog.Error("Error encoding response: ", err)
+		}
+	}
+}
+
+// Set campaign
+//
+//	@Summary	Set campaign
+//	@Schemes
+//	@Description	creates or replaces a UTM campaign preset, admin only. Redirects on other instances may use the previous preset for up to 30 seconds
+//	@Tags			v1 campaigns
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			name	path		string				true	"Name"
+//	@Param			query	body		models.UTMParams	true	"Query"
+//	@Success		200		{object}	models.Response
+//	@Failure		400		{string}	Bad	request
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/campaigns/{name} [put]
+//	@Security		AccessToken
+func SetCampaignRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		user, _ := r.Context().Value(middleware.UserKey).(string)
+		campaign := models.Campaign{Name: mux.Vars(r)["name"]}
+		if err := json.NewDecoder(r.Body).Decode(&campaign.UTMParams); err != nil {
+			rlog.Error("Failed to decode body", err)
+			http.Error(w, "Failed to decode body", http.StatusBadRequest)
+			return
+		}
+		defer r.Body.Close()
+
+		if err := SetCampaign(rdb, campaign, user); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		forgetCampaign(campaign.Name)
+
+		writeResponse(w, http.StatusOK, "Campaign updated successfully")
+	}
+}
+
+// Delete campaign
+//
+//	@Summary	Delete campaign
+//	@Schemes
+//	@Description	deletes a UTM campaign preset, admin only. Redirects on other instances may use the deleted preset for up to 30 seconds
+//	@Tags			v1 campaigns
+//	@Accept			application/json
+//	@Produce		application/json
+//	@Param			name	path		string	true	"Name"
+//	@Success		200		{object}	models.Response
+//	@Failure		403		{string}	Forbidden
+//	@Failure		401		{string}	Unauthorized
+//	@Failure		404		{string}	Not	found
+//	@Failure		500		{string}	Failure	message
+//	@Router			/v1/campaigns/{name} [delete]
+//	@Security		AccessToken
+func DeleteCampaignRedirect(rdb *redis.Client) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		isAdmin, _ := r.Context().Value(middleware.IsAdminKey).(bool)
+		if !isAdmin {
+			http.Error(w, "Forbidden: Only admin users can perform this action", http.StatusForbidden)
+			return
+		}
+
+		name := mux.Vars(r)["name"]
+		if err := DeleteCampaign(rdb, name); err != nil {
+			http.Error(w, err.Error(), errorStatus(err))
+			return
+		}
+		forgetCampaign(name)
+
+		writeResponse(w, http.StatusOK, "Campaign deleted successfully")
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+func TestMergeUTM(t *testing.T) {
+	params := models.UTMParams{Source: "newsletter", Medium: "email", Campaign: "spring"}
+
+	t.Run("Adds parameters", func(t *testing.T) {
+		got, _ := url.Parse(mergeUTM("https://example.com/page?id=1", params))
+		query := got.Query()
+		if query.Get("id") != "1" || query.Get("utm_source") != "newsletter" || query.Get("utm_campaign") != "spring" {
+			t.Errorf("unexpected query: %v", query)
+		}
+		if query.Has("utm_term") {
+			t.Errorf("expected empty parameters to be skipped, got %v", query)
+		}
+	})
+
+	t.Run("Keeps existing parameters", func(t *testing.T) {
+		got, _ := url.Parse(mergeUTM("https://example.com/?utm_source=partner", params))
+		if source := got.Query().Get("utm_source"); source != "partner" {
+			t.Errorf("expected existing utm_source to be kept, got %q", source)
+		}
+	})
+
+	t.Run("Deep links are not tagged", func(t *testing.T) {
+		if got := mergeUTM("myapp://open", params); got != "myapp://open" {
+			t.Errorf("expected deep link unchanged, got %q", got)
+		}
+	})
+}
+
+func TestApplyUTM(t *testing.T) {
+	original := GetCampaign
+	t.Cleanup(func() {
+		GetCampaign = original
+		forgetCampaign("spring")
+	})
+
+	reads := 0
+	GetCampaign = func(rdb *redis.Client, name string) (models.Campaign, error) {
+		reads++
+		return models.Campaign{Name: name, UTMParams: models.UTMParams{Source: "newsletter", Medium: "email", Campaign: "spring"}}, nil
+	}
+
+	redirect := models.RedirectPath{
+		Path: "ab",
+		URL:  "https://example.com",
+		UTM:  &models.UTM{Preset: "spring", UTMParams: models.UTMParams{Campaign: "spring-2026"}},
+	}
+
+	t.Run("Redirect overrides preset", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+		got, _ := url.Parse(applyUTM(nil, req, redirect, redirect.URL))
+		query := got.Query()
+		if query.Get("utm_source") != "newsletter" || query.Get("utm_medium") != "email" || query.Get("utm_campaign") != "spring-2026" {
+			t.Errorf("unexpected query: %v", query)
+		}
+	})
+
+	t.Run("QR scans use qr medium", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/ab?src=qr", nil)
+		got, _ := url.Parse(applyUTM(nil, req, redirect, redirect.URL))
+		if medium := got.Query().Get("utm_medium"); medium != "qr" {
+			t.Errorf("expected utm_medium=qr, got %q", medium)
+		}
+	})
+
+	t.Run("No settings leaves target untouched", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/ab?src=qr", nil)
+		if got := applyUTM(nil, req, models.RedirectPath{URL: "https://example.com"}, "https://example.com"); got != "https://example.com" {
+			t.Errorf("expected untouched target, got %q", got)
+		}
+	})
+
+	t.Run("Presets are read once", func(t *testing.T) {
+		forgetCampaign("spring")
+		reads = 0
+		req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+		applyUTM(nil, req, redirect, redirect.URL)
+		applyUTM(nil, req, redirect, redirect.URL)
+		if reads != 1 {
+			t.Errorf("expected the preset to be read once, got %d reads", reads)
+		}
+	})
+}
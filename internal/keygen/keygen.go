@@ -0,0 +1,156 @@
+// Package keygen generates random short keys for redirects created without a path
+package keygen
+
+import (
+	"crypto/rand"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/NorskHelsenett/shorty/internal/config"
+	"github.com/spf13/viper"
+)
+
+const (
+	// DefaultAlphabet is used when KEY_ALPHABET is not set
+	DefaultAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	// DefaultLength is used when KEY_LENGTH is not set
+	DefaultLength = 6
+
+	// ambiguousCharacters are easily mixed up when a key is read aloud or printed
+	ambiguousCharacters = "0Oo1lI"
+	// attemptsPerLength is how many keys are tried before the length grows
+	attemptsPerLength = 5
+	// maxExtraLength is how much longer than the configured length a key may grow
+	maxExtraLength = 4
+)
+
+// ErrNoKeyAvailable is returned when no free key was found within the allowed lengths
+var ErrNoKeyAvailable = errors.New("no free key could be generated")
+
+// blockedWords keeps generated keys free of offensive words. Matching is case-insensitive
+// and applies to any part of the key. Extra words can be added with KEY_BLOCKLIST.
+var blockedWords = []string{
+	"anal", "arse", "ass", "bitch", "butt", "cock", "crap", "cum", "cunt", "dick",
+	"fag", "fuck", "hore", "jævl", "kuk", "fitte", "nazi", "nigg", "penis", "piss",
+	"porn", "pule", "pussy", "rape", "sex", "shit", "slut", "tits", "twat", "whore",
+}
+
+// Options controls how keys are generated
+type Options struct {
+	Length           int
+	Alphabet         string
+	ExcludeAmbiguous bool
+	Blocklist        []string
+}
+
+// OptionsFromConfig reads the key generation settings: KEY_LENGTH, KEY_ALPHABET,
+// KEY_EXCLUDE_AMBIGUOUS and KEY_BLOCKLIST. When keys are case-insensitive
+// (KEY_FOLD_CASE) the alphabet is reduced to lowercase.
+func OptionsFromConfig() Options {
+	opts := Options{
+		Length:           viper.GetInt("KEY_LENGTH"),
+		Alphabet:         viper.GetString("KEY_ALPHABET"),
+		ExcludeAmbiguous: viper.GetBool("KEY_EXCLUDE_AMBIGUOUS"),
+		Blocklist:        append(slices.Clone(blockedWords), config.GetList("KEY_BLOCKLIST")...),
+	}
+	if viper.GetBool("KEY_FOLD_CASE") {
+		opts.Alphabet = strings.ToLower(opts.Alphabet)
+		if opts.Alphabet == "" {
+			opts.Alphabet = strings.ToLower(DefaultAlphabet)
+		}
+	}
+	return opts
+}
+
+// Generate returns a random key that is not taken. Keys that are taken, or contain a
+// blocked word, are retried a few times before the length grows by one character.
+func Generate(opts Options, taken func(key string) (bool, error)) (string, error) {
+	alphabet := opts.alphabet()
+	length := opts.Length
+	if length <= 0 {
+		length = DefaultLength
+	}
+
+	for ; length <= opts.maxLength(); length++ {
+		for range attemptsPerLength {
+			key, err := randomKey(alphabet, length)
+			if err != nil {
+				return "", err
+			}
+			if opts.blocked(key) {
+				continue
+			}
+
+			isTaken, err := taken(key)
+			if err != nil {
+				return "", err
+			}
+			if !isTaken {
+				return key, nil
+			}
+		}
+	}
+	return "", ErrNoKeyAvailable
+}
+
+// alphabet returns the distinct characters keys are built from
+func (o Options) alphabet() []rune {
+	source := o.Alphabet
+	if source == "" {
+		source = DefaultAlphabet
+	}
+
+	var alphabet []rune
+	for _, r := range source {
+		if o.ExcludeAmbiguous && strings.ContainsRune(ambiguousCharacters, r) {
+			continue
+		}
+		if !slices.Contains(alphabet, r) {
+			alphabet = append(alphabet, r)
+		}
+	}
+	return alphabet
+}
+
+// maxLength returns the longest key Generate will try
+func (o Options) maxLength() int {
+	if o.Length <= 0 {
+		return DefaultLength + maxExtraLength
+	}
+	return o.Length + maxExtraLength
+}
+
+// blocked reports whether a key contains a blocked word or starts with a character keys may not start with
+func (o Options) blocked(key string) bool {
+	if strings.HasPrefix(key, "-") || strings.HasPrefix(key, "_") {
+		return true
+	}
+
+	lower := strings.ToLower(key)
+	for _, word := range o.Blocklist {
+		if word != "" && strings.Contains(lower, strings.ToLower(word)) {
+			return true
+		}
+	}
+	return false
+}
+
+// randomKey builds a key of the given length from the alphabet using crypto/rand
+func randomKey(alphabet []rune, length int) (string, error) {
+	if len(alphabet) == 0 {
+		return "", errors.New("key alphabet is empty")
+	}
+
+	max := big.NewInt(int64(len(alphabet)))
+	key := make([]rune, length)
+	for i := range key {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		key[i] = alphabet[n.Int64()]
+	}
+	return string(key), nil
+}
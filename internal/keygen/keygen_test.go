@@ -0,0 +1,112 @@
+package keygen
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	free := func(string) (bool, error) { return false, nil }
+
+	tests := []struct {
+		name  string
+		opts  Options
+		check func(t *testing.T, key string)
+	}{
+		{
+			name: "Default length and alphabet",
+			opts: Options{},
+			check: func(t *testing.T, key string) {
+				if len(key) != DefaultLength {
+					t.Errorf("expected length %d, got %q", DefaultLength, key)
+				}
+			},
+		},
+		{
+			name: "Custom length and alphabet",
+			opts: Options{Length: 10, Alphabet: "ab"},
+			check: func(t *testing.T, key string) {
+				if len(key) != 10 || strings.Trim(key, "ab") != "" {
+					t.Errorf("expected 10 characters from \"ab\", got %q", key)
+				}
+			},
+		},
+		{
+			name: "Ambiguous characters excluded",
+			opts: Options{Length: 20, Alphabet: "0Oo1lIx", ExcludeAmbiguous: true},
+			check: func(t *testing.T, key string) {
+				if key != strings.Repeat("x", 20) {
+					t.Errorf("expected only unambiguous characters, got %q", key)
+				}
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			key, err := Generate(tc.opts, free)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			tc.check(t, key)
+		})
+	}
+}
+
+func TestGenerateGrowsLengthOnCollision(t *testing.T) {
+	calls := 0
+	taken := func(key string) (bool, error) {
+		calls++
+		return len(key) < 5, nil
+	}
+
+	key, err := Generate(Options{Length: 3}, taken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(key) != 5 {
+		t.Errorf("expected key of length 5, got %q", key)
+	}
+	if calls != 2*attemptsPerLength+1 {
+		t.Errorf("expected %d attempts, got %d", 2*attemptsPerLength+1, calls)
+	}
+}
+
+func TestGenerateExhausted(t *testing.T) {
+	taken := func(string) (bool, error) { return true, nil }
+
+	if _, err := Generate(Options{Length: 2}, taken); !errors.Is(err, ErrNoKeyAvailable) {
+		t.Errorf("expected ErrNoKeyAvailable, got %v", err)
+	}
+}
+
+func TestGenerateTakenError(t *testing.T) {
+	lookupErr := errors.New("redis down")
+	taken := func(string) (bool, error) { return false, lookupErr }
+
+	if _, err := Generate(Options{}, taken); !errors.Is(err, lookupErr) {
+		t.Errorf("expected lookup error, got %v", err)
+	}
+}
+
+func TestBlocked(t *testing.T) {
+	opts := Options{Blocklist: []string{"bad"}}
+
+	tests := []struct {
+		key  string
+		want bool
+	}{
+		{key: "xBaDx", want: true},
+		{key: "-abcd", want: true},
+		{key: "_abcd", want: true},
+		{key: "ab-cd", want: false},
+		{key: "goodkey", want: false},
+	}
+
+	for _, tc := range tests {
+		if got := opts.blocked(tc.key); got != tc.want {
+			t.Errorf("blocked(%q) = %v, want %v", tc.key, got, tc.want)
+		}
+	}
+}
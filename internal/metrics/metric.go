@@ -12,12 +12,59 @@ import (
 )
 
 var (
+	// RequestCount only has labels with a few fixed values, so the number of series stays bounded.
+	// Clicks per link are kept in the link statistics instead.
 	RequestCount = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
-			Name: "http_requests_total",
-			Help: "Number of HTTP-request per month",
+			Name: "http_redirects_total",
+			Help: "Number of redirect requests from people, by status class, result and redirect mode",
+		},
+		[]string{"status_class", "result", "mode"},
+	)
+
+	BotRequestCount = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_bot_requests_total",
+			Help: "Number of redirect requests from bots, link previewers and scanners, by why they were classified as automated",
+		},
+		[]string{"reason"},
+	)
+
+	ClickEventsDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "click_events_dropped_total",
+			Help: "Number of click events dropped because the click queue was full or closed",
+		},
+	)
+
+	ClickEventsFailed = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "click_events_failed_total",
+			Help: "Number of click events lost because writing their batch to redis failed",
+		},
+	)
+
+	TokenValidationDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "token_validation_duration_seconds",
+			Help:    "Histogram of the time it takes to validate a bearer token",
+			Buckets: prometheus.DefBuckets,
+		},
+	)
+
+	TokenValidationFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "token_validation_failures_total",
+			Help: "Number of bearer tokens that were rejected, by reason",
+		},
+		[]string{"reason"},
+	)
+
+	OIDCDiscoveryFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "oidc_discovery_failures_total",
+			Help: "Number of failed attempts to load the OIDC provider configuration",
 		},
-		[]string{"url_path", "year_month"},
 	)
 
 	ResponseTimeHistogram = prometheus.NewHistogram(
@@ -29,8 +76,45 @@ var (
 	)
 )
 
+// Results of a redirect request
+const (
+	ResultFound    = "found"
+	ResultNotFound = "not_found"
+	ResultPaused   = "paused"
+)
+
+// Redirect modes, by how the destination was chosen
+const (
+	ModeSingle   = "single"
+	ModeTargets  = "targets"
+	ModeDevice   = "device"
+	ModeLanguage = "language"
+	ModeNone     = "none"
+)
+
+// Reasons a bearer token is rejected
+const (
+	TokenMissing             = "missing"
+	TokenExpired             = "expired"
+	TokenInvalid             = "invalid"
+	TokenClaims              = "claims"
+	TokenNoEmail             = "no_email"
+	TokenProviderUnavailable = "provider_unavailable"
+)
+
+// StatusClass returns the class of an HTTP status code, such as 3xx
+func StatusClass(code int) string {
+	return fmt.Sprintf("%dxx", code/100)
+}
+
 func InitMetrics() {
 	prometheus.MustRegister(RequestCount)
+	prometheus.MustRegister(BotRequestCount)
+	prometheus.MustRegister(ClickEventsDropped)
+	prometheus.MustRegister(ClickEventsFailed)
+	prometheus.MustRegister(TokenValidationDuration)
+	prometheus.MustRegister(TokenValidationFailures)
+	prometheus.MustRegister(OIDCDiscoveryFailures)
 	prometheus.MustRegister(ResponseTimeHistogram)
 }
 
@@ -38,6 +122,15 @@ func InitMetrics() {
 // and ensures proper cleanup during application shutdown
 func CleanupMetrics() {
 	prometheus.Unregister(RequestCount)
+	if topLinks != nil {
+		prometheus.Unregister(topLinks)
+	}
+	prometheus.Unregister(BotRequestCount)
+	prometheus.Unregister(ClickEventsDropped)
+	prometheus.Unregister(ClickEventsFailed)
+	prometheus.Unregister(TokenValidationDuration)
+	prometheus.Unregister(TokenValidationFailures)
+	prometheus.Unregister(OIDCDiscoveryFailures)
 	prometheus.Unregister(ResponseTimeHistogram)
 }
 
@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"github.com/NorskHelsenett/shorty/internal/models"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TopLinksFunc returns the links with the most all-time clicks, most clicked first
+type TopLinksFunc func(limit int) ([]models.LinkClicks, error)
+
+var topLinks *topLinksCollector
+
+// topLinksCollector exports the clicks of the most clicked links only, read from the
+// link statistics on every scrape, so the number of series never exceeds the limit
+type topLinksCollector struct {
+	limit int
+	top   TopLinksFunc
+	desc  *prometheus.Desc
+}
+
+// RegisterTopLinks exports the all-time clicks of the limit most clicked links
+func RegisterTopLinks(limit int, top TopLinksFunc) {
+	topLinks = &topLinksCollector{
+		limit: limit,
+		top:   top,
+		desc: prometheus.NewDesc(
+			"shorty_top_link_clicks",
+			"All-time clicks from people of the most clicked links",
+			[]string{"url_path"}, nil,
+		),
+	}
+	prometheus.MustRegister(topLinks)
+}
+
+func (c *topLinksCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *topLinksCollector) Collect(ch chan<- prometheus.Metric) {
+	links, err := c.top(c.limit)
+	if err != nil {
+		rlog.Error("Failed to get most clicked links", err)
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	for _, link := range links {
+		ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(link.Clicks), "/"+link.Path)
+	}
+}
@@ -3,11 +3,15 @@ package middleware
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
+	"slices"
 	"strings"
 	"time"
 
+	"github.com/NorskHelsenett/shorty/internal/metrics"
+	"github.com/NorskHelsenett/shorty/internal/models"
 	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
 
 	"github.com/NorskHelsenett/ror/pkg/rlog"
@@ -17,9 +21,15 @@ import (
 	"github.com/spf13/viper"
 )
 
-// User represents an authenticated user
+// User represents an authenticated user. Tokens of service accounts have no email,
+// and are recognised from their client id (client_id or azp) or subject instead.
 type User struct {
-	Email string `json:"email"`
+	Email           string `json:"email"`
+	Subject         string `json:"sub"`
+	ClientID        string `json:"client_id"`
+	AuthorizedParty string `json:"azp"`
+	// Groups are the values of the groups claim in OIDC_GROUPS_CLAIM
+	Groups []string `json:"-"`
 }
 
 // AuthenticationMiddlewareWrapper creates a mux-compatible middleware for authentication
@@ -36,6 +46,7 @@ func AuthenticationMiddleware(next http.Handler, rdb *redis.Client) http.Handler
 		authHeader := r.Header.Get("Authorization")
 		if !strings.HasPrefix(authHeader, "Bearer ") {
 			rlog.Info("Authentication failed: Missing or invalid Authorization header")
+			metrics.TokenValidationFailures.WithLabelValues(metrics.TokenMissing).Inc()
 			http.Error(w, "Unauthorized: Missing or invalid Authorization header", http.StatusUnauthorized)
 			return
 		}
@@ -43,16 +54,89 @@ func AuthenticationMiddleware(next http.Handler, rdb *redis.Client) http.Handler
 		// Extract the token
 		token := strings.TrimPrefix(authHeader, "Bearer ")
 
+		// Personal access tokens are checked against their stored hash, other tokens with the OIDC provider
+		if redisdb.IsAccessToken(token) {
+			accessToken, err := redisdb.ValidateAccessToken(rdb, token)
+			if err != nil && !errors.Is(err, redisdb.ErrInvalidAccessToken) && !errors.Is(err, redisdb.ErrAccessTokenExpired) {
+				rlog.Error("Failed to look up access token", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+			if err != nil {
+				reason := metrics.TokenInvalid
+				if errors.Is(err, redisdb.ErrAccessTokenExpired) {
+					reason = metrics.TokenExpired
+				}
+				metrics.TokenValidationFailures.WithLabelValues(reason).Inc()
+				rlog.Error("Authentication with access token failed", err)
+				http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
+				return
+			}
+
+			if !allowedByScopes(r, accessToken.Scopes) {
+				http.Error(w, "Forbidden: Access token does not have the write scope", http.StatusForbidden)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserKey, accessToken.Owner)
+			ctx = context.WithValue(ctx, TokenScopesKey, accessToken.Scopes)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
 		// Validate the token and extract user information
-		user, err := validateAccessToken(token)
+		user, err := validateAccessToken(r.Context(), token)
+		if errors.Is(err, ErrProviderUnavailable) {
+			rlog.Error("Authentication failed", err)
+			http.Error(w, "Service Unavailable: identity provider not available", http.StatusServiceUnavailable)
+			return
+		}
 		if err != nil {
 			rlog.Error("Authentication failed", err)
 			http.Error(w, fmt.Sprintf("Unauthorized: %v", err), http.StatusUnauthorized)
 			return
 		}
 
+		// Tokens without an email belong to service accounts, registered by admins
+		if user.Email == "" {
+			clientID := user.ClientID
+			if clientID == "" {
+				clientID = user.AuthorizedParty
+			}
+			account, err := redisdb.FindServiceAccount(rdb, clientID, user.Subject)
+			if errors.Is(err, redisdb.ErrServiceAccountNotFound) {
+				metrics.TokenValidationFailures.WithLabelValues(metrics.TokenNoEmail).Inc()
+				rlog.Info("Authentication failed: token has no email and no service account", rlog.String("clientId", clientID), rlog.String("subject", user.Subject))
+				http.Error(w, "Unauthorized: token does not contain an email claim and is not a registered service account", http.StatusUnauthorized)
+				return
+			}
+			if err != nil {
+				rlog.Error("Failed to look up service account", err)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+				return
+			}
+
+			if !allowedByScopes(r, account.Scopes) {
+				http.Error(w, "Forbidden: Service account does not have the write scope", http.StatusForbidden)
+				return
+			}
+
+			rlog.Debug("Service account authenticated", rlog.String("name", account.Name))
+			ctx := context.WithValue(r.Context(), UserKey, redisdb.ServiceUser(account.Name))
+			ctx = context.WithValue(ctx, TokenScopesKey, account.Scopes)
+			ctx = context.WithValue(ctx, ServiceAccountKey, account)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+
+		// Owners who keep signing in are not reported as gone
+		if err := redisdb.RecordSignIn(rdb, user.Email); err != nil {
+			rlog.Error("Failed to record sign-in", err, rlog.String("email", user.Email))
+		}
+
 		// Add user information to the request context
 		ctx := context.WithValue(r.Context(), UserKey, user.Email)
+		ctx = context.WithValue(ctx, GroupsKey, user.Groups)
 		r = r.WithContext(ctx)
 
 		// Continue to the next handler
@@ -60,34 +144,32 @@ func AuthenticationMiddleware(next http.Handler, rdb *redis.Client) http.Handler
 	})
 }
 
-// validateAccessToken verifies an OIDC token and extracts user information
-func validateAccessToken(token string) (User, error) {
-	ctx := context.Background()
+// validateAccessToken verifies an OIDC token with the cached provider and extracts user information
+func validateAccessToken(ctx context.Context, token string) (User, error) {
+	start := time.Now()
+	defer func() { metrics.TokenValidationDuration.Observe(time.Since(start).Seconds()) }()
 
-	// Initialize an OIDC provider
-	provider, err := oidc.NewProvider(ctx, viper.GetString("OIDC_PROVIDER_URL"))
-	if err != nil {
-		rlog.Error("OIDC provider initialization failed", err)
-		return User{}, fmt.Errorf("could not initialize OIDC provider: %w", err)
+	tokenVerifier := verifier.Load()
+	if tokenVerifier == nil {
+		metrics.TokenValidationFailures.WithLabelValues(metrics.TokenProviderUnavailable).Inc()
+		return User{}, ErrProviderUnavailable
 	}
 
-	// Create a token verifier
-	verifier := provider.Verifier(&oidc.Config{
-		ClientID:                   viper.GetString("OIDC_CLIENT_ID"),
-		SkipIssuerCheck:            viper.GetBool("SKIPISSUERCHECK"),
-		InsecureSkipSignatureCheck: viper.GetBool("INSECURE_SKIP_SIGNATURE_CHECK"),
-	})
-	idToken, err := verifier.Verify(ctx, token)
+	idToken, err := tokenVerifier.Verify(ctx, token)
 	if err != nil {
 		rlog.Error("Token verification failed", err)
-		if strings.Contains(err.Error(), "expired") {
+		var expired *oidc.TokenExpiredError
+		if errors.As(err, &expired) {
+			metrics.TokenValidationFailures.WithLabelValues(metrics.TokenExpired).Inc()
 			return User{}, fmt.Errorf("token has expired: %w", err)
 		}
+		metrics.TokenValidationFailures.WithLabelValues(metrics.TokenInvalid).Inc()
 		return User{}, fmt.Errorf("invalid token: %w", err)
 	}
 
 	// Check if the token has expired
 	if idToken.Expiry.Before(time.Now()) {
+		metrics.TokenValidationFailures.WithLabelValues(metrics.TokenExpired).Inc()
 		return User{}, fmt.Errorf("token has expired")
 	}
 
@@ -95,17 +177,25 @@ func validateAccessToken(token string) (User, error) {
 	var user User
 	if err := idToken.Claims(&user); err != nil {
 		rlog.Error("Failed to parse user claims", err)
+		metrics.TokenValidationFailures.WithLabelValues(metrics.TokenClaims).Inc()
 		return User{}, fmt.Errorf("unable to parse user claims: %w", err)
 	}
 
-	if user.Email == "" {
-		return User{}, fmt.Errorf("token does not contain an email claim")
+	var claims map[string]any
+	if err := idToken.Claims(&claims); err == nil {
+		user.Groups = claimValues(claims, viper.GetString("OIDC_GROUPS_CLAIM"))
 	}
 
-	rlog.Debug("User authenticated successfully", rlog.String("email", user.Email))
+	rlog.Debug("Token validated successfully", rlog.String("email", user.Email), rlog.String("subject", user.Subject))
 	return user, nil
 }
 
+// allowedByScopes reports whether a token with the given scopes may make the request.
+// Without the write scope only reading is allowed.
+func allowedByScopes(r *http.Request, scopes []string) bool {
+	return slices.Contains(scopes, models.ScopeWrite) || r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
 // AddAdminStatusMiddlewareWrapper creates a mux-compatible middleware for adding admin status
 func AddAdminStatusMiddlewareWrapper(rdb *redis.Client) mux.MiddlewareFunc {
 	return func(next http.Handler) http.Handler {
@@ -129,17 +219,39 @@ func AddAdminStatusMiddleware(next http.Handler, rdb *redis.Client) http.Handler
 			return
 		}
 
-		// Check if user is an admin
-		isAdminUser = redisdb.AdminUserExists(rdb, email)
+		// Check if user is an admin, from the admin list or their groups, or an admin of the requested domain only.
+		// Service accounts are admins with the admin scope. Personal access tokens only carry the admin
+		// rights of their owner with the admin scope.
+		var grants, domainAdmin []string
+		if account, ok := r.Context().Value(ServiceAccountKey).(models.ServiceAccount); ok {
+			grants, domainAdmin = []string{}, []string{}
+			if slices.Contains(account.Scopes, models.ScopeAdmin) {
+				grants = append(grants, grantScope+models.ScopeAdmin)
+			}
+		} else {
+			groups, _ := r.Context().Value(GroupsKey).([]string)
+			grants = adminGrants(rdb, email, groups)
+			domainAdmin = domainGrants(GetDomain(r), email)
+			if scopes, ok := r.Context().Value(TokenScopesKey).([]string); ok && !slices.Contains(scopes, models.ScopeAdmin) {
+				grants, domainAdmin = []string{}, []string{}
+			}
+		}
+		isAdminUser = len(grants) > 0
+		isDomainAdmin := len(domainAdmin) > 0
+		grants = append(grants, domainAdmin...)
 		rlog.Debug("Setting admin status",
 			rlog.Any("isAdmin", isAdminUser),
+			rlog.Any("isDomainAdmin", isDomainAdmin),
+			rlog.Any("grants", grants),
 			rlog.String("email", email))
 
 		// Add admin status to the response header
-		w.Header().Set("X-Is-Admin", fmt.Sprintf("%t", isAdminUser))
+		w.Header().Set("X-Is-Admin", fmt.Sprintf("%t", isAdminUser || isDomainAdmin))
 
 		// Add admin status to request context
 		ctx := context.WithValue(r.Context(), IsAdminKey, isAdminUser)
+		ctx = context.WithValue(ctx, IsDomainAdminKey, isDomainAdmin)
+		ctx = context.WithValue(ctx, AdminGrantsKey, grants)
 		r = r.WithContext(ctx)
 
 		// Continue to the next handler
@@ -158,9 +270,13 @@ func IsOwnerMiddlewareWrapper(rdb *redis.Client) mux.MiddlewareFunc {
 // Only the path owner (creator) and admins can modify or delete paths
 func IsOwnerMiddleware(next http.Handler, rdb *redis.Client) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		// Skip ownership check for GET and POST requests
+		// Get the path ID from route parameters
+		params := mux.Vars(r)
+		pathID := params["id"]
+
+		// Skip ownership check for GET and POST requests on the collection
 		method := r.Method
-		if method == http.MethodGet || method == http.MethodPost {
+		if pathID == "" && (method == http.MethodGet || method == http.MethodPost) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -173,9 +289,6 @@ func IsOwnerMiddleware(next http.Handler, rdb *redis.Client) http.Handler {
 			return
 		}
 
-		// Get the path ID from route parameters
-		params := mux.Vars(r)
-		pathID := params["id"]
 		if pathID == "" {
 			rlog.Warn("Path ID not found in request")
 			http.Error(w, "Bad Request: Path ID not found", http.StatusBadRequest)
@@ -184,6 +297,10 @@ func IsOwnerMiddleware(next http.Handler, rdb *redis.Client) http.Handler {
 
 		// Get the path owner from database
 		pathOwner, err := redisdb.GetPathOwner(rdb, pathID)
+		if errors.Is(err, redisdb.ErrOwnerNotFound) || errors.Is(err, redisdb.ErrURLNotFound) {
+			http.NotFound(w, r)
+			return
+		}
 		if err != nil {
 			rlog.Error("Failed to get path owner", err,
 				rlog.String("pathID", pathID),
@@ -0,0 +1,205 @@
+package middleware
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redismock/v8"
+	"github.com/gorilla/mux"
+)
+
+func TestAuthenticationMiddlewareAccessToken(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	sum := sha256.Sum256([]byte("secret"))
+	stored := map[string]string{
+		"name": "ci", "owner": "user@example.com", "scopes": `["read"]`, "hash": hex.EncodeToString(sum[:]),
+		"createdTime": "2025-03-01T00:00:00Z", "expiresTime": time.Now().UTC().Add(time.Hour).Format(time.RFC3339),
+		"lastUsed": time.Now().UTC().Format(time.RFC3339),
+	}
+
+	tests := []struct {
+		name       string
+		method     string
+		token      string
+		found      bool
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "Read with read scope", method: http.MethodGet, token: "shorty_pat_abc_secret", found: true, wantStatus: http.StatusOK, wantUser: "user@example.com"},
+		{name: "Write without write scope", method: http.MethodPost, token: "shorty_pat_abc_secret", found: true, wantStatus: http.StatusForbidden},
+		{name: "Wrong secret", method: http.MethodGet, token: "shorty_pat_abc_guess", found: true, wantStatus: http.StatusUnauthorized},
+		{name: "Revoked token", method: http.MethodGet, token: "shorty_pat_abc_secret", wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.found {
+				mock.ExpectHGetAll("token:abc").SetVal(stored)
+			} else {
+				mock.ExpectHGetAll("token:abc").SetVal(map[string]string{})
+			}
+
+			var gotUser string
+			var gotScopes []string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUser, _ = r.Context().Value(UserKey).(string)
+				gotScopes, _ = r.Context().Value(TokenScopesKey).([]string)
+			})
+
+			req := httptest.NewRequest(tc.method, "/v1/", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.token)
+			rr := httptest.NewRecorder()
+			AuthenticationMiddleware(next, db).ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if gotUser != tc.wantUser {
+				t.Errorf("expected user %q, got %q", tc.wantUser, gotUser)
+			}
+			if tc.wantUser != "" && len(gotScopes) != 1 {
+				t.Errorf("expected the token scopes in the context, got %v", gotScopes)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAuthenticationMiddlewareServiceAccount(t *testing.T) {
+	server, _ := testProvider(t, nil)
+	if err := LoadOIDC(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	db, mock := redismock.NewClientMock()
+	token := func(claims map[string]any) string {
+		claims["iss"], claims["aud"], claims["exp"] = server.URL, "shortyfront", time.Now().Add(time.Hour).Unix()
+		return testToken(t, claims)
+	}
+	service := map[string]string{"owner": "team@example.com", "clientId": "ci-client", "scopes": `["read"]`, "dailyLinkLimit": "50"}
+
+	tests := []struct {
+		name       string
+		method     string
+		claims     map[string]any
+		expect     func()
+		wantStatus int
+		wantUser   string
+	}{
+		{name: "Registered client id", method: http.MethodGet, claims: map[string]any{"sub": "123", "azp": "ci-client"},
+			expect: func() {
+				mock.ExpectGet("service-client:ci-client").SetVal("ci")
+				mock.ExpectHGetAll("service:ci").SetVal(service)
+			}, wantStatus: http.StatusOK, wantUser: "service:ci"},
+		{name: "Registered subject", method: http.MethodGet, claims: map[string]any{"sub": "123", "client_id": "other"},
+			expect: func() {
+				mock.ExpectGet("service-client:other").RedisNil()
+				mock.ExpectGet("service-subject:123").SetVal("ci")
+				mock.ExpectHGetAll("service:ci").SetVal(service)
+			}, wantStatus: http.StatusOK, wantUser: "service:ci"},
+		{name: "Write without write scope", method: http.MethodPost, claims: map[string]any{"sub": "123", "azp": "ci-client"},
+			expect: func() {
+				mock.ExpectGet("service-client:ci-client").SetVal("ci")
+				mock.ExpectHGetAll("service:ci").SetVal(service)
+			}, wantStatus: http.StatusForbidden},
+		{name: "Unknown service", method: http.MethodGet, claims: map[string]any{"sub": "456", "azp": "unknown"},
+			expect: func() {
+				mock.ExpectGet("service-client:unknown").RedisNil()
+				mock.ExpectGet("service-subject:456").RedisNil()
+			}, wantStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.expect()
+
+			var gotUser string
+			var gotAccount models.ServiceAccount
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotUser, _ = r.Context().Value(UserKey).(string)
+				gotAccount, _ = r.Context().Value(ServiceAccountKey).(models.ServiceAccount)
+			})
+
+			req := httptest.NewRequest(tc.method, "/v1/", nil)
+			req.Header.Set("Authorization", "Bearer "+token(tc.claims))
+			rr := httptest.NewRecorder()
+			AuthenticationMiddleware(next, db).ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if gotUser != tc.wantUser {
+				t.Errorf("expected user %q, got %q", tc.wantUser, gotUser)
+			}
+			if tc.wantUser != "" && (gotAccount.Owner != "team@example.com" || gotAccount.DailyLinkLimit != 50) {
+				t.Errorf("expected the service account in the context, got %+v", gotAccount)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestIsOwnerMiddleware(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	tests := []struct {
+		name       string
+		method     string
+		id         string
+		setup      func()
+		wantStatus int
+		wantOwner  bool
+	}{
+		{name: "Collection is not checked", method: http.MethodGet, wantStatus: http.StatusOK},
+		{name: "Owner of the link", method: http.MethodGet, id: "mine", wantStatus: http.StatusOK, wantOwner: true,
+			setup: func() { mock.ExpectHGet("path:mine", "createdBy").SetVal("user@example.com") }},
+		{name: "Link of someone else", method: http.MethodDelete, id: "theirs", wantStatus: http.StatusOK,
+			setup: func() { mock.ExpectHGet("path:theirs", "createdBy").SetVal("other@example.com") }},
+		{name: "Unknown link", method: http.MethodGet, id: "missing", wantStatus: http.StatusNotFound,
+			setup: func() {
+				mock.ExpectHGet("path:missing", "createdBy").RedisNil()
+				mock.ExpectGet("alias:missing").RedisNil()
+			}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.setup != nil {
+				tc.setup()
+			}
+
+			var gotOwner bool
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotOwner, _ = r.Context().Value(IsOwnerKey).(bool)
+			})
+
+			req := httptest.NewRequest(tc.method, "/v1/"+tc.id, nil)
+			req = mux.SetURLVars(req, map[string]string{"id": tc.id})
+			req = req.WithContext(context.WithValue(req.Context(), UserKey, "user@example.com"))
+			rr := httptest.NewRecorder()
+			IsOwnerMiddleware(next, db).ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d: %s", tc.wantStatus, rr.Code, rr.Body.String())
+			}
+			if gotOwner != tc.wantOwner {
+				t.Errorf("expected owner %v, got %v", tc.wantOwner, gotOwner)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/NorskHelsenett/shorty/internal/bots"
+	"github.com/spf13/viper"
+)
+
+// BotMiddleware classifies requests from crawlers, link previewers and scanners, so
+// handlers can count them apart from human clicks. Disabled with BOT_FILTER_ENABLED=false.
+func BotMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !viper.GetBool("BOT_FILTER_ENABLED") {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), BotKey, bots.Classify(r, ClientIP(r)))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// BotReason returns why a request was classified as automated, or an empty string for people
+func BotReason(r *http.Request) string {
+	reason, _ := r.Context().Value(BotKey).(string)
+	return reason
+}
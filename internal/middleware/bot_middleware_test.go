@@ -0,0 +1,40 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/bots"
+	"github.com/spf13/viper"
+)
+
+func TestBotMiddleware(t *testing.T) {
+	t.Cleanup(func() { viper.Set("BOT_FILTER_ENABLED", nil) })
+
+	tests := []struct {
+		name    string
+		enabled bool
+		method  string
+		want    string
+	}{
+		{name: "HEAD request classified", enabled: true, method: http.MethodHead, want: bots.ReasonHead},
+		{name: "Filter disabled", enabled: false, method: http.MethodHead, want: ""},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("BOT_FILTER_ENABLED", tc.enabled)
+
+			var got string
+			handler := BotMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = BotReason(r)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tc.method, "/ab", nil))
+
+			if got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
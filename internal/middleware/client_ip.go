@@ -0,0 +1,80 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// DefaultTrustedProxies are the proxies trusted when TRUSTED_PROXIES is not set: loopback and
+// private networks, where ingress controllers and load balancers usually run
+const DefaultTrustedProxies = "127.0.0.0/8,::1/128,10.0.0.0/8,172.16.0.0/12,192.168.0.0/16,fc00::/7"
+
+// ClientIP returns the address of the client that made a request. Behind a proxy (TRUST_PROXY_HEADERS),
+// requests from an address in TRUSTED_PROXIES use the right-most address in X-Forwarded-For that is not
+// a trusted proxy, since the addresses left of it are set by the client. X-Real-IP is used without
+// X-Forwarded-For.
+func ClientIP(r *http.Request) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !viper.GetBool("TRUST_PROXY_HEADERS") {
+		return remote
+	}
+
+	trusted := trustedProxies()
+	if ip := net.ParseIP(remote); ip == nil || !isTrustedProxy(trusted, ip) {
+		return remote
+	}
+
+	if forwarded := r.Header.Values("X-Forwarded-For"); len(forwarded) > 0 {
+		addresses := strings.Split(strings.Join(forwarded, ","), ",")
+		for i := len(addresses) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(addresses[i]))
+			if ip == nil {
+				return remote
+			}
+			if i == 0 || !isTrustedProxy(trusted, ip) {
+				return ip.String()
+			}
+		}
+	}
+	if ip := net.ParseIP(strings.TrimSpace(r.Header.Get("X-Real-IP"))); ip != nil {
+		return ip.String()
+	}
+	return remote
+}
+
+// trustedProxies parses TRUSTED_PROXIES, comma separated address ranges in CIDR form or single addresses
+func trustedProxies() []*net.IPNet {
+	var networks []*net.IPNet
+	for _, entry := range strings.Split(viper.GetString("TRUSTED_PROXIES"), ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			if ip := net.ParseIP(entry); ip != nil {
+				networks = append(networks, &net.IPNet{IP: ip, Mask: net.CIDRMask(len(ip)*8, len(ip)*8)})
+			}
+			continue
+		}
+		if _, network, err := net.ParseCIDR(entry); err == nil {
+			networks = append(networks, network)
+		}
+	}
+	return networks
+}
+
+// isTrustedProxy reports whether an address belongs to one of the trusted proxy networks
+func isTrustedProxy(trusted []*net.IPNet, ip net.IP) bool {
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestClientIP(t *testing.T) {
+	viper.Set("TRUSTED_PROXIES", "192.0.2.0/24, 10.0.0.0/8, 2001:db8::5")
+	t.Cleanup(func() {
+		viper.Set("TRUST_PROXY_HEADERS", nil)
+		viper.Set("TRUSTED_PROXIES", nil)
+	})
+
+	tests := []struct {
+		name      string
+		trust     bool
+		remote    string
+		forwarded string
+		realIP    string
+		want      string
+	}{
+		{name: "Remote address", want: "192.0.2.1"},
+		{name: "Forwarded ignored without trust", forwarded: "203.0.113.7", want: "192.0.2.1"},
+		{name: "Right-most untrusted forwarded address", trust: true, forwarded: "198.51.100.9, 203.0.113.7, 10.0.0.1", want: "203.0.113.7"},
+		{name: "Single trusted proxy address", trust: true, remote: "[2001:db8::5]:443", forwarded: "203.0.113.7", want: "203.0.113.7"},
+		{name: "Only trusted proxies", trust: true, forwarded: "10.0.0.2, 10.0.0.1", want: "10.0.0.2"},
+		{name: "Forwarded ignored from untrusted peer", trust: true, remote: "198.51.100.1:1234", forwarded: "203.0.113.7", want: "198.51.100.1"},
+		{name: "Real IP header", trust: true, realIP: "2001:db8::1", want: "2001:db8::1"},
+		{name: "Invalid forwarded address", trust: true, forwarded: "unknown", want: "192.0.2.1"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("TRUST_PROXY_HEADERS", tc.trust)
+
+			req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+			if tc.remote != "" {
+				req.RemoteAddr = tc.remote
+			}
+			if tc.forwarded != "" {
+				req.Header.Set("X-Forwarded-For", tc.forwarded)
+			}
+			if tc.realIP != "" {
+				req.Header.Set("X-Real-IP", tc.realIP)
+			}
+
+			if got := ClientIP(req); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
@@ -14,4 +14,25 @@ const (
 
 	// UserKey stores the authenticated user's email
 	UserKey contextKey = "authenticatedUserEmail"
+
+	// IsDomainAdminKey represents whether the current user is an admin of the requested domain
+	IsDomainAdminKey contextKey = "isDomainAdmin"
+
+	// DomainKey stores the short link domain of the requested host
+	DomainKey contextKey = "domain"
+
+	// TokenScopesKey stores the scopes of the personal access token of the request, unset for OIDC tokens
+	TokenScopesKey contextKey = "tokenScopes"
+
+	// ServiceAccountKey stores the service account of the request, unset for people
+	ServiceAccountKey contextKey = "serviceAccount"
+
+	// GroupsKey stores the groups or roles of the authenticated user from their OIDC token
+	GroupsKey contextKey = "groups"
+
+	// AdminGrantsKey stores what makes the current user an admin, such as redis or group:<name>, empty for other users
+	AdminGrantsKey contextKey = "adminGrants"
+
+	// BotKey stores why a request was classified as automated, empty for people
+	BotKey contextKey = "bot"
 )
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/NorskHelsenett/shorty/internal/config"
+	"github.com/gorilla/mux"
+)
+
+// DomainMiddleware adds the short link domain of the requested host to the request context
+func DomainMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), DomainKey, config.DomainForHost(r.Host))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetDomain returns the short link domain of a request
+func GetDomain(r *http.Request) config.Domain {
+	if domain, ok := r.Context().Value(DomainKey).(config.Domain); ok {
+		return domain
+	}
+	return config.DomainForHost(r.Host)
+}
+
+// NamespaceMiddleware rewrites the {id} route parameter to the stored key in the namespace of the requested domain.
+// Keys from other namespaces cannot be addressed directly.
+func NamespaceMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		id, ok := vars["id"]
+		if !ok || id == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if strings.Contains(id, ":") {
+			http.NotFound(w, r)
+			return
+		}
+
+		vars = maps.Clone(vars)
+		vars["id"] = GetDomain(r).Key(id)
+		next.ServeHTTP(w, mux.SetURLVars(r, vars))
+	})
+}
@@ -0,0 +1,115 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/config"
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+)
+
+// loadTestDomains configures a default domain and a namespaced domain for the test
+func loadTestDomains(t *testing.T) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "domains.json")
+	data := `[
+		{"host": "k.nhn.no"},
+		{"host": "go.hr.no", "namespace": "hr", "fallbackUrl": "https://hr.no", "admins": ["Boss@hr.no"]}
+	]`
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("DOMAINS_CONFIG", path)
+	if err := config.LoadDomains(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() {
+		viper.Set("DOMAINS_CONFIG", "")
+		if err := config.LoadDomains(); err != nil {
+			t.Errorf("failed to restore default domains: %v", err)
+		}
+	})
+}
+
+func TestDomainMiddleware(t *testing.T) {
+	loadTestDomains(t)
+
+	tests := []struct {
+		name          string
+		host          string
+		wantHost      string
+		wantFallback  string
+		wantNamespace string
+	}{
+		{name: "Default domain", host: "k.nhn.no", wantHost: "k.nhn.no", wantFallback: "https://nhn.no"},
+		{name: "Namespaced domain with port", host: "go.hr.no:8880", wantHost: "go.hr.no", wantFallback: "https://hr.no", wantNamespace: "hr"},
+		{name: "Unknown host uses default", host: "localhost:8880", wantHost: "k.nhn.no", wantFallback: "https://nhn.no"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got config.Domain
+			handler := DomainMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetDomain(r)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/ab", nil)
+			req.Host = tc.host
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			if got.Host != tc.wantHost || got.FallbackURL != tc.wantFallback || got.Namespace != tc.wantNamespace {
+				t.Errorf("unexpected domain: %+v", got)
+			}
+		})
+	}
+
+	t.Run("Domain admins", func(t *testing.T) {
+		domain := config.DomainForHost("go.hr.no")
+		if !domain.IsAdmin("boss@hr.no") || config.DomainForHost("k.nhn.no").IsAdmin("boss@hr.no") {
+			t.Errorf("expected boss@hr.no to be admin of go.hr.no only")
+		}
+	})
+}
+
+func TestNamespaceMiddleware(t *testing.T) {
+	loadTestDomains(t)
+
+	tests := []struct {
+		name       string
+		host       string
+		id         string
+		wantID     string
+		wantStatus int
+	}{
+		{name: "Default domain keeps key", host: "k.nhn.no", id: "onboarding", wantID: "onboarding", wantStatus: http.StatusOK},
+		{name: "Namespaced domain prefixes key", host: "go.hr.no", id: "onboarding", wantID: "hr:onboarding", wantStatus: http.StatusOK},
+		{name: "Other namespaces cannot be addressed", host: "k.nhn.no", id: "hr:onboarding", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotID string
+			handler := NamespaceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotID = mux.Vars(r)["id"]
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/"+tc.id, nil)
+			req.Host = tc.host
+			req = mux.SetURLVars(req, map[string]string{"id": tc.id})
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tc.wantStatus {
+				t.Fatalf("expected status %d, got %d", tc.wantStatus, rr.Code)
+			}
+			if gotID != tc.wantID {
+				t.Errorf("expected id %q, got %q", tc.wantID, gotID)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/metrics"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/coreos/go-oidc/v3/oidc"
+	"github.com/spf13/viper"
+)
+
+const (
+	// oidcTimeout bounds each request to the identity provider, for discovery as well as signing keys
+	oidcTimeout = 10 * time.Second
+	// oidcMaxRetry caps the wait between attempts while the identity provider cannot be reached
+	oidcMaxRetry = 5 * time.Minute
+)
+
+// ErrProviderUnavailable is returned while the OIDC provider has not been loaded yet
+var ErrProviderUnavailable = errors.New("identity provider not available")
+
+// verifier is the token verifier of the last successfully loaded OIDC provider.
+// It fetches the signing keys itself, and again when a token is signed with a key it does not know.
+var verifier atomic.Pointer[oidc.IDTokenVerifier]
+
+// LoadOIDC discovers the OIDC provider and replaces the token verifier in use.
+// On error the previous verifier stays in use.
+func LoadOIDC(ctx context.Context) error {
+	client := &http.Client{Timeout: oidcTimeout}
+	provider, err := oidc.NewProvider(oidc.ClientContext(ctx, client), viper.GetString("OIDC_PROVIDER_URL"))
+	if err != nil {
+		metrics.OIDCDiscoveryFailures.Inc()
+		return fmt.Errorf("could not initialize OIDC provider: %w", err)
+	}
+
+	verifier.Store(provider.Verifier(&oidc.Config{
+		ClientID:                   viper.GetString("OIDC_CLIENT_ID"),
+		SkipIssuerCheck:            viper.GetBool("SKIPISSUERCHECK"),
+		InsecureSkipSignatureCheck: viper.GetBool("INSECURE_SKIP_SIGNATURE_CHECK"),
+	}))
+	rlog.Info("OIDC provider loaded", rlog.String("provider", viper.GetString("OIDC_PROVIDER_URL")))
+	return nil
+}
+
+// WatchOIDC loads the OIDC provider again every refresh interval, until ctx is done.
+// While it has never been loaded, or after a failed refresh, it retries sooner, starting at retry
+// and backing off up to the refresh interval. A refresh of zero only retries until the first success.
+func WatchOIDC(ctx context.Context, refresh time.Duration, retry time.Duration) {
+	if retry <= 0 {
+		retry = oidcTimeout
+	}
+	maxRetry := oidcMaxRetry
+	if refresh > 0 && refresh < maxRetry {
+		maxRetry = refresh
+	}
+
+	wait := retry
+	next := refresh
+	if verifier.Load() == nil {
+		next = retry
+	} else if refresh <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(next)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		if err := LoadOIDC(ctx); err != nil {
+			rlog.Error("Failed to load OIDC provider, retrying", err, rlog.String("retryIn", wait.String()))
+			timer.Reset(wait)
+			wait = min(wait*2, maxRetry)
+			continue
+		}
+		if refresh <= 0 {
+			return
+		}
+		wait = retry
+		timer.Reset(refresh)
+	}
+}
@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// testProvider serves OIDC discovery and counts the discovery requests. It fails while down is set.
+func testProvider(t *testing.T, down *atomic.Bool) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var discoveries atomic.Int32
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			discoveries.Add(1)
+			if down != nil && down.Load() {
+				http.Error(w, "unavailable", http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprintf(w, `{"issuer":%q,"jwks_uri":%q,"id_token_signing_alg_values_supported":["RS256"]}`, server.URL, server.URL+"/keys")
+		case "/keys":
+			fmt.Fprint(w, `{"keys":[]}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	viper.Set("OIDC_PROVIDER_URL", server.URL)
+	viper.Set("OIDC_CLIENT_ID", "shortyfront")
+	// Tokens in these tests are not signed, the signing keys are the concern of go-oidc
+	viper.Set("INSECURE_SKIP_SIGNATURE_CHECK", true)
+	t.Cleanup(func() {
+		viper.Set("OIDC_PROVIDER_URL", "")
+		viper.Set("OIDC_CLIENT_ID", "")
+		viper.Set("INSECURE_SKIP_SIGNATURE_CHECK", false)
+		verifier.Store(nil)
+	})
+	verifier.Store(nil)
+	return server, &discoveries
+}
+
+// testToken returns a token with the given claims and a signature that is not checked
+func testToken(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatal(err)
+	}
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"RS256","kid":"test"}`)) + "." + encode(payload) + "." + encode([]byte("signature"))
+}
+
+func TestValidateAccessToken(t *testing.T) {
+	server, discoveries := testProvider(t, nil)
+
+	if _, err := validateAccessToken(context.Background(), "token"); !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("expected ErrProviderUnavailable before the provider is loaded, got %v", err)
+	}
+
+	if err := LoadOIDC(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	valid := testToken(t, map[string]any{"iss": server.URL, "aud": "shortyfront", "exp": time.Now().Add(time.Hour).Unix(), "email": "user@example.com"})
+	for range 3 {
+		user, err := validateAccessToken(context.Background(), valid)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if user.Email != "user@example.com" {
+			t.Errorf("expected user@example.com, got %q", user.Email)
+		}
+	}
+	if got := discoveries.Load(); got != 1 {
+		t.Errorf("expected one discovery request, got %d", got)
+	}
+
+	tests := []struct {
+		name   string
+		claims map[string]any
+	}{
+		{name: "Expired", claims: map[string]any{"iss": server.URL, "aud": "shortyfront", "exp": time.Now().Add(-time.Hour).Unix(), "email": "user@example.com"}},
+		{name: "Other audience", claims: map[string]any{"iss": server.URL, "aud": "other", "exp": time.Now().Add(time.Hour).Unix(), "email": "user@example.com"}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := validateAccessToken(context.Background(), testToken(t, tc.claims)); err == nil {
+				t.Error("expected the token to be rejected")
+			}
+		})
+	}
+}
+
+func TestAuthenticationMiddlewareProviderUnavailable(t *testing.T) {
+	testProvider(t, nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/", nil)
+	req.Header.Set("Authorization", "Bearer token")
+	rr := httptest.NewRecorder()
+	AuthenticationMiddleware(http.NotFoundHandler(), nil).ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusServiceUnavailable {
+		t.Errorf("expected status %d, got %d", http.StatusServiceUnavailable, rr.Code)
+	}
+}
+
+func TestWatchOIDC(t *testing.T) {
+	var down atomic.Bool
+	down.Store(true)
+	_, discoveries := testProvider(t, &down)
+
+	if err := LoadOIDC(context.Background()); err == nil {
+		t.Fatal("expected discovery to fail while the provider is down")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		WatchOIDC(ctx, 0, 10*time.Millisecond)
+		close(done)
+	}()
+
+	time.Sleep(30 * time.Millisecond)
+	down.Store(false)
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the watcher to stop once the provider was loaded")
+	}
+	if verifier.Load() == nil {
+		t.Error("expected the provider to be loaded after retrying")
+	}
+	if got := discoveries.Load(); got < 3 {
+		t.Errorf("expected discovery to be retried, got %d requests", got)
+	}
+}
@@ -0,0 +1,110 @@
+package middleware
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/NorskHelsenett/shorty/internal/config"
+	redisdb "github.com/NorskHelsenett/shorty/internal/redis"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+)
+
+// Where admin rights come from, as set in ADMIN_SOURCE
+const (
+	// AdminSourceRedis only uses the admin list in redis
+	AdminSourceRedis = "redis"
+	// AdminSourceOIDC only uses the groups claim of the OIDC token, ignoring the admin list in redis
+	AdminSourceOIDC = "oidc"
+	// AdminSourceCombined makes users admins when either grants it
+	AdminSourceCombined = "combined"
+)
+
+// Prefixes of the reported sources of admin rights, followed by the group, domain or scope that granted them
+const (
+	grantGroup  = "group:"
+	grantDomain = "domain:"
+	grantScope  = "scope:"
+)
+
+// CheckAdminSource returns an error when ADMIN_SOURCE has an unknown value
+func CheckAdminSource() error {
+	source := viper.GetString("ADMIN_SOURCE")
+	if !slices.Contains([]string{AdminSourceRedis, AdminSourceOIDC, AdminSourceCombined}, source) {
+		return fmt.Errorf("unknown ADMIN_SOURCE %q, must be %s, %s or %s", source, AdminSourceRedis, AdminSourceOIDC, AdminSourceCombined)
+	}
+	return nil
+}
+
+// claimValues reads a claim holding a list of strings, or a single string. Nested claims,
+// such as realm_access.roles, are given as a path separated by dots.
+func claimValues(claims map[string]any, path string) []string {
+	var value any = claims
+	for _, name := range strings.Split(path, ".") {
+		object, ok := value.(map[string]any)
+		if !ok {
+			return nil
+		}
+		value = object[name]
+	}
+
+	switch value := value.(type) {
+	case string:
+		return []string{value}
+	case []any:
+		values := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// adminGroups returns the groups or roles in OIDC_ADMIN_GROUPS (comma separated) that make users admins
+func adminGroups() []string {
+	var groups []string
+	for _, group := range strings.Split(viper.GetString("OIDC_ADMIN_GROUPS"), ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			groups = append(groups, group)
+		}
+	}
+	return groups
+}
+
+// IsGroupGrant reports whether an admin grant comes from an OIDC group. Personal access tokens carry
+// no groups, so these grants never apply to requests with a token.
+func IsGroupGrant(grant string) bool {
+	return strings.HasPrefix(grant, grantGroup)
+}
+
+// adminGrants returns what makes a user an admin according to ADMIN_SOURCE: the admin list in redis
+// and the admin groups they are a member of. The user is an admin when any grant is returned.
+// Requests with a personal access token have no groups, so only the admin list counts for them.
+func adminGrants(rdb *redis.Client, email string, groups []string) []string {
+	source := viper.GetString("ADMIN_SOURCE")
+	grants := []string{}
+	if source != AdminSourceOIDC && redisdb.AdminUserExists(rdb, email) {
+		grants = append(grants, AdminSourceRedis)
+	}
+	if source != AdminSourceRedis {
+		for _, group := range adminGroups() {
+			if slices.Contains(groups, group) {
+				grants = append(grants, grantGroup+group)
+			}
+		}
+	}
+	return grants
+}
+
+// domainGrants returns the grant of a user listed as admin of the requested domain
+func domainGrants(domain config.Domain, email string) []string {
+	if domain.IsAdmin(email) {
+		return []string{grantDomain + domain.Host}
+	}
+	return []string{}
+}
@@ -0,0 +1,130 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redismock/v8"
+	"github.com/spf13/viper"
+)
+
+func TestClaimValues(t *testing.T) {
+	claims := map[string]any{
+		"groups":       []any{"shorty-admins", "staff", 42},
+		"role":         "editor",
+		"realm_access": map[string]any{"roles": []any{"admin"}},
+	}
+
+	tests := []struct {
+		name string
+		path string
+		want []string
+	}{
+		{name: "List of strings", path: "groups", want: []string{"shorty-admins", "staff"}},
+		{name: "Single string", path: "role", want: []string{"editor"}},
+		{name: "Nested claim", path: "realm_access.roles", want: []string{"admin"}},
+		{name: "Missing claim", path: "roles", want: nil},
+		{name: "Path through a string", path: "role.name", want: nil},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := claimValues(claims, tc.path); !slices.Equal(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestAdminGrants(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	viper.Set("OIDC_ADMIN_GROUPS", "shorty-admins, platform")
+	t.Cleanup(func() {
+		viper.Set("ADMIN_SOURCE", nil)
+		viper.Set("OIDC_ADMIN_GROUPS", nil)
+	})
+
+	tests := []struct {
+		name    string
+		source  string
+		inRedis bool
+		groups  []string
+		want    []string
+	}{
+		{name: "Redis only", source: AdminSourceRedis, inRedis: true, groups: []string{"platform"}, want: []string{"redis"}},
+		{name: "OIDC ignores the admin list", source: AdminSourceOIDC, groups: []string{"staff", "platform"}, want: []string{"group:platform"}},
+		{name: "Combined reports every source", source: AdminSourceCombined, inRedis: true, groups: []string{"shorty-admins"}, want: []string{"redis", "group:shorty-admins"}},
+		{name: "Combined without grants", source: AdminSourceCombined, groups: []string{"staff"}, want: []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			viper.Set("ADMIN_SOURCE", tc.source)
+			if tc.source != AdminSourceOIDC {
+				if tc.inRedis {
+					mock.ExpectGet("email:user@example.com").SetVal("user-id")
+				} else {
+					mock.ExpectGet("email:user@example.com").RedisNil()
+				}
+			}
+
+			if got := adminGrants(db, "user@example.com", tc.groups); !slices.Equal(got, tc.want) {
+				t.Errorf("expected %v, got %v", tc.want, got)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAddAdminStatusMiddlewareGroups(t *testing.T) {
+	db, _ := redismock.NewClientMock()
+	viper.Set("ADMIN_SOURCE", AdminSourceOIDC)
+	viper.Set("OIDC_ADMIN_GROUPS", "shorty-admins")
+	t.Cleanup(func() {
+		viper.Set("ADMIN_SOURCE", nil)
+		viper.Set("OIDC_ADMIN_GROUPS", nil)
+	})
+
+	tests := []struct {
+		name       string
+		scopes     []string
+		wantAdmin  bool
+		wantGrants []string
+	}{
+		{name: "Member of an admin group", wantAdmin: true, wantGrants: []string{"group:shorty-admins"}},
+		{name: "Access token without the admin scope", scopes: []string{models.ScopeRead}, wantGrants: []string{}},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var gotAdmin bool
+			var gotGrants []string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				gotAdmin, _ = r.Context().Value(IsAdminKey).(bool)
+				gotGrants, _ = r.Context().Value(AdminGrantsKey).([]string)
+			})
+
+			ctx := context.WithValue(context.Background(), UserKey, "user@example.com")
+			ctx = context.WithValue(ctx, GroupsKey, []string{"staff", "shorty-admins"})
+			if tc.scopes != nil {
+				ctx = context.WithValue(ctx, TokenScopesKey, tc.scopes)
+			}
+			req := httptest.NewRequest(http.MethodGet, "/v1/me", nil).WithContext(ctx)
+			AddAdminStatusMiddleware(next, db).ServeHTTP(httptest.NewRecorder(), req)
+
+			if gotAdmin != tc.wantAdmin {
+				t.Errorf("expected admin %v, got %v", tc.wantAdmin, gotAdmin)
+			}
+			if !slices.Equal(gotGrants, tc.wantGrants) {
+				t.Errorf("expected grants %v, got %v", tc.wantGrants, gotGrants)
+			}
+		})
+	}
+}
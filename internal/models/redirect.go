@@ -1,27 +1,305 @@
 package models
 
+import "time"
+
+// Target modes for redirects with several weighted targets
+const (
+	// TargetModeRandom picks a weighted random target on every request
+	TargetModeRandom = "random"
+	// TargetModeSticky keeps a client on the same target using a cookie
+	TargetModeSticky = "sticky"
+)
+
+// Device classes that can be given their own redirect target
+const (
+	DeviceIOS     = "ios"
+	DeviceAndroid = "android"
+	DeviceDesktop = "desktop"
+)
+
+// Availability states of a key
+const (
+	KeyAvailable = "available"
+	KeyTaken     = "taken"
+	KeyReserved  = "reserved"
+	KeyInvalid   = "invalid"
+)
+
+// DeviceClasses lists all device classes that can be given their own redirect target
+var DeviceClasses = []string{DeviceIOS, DeviceAndroid, DeviceDesktop}
+
 // Redirect represents a URL redirection with a short path
 type Redirect struct {
 	Path string `json:"path,omitempty"` // key/id
 	URL  string `json:"url,omitempty"`
 }
 
+// RedirectRename represents a request to move a redirect to a new key.
+// The old key can stay as an alias, or be reserved for a number of days so it is not reused right away.
+type RedirectRename struct {
+	Path        string `json:"path"`
+	KeepAlias   bool   `json:"keepAlias,omitempty"`
+	ReserveDays int    `json:"reserveDays,omitempty"`
+}
+
 // RedirectUser represents a user with permission to create redirects
 type RedirectUser struct {
 	Email string `json:"email"`
 }
 
+// Target represents one weighted destination of a redirect
+type Target struct {
+	URL    string `json:"url"`
+	Weight int    `json:"weight"`
+}
+
+// RedirectTargets represents the weighted targets of a redirect and how they are selected
+type RedirectTargets struct {
+	Mode    string   `json:"mode,omitempty"`
+	Targets []Target `json:"targets"`
+}
+
+// LanguageTarget represents the target of a single language variant
+type LanguageTarget struct {
+	URL string `json:"url"`
+}
+
+// DeviceTarget represents the target for a single device class, e.g. an app store page or deep link
+type DeviceTarget struct {
+	URL string `json:"url"`
+}
+
+// RedirectAlias represents an alias key that points at a canonical redirect
+type RedirectAlias struct {
+	Alias string `json:"alias"`
+}
+
+// RedirectStatus represents whether a redirect is enabled, and what paused redirects serve instead
+type RedirectStatus struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message,omitempty"`
+	Target  string `json:"target,omitempty"`
+}
+
+// UTMParams represents the UTM campaign parameters added to the target of a redirect
+type UTMParams struct {
+	Source   string `json:"source,omitempty"`
+	Medium   string `json:"medium,omitempty"`
+	Campaign string `json:"campaign,omitempty"`
+	Term     string `json:"term,omitempty"`
+	Content  string `json:"content,omitempty"`
+}
+
+// UTM represents the UTM settings of a redirect, optionally based on a named campaign preset.
+// Parameters set on the redirect override the ones from the preset.
+type UTM struct {
+	Preset string `json:"preset,omitempty"`
+	UTMParams
+}
+
+// Campaign represents a named UTM preset managed by admins
+type Campaign struct {
+	Name string `json:"name"`
+	UTMParams
+}
+
 // RedirectPath represents a redirect with ownership information
 type RedirectPath struct {
-	Path  string `json:"path,omitempty"`
-	URL   string `json:"url,omitempty"`
-	Owner string `json:"owner,omitempty"`
+	Path         string            `json:"path,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Owner        string            `json:"owner,omitempty"`
+	ServiceOwner string            `json:"serviceOwner,omitempty"`
+	Targets      []Target          `json:"targets,omitempty"`
+	TargetMode   string            `json:"targetMode,omitempty"`
+	Languages    map[string]string `json:"languages,omitempty"`
+	Devices      map[string]string `json:"devices,omitempty"`
+	Aliases      []string          `json:"aliases,omitempty"`
+	Status       *RedirectStatus   `json:"status,omitempty"`
+	UTM          *UTM              `json:"utm,omitempty"`
 }
 
 // RedirectAllPaths represents a redirect with ownership and permissions
 type RedirectAllPaths struct {
-	Path   string `json:"path,omitempty"`
-	URL    string `json:"url,omitempty"`
-	Owner  string `json:"owner,omitempty"`
-	Modify bool   `json:"modify"`
+	Path         string            `json:"path,omitempty"`
+	URL          string            `json:"url,omitempty"`
+	Owner        string            `json:"owner,omitempty"`
+	ServiceOwner string            `json:"serviceOwner,omitempty"`
+	Targets      []Target          `json:"targets,omitempty"`
+	TargetMode   string            `json:"targetMode,omitempty"`
+	Languages    map[string]string `json:"languages,omitempty"`
+	Devices      map[string]string `json:"devices,omitempty"`
+	Aliases      []string          `json:"aliases,omitempty"`
+	Status       *RedirectStatus   `json:"status,omitempty"`
+	UTM          *UTM              `json:"utm,omitempty"`
+	Modify       bool              `json:"modify"`
+}
+
+// RedirectLoop represents redirects whose targets point at each other through our own short links
+type RedirectLoop struct {
+	Keys []string `json:"keys"`
+}
+
+// KeyCollision represents existing keys that normalize to the same key
+type KeyCollision struct {
+	Normalized string   `json:"normalized"`
+	Keys       []string `json:"keys"`
+	Kept       string   `json:"kept,omitempty"`
+	Merged     []string `json:"merged,omitempty"`
+}
+
+// KeyAvailability reports whether a key can be used for a new redirect, with free alternatives when it cannot
+type KeyAvailability struct {
+	Path        string   `json:"path"`
+	Available   bool     `json:"available"`
+	Status      string   `json:"status"`
+	Reason      string   `json:"reason,omitempty"`
+	Suggestions []string `json:"suggestions,omitempty"`
+}
+
+// Click dimensions broken down in the link statistics
+const (
+	DimensionReferrer = "referrer"
+	DimensionDevice   = "device"
+	DimensionBrowser  = "browser"
+	DimensionCountry  = "country"
+	DimensionTarget   = "target"
+)
+
+// Dimensions lists the click dimensions broken down in the link statistics
+var Dimensions = []string{DimensionReferrer, DimensionDevice, DimensionBrowser, DimensionCountry, DimensionTarget}
+
+// Click is one redirect of a link as counted in its statistics. It holds no raw client address.
+// Bot holds why the request was classified as automated, and is empty for people.
+type Click struct {
+	Time     time.Time
+	Bot      string
+	Visitor  string
+	Referrer string
+	Device   string
+	Browser  string
+	Country  string
+	Target   string
+}
+
+// Dimension returns the value of a click dimension
+func (c Click) Dimension(dimension string) string {
+	switch dimension {
+	case DimensionReferrer:
+		return c.Referrer
+	case DimensionDevice:
+		return c.Device
+	case DimensionBrowser:
+		return c.Browser
+	case DimensionCountry:
+		return c.Country
+	case DimensionTarget:
+		return c.Target
+	default:
+		return ""
+	}
+}
+
+// ClickEvent is a click on the link with the stored key Key. With NotFound set, Key is a
+// requested key without a link, and only the not-found hit is counted.
+type ClickEvent struct {
+	Key      string
+	Click    Click
+	NotFound bool
+}
+
+// ExportedClick is a click event as exported for data warehouses
+type ExportedClick struct {
+	Timestamp      time.Time `json:"timestamp"`
+	Key            string    `json:"key"`
+	Target         string    `json:"target"`
+	ReferrerDomain string    `json:"referrerDomain"`
+	DeviceClass    string    `json:"deviceClass"`
+	Bot            bool      `json:"bot"`
+}
+
+// StaleLink is a link without clicks for a while, as listed in the stale and orphan reports.
+// Times are RFC 3339 and empty when unknown. OwnerLastSeen is the last sign-in of the owner.
+type StaleLink struct {
+	Path          string `json:"path"`
+	URL           string `json:"url"`
+	Owner         string `json:"owner,omitempty"`
+	CreatedTime   string `json:"createdTime,omitempty"`
+	LastAccess    string `json:"lastAccess,omitempty"`
+	ConfirmedTime string `json:"confirmedTime,omitempty"`
+	OwnerLastSeen string `json:"ownerLastSeen,omitempty"`
+	IdleDays      int    `json:"idleDays"`
+}
+
+// StaleLinkActions lists stale links to keep, which resets their idle time, and links to delete
+type StaleLinkActions struct {
+	Confirm []string `json:"confirm"`
+	Delete  []string `json:"delete"`
+}
+
+// LinkArchive lists links to archive
+type LinkArchive struct {
+	Paths []string `json:"paths"`
+}
+
+// BulkResult is the outcome of a bulk action on one link. Error is empty when it succeeded.
+type BulkResult struct {
+	Path   string `json:"path"`
+	Action string `json:"action"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Count is the number of links or hits with one value, such as a creator or a week
+type Count struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// AdminOverview is the global usage of shorty as shown on the admin dashboard
+type AdminOverview struct {
+	GeneratedAt    time.Time    `json:"generatedAt"`
+	TotalLinks     int64        `json:"totalLinks"`
+	CreatedPerWeek []Count      `json:"createdPerWeek"`
+	MostClicked    []LinkClicks `json:"mostClicked"`
+	TopCreators    []Count      `json:"topCreators"`
+	NotFound       []Count      `json:"notFound"`
+	TargetDomains  []Count      `json:"targetDomains"`
+}
+
+// LinkClicks is the all-time number of clicks of a link
+type LinkClicks struct {
+	Path   string `json:"path"`
+	Clicks int64  `json:"clicks"`
+}
+
+// BreakdownEntry is the number of clicks with one value of a click dimension
+type BreakdownEntry struct {
+	Value  string `json:"value"`
+	Clicks int64  `json:"clicks"`
+}
+
+// DailyClicks is the number of human clicks, estimated unique visitors and automated requests
+// from bots, previewers and scanners of a link during one day (UTC)
+type DailyClicks struct {
+	Date     string `json:"date"`
+	Clicks   int64  `json:"clicks"`
+	Visitors int64  `json:"visitors"`
+	Bots     int64  `json:"bots"`
+}
+
+// LinkStats represents the click statistics of a link for a period
+type LinkStats struct {
+	Path           string        `json:"path"`
+	From           string        `json:"from"`
+	To             string        `json:"to"`
+	Total          int64         `json:"total"`
+	PeriodClicks   int64         `json:"periodClicks"`
+	Visitors       int64         `json:"visitors"`
+	PeriodVisitors int64         `json:"periodVisitors"`
+	Bots           int64         `json:"bots"`
+	PeriodBots     int64         `json:"periodBots"`
+	LastAccess     string        `json:"lastAccess,omitempty"`
+	Days           []DailyClicks `json:"days"`
+
+	// Breakdown holds the top values of each click dimension over all clicks, not just the period
+	Breakdown map[string][]BreakdownEntry `json:"breakdown,omitempty"`
 }
@@ -2,8 +2,10 @@ package models
 
 // Response represents a standard API response
 type Response struct {
-	Success bool   `json:"success"`
-	Message string `json:"message"`
+	Success  bool   `json:"success"`
+	Message  string `json:"message"`
+	Path     string `json:"path,omitempty"`
+	ShortURL string `json:"shortUrl,omitempty"`
 }
 
 // ResponseUser represents a user-specific API response
@@ -11,3 +13,13 @@ type ResponseUser struct {
 	Success bool   `json:"success"`
 	Message string `json:"message"`
 }
+
+// CurrentUser describes the authenticated user and where their admin rights come from
+type CurrentUser struct {
+	Email       string `json:"email"`
+	Admin       bool   `json:"admin"`
+	DomainAdmin bool   `json:"domainAdmin"`
+	// AdminGrants are the sources of the admin rights: redis, group:<name>, domain:<host> or scope:admin
+	AdminGrants []string `json:"adminGrants"`
+	Groups      []string `json:"groups"`
+}
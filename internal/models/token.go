@@ -0,0 +1,53 @@
+package models
+
+// Scopes of personal access tokens
+const (
+	// ScopeRead allows reading links and statistics
+	ScopeRead = "read"
+	// ScopeWrite allows creating, changing and deleting links as well
+	ScopeWrite = "write"
+	// ScopeAdmin lets the token use the admin rights of its owner
+	ScopeAdmin = "admin"
+)
+
+// Scopes lists all scopes a personal access token can have
+var Scopes = []string{ScopeRead, ScopeWrite, ScopeAdmin}
+
+// AccessToken is a personal access token as listed, without its secret.
+// Times are RFC 3339; LastUsed is empty until the token is used.
+type AccessToken struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Owner       string   `json:"owner"`
+	Scopes      []string `json:"scopes"`
+	CreatedTime string   `json:"createdTime"`
+	ExpiresTime string   `json:"expiresTime"`
+	LastUsed    string   `json:"lastUsed,omitempty"`
+}
+
+// AccessTokenRequest asks for a new personal access token. ExpiresInDays defaults to 90.
+type AccessTokenRequest struct {
+	Name          string   `json:"name"`
+	Scopes        []string `json:"scopes"`
+	ExpiresInDays int      `json:"expiresInDays,omitempty"`
+}
+
+// CreatedAccessToken is a new personal access token. The token itself is only shown once.
+type CreatedAccessToken struct {
+	AccessToken
+	Token string `json:"token"`
+}
+
+// ServiceAccount is a machine identity, recognised from the client id or subject of its OIDC tokens.
+// Owner is the person or team responsible for it and is shown on the links it creates.
+// Scopes are the same as those of personal access tokens.
+type ServiceAccount struct {
+	Name           string   `json:"name"`
+	Owner          string   `json:"owner"`
+	ClientID       string   `json:"clientId,omitempty"`
+	Subject        string   `json:"subject,omitempty"`
+	Scopes         []string `json:"scopes"`
+	DailyLinkLimit int      `json:"dailyLinkLimit"`
+	CreatedBy      string   `json:"createdBy,omitempty"`
+	CreatedTime    string   `json:"createdTime,omitempty"`
+}
@@ -0,0 +1,146 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrKeyExists is returned when a key is already taken by a redirect or an alias
+	ErrKeyExists = errors.New("key already in use")
+	// ErrAliasNotFound is returned when an alias is not configured on a redirect
+	ErrAliasNotFound = errors.New("alias not found")
+)
+
+// aliasKey returns the redis key of the alias pointer for a short key
+func aliasKey(key string) string {
+	return "alias:" + NormalizeKey(key)
+}
+
+// IsAlias reports whether the key is an alias of another redirect
+func IsAlias(rdb *redis.Client, key string) (bool, error) {
+	n, err := rdb.Exists(context.Background(), aliasKey(key)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// GetAliases retrieves the aliases pointing at a redirect
+func GetAliases(rdb *redis.Client, key string) ([]string, error) {
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if redirect.Aliases == nil {
+		return []string{}, nil
+	}
+	return redirect.Aliases, nil
+}
+
+// AddAlias makes alias resolve to the canonical redirect behind key
+func AddAlias(rdb *redis.Client, key string, alias string, user string) error {
+	if err := ValidateKey(alias); err != nil {
+		return err
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+
+	// Links stored before key normalization keep their exact key, so resolve the alias before folding it
+	ctx := context.Background()
+	stored, err := storedKey(ctx, rdb, alias)
+	if err != nil {
+		return err
+	}
+	alias = NormalizeKey(alias)
+	exists, err := rdb.Exists(ctx, "path:"+stored).Result()
+	if err != nil {
+		return err
+	}
+	if exists > 0 {
+		return fmt.Errorf("%w: `%s`", ErrKeyExists, alias)
+	}
+
+	reserved, err := IsReserved(rdb, alias)
+	if err != nil {
+		return err
+	}
+	if reserved {
+		return fmt.Errorf("%w: `%s`", ErrKeyReserved, alias)
+	}
+
+	created, err := rdb.SetNX(ctx, aliasKey(alias), redirect.Path, 0).Result()
+	if err != nil {
+		rlog.Error("Failed to create alias", err, rlog.String("key", redirect.Path), rlog.String("alias", alias))
+		return err
+	}
+	if !created {
+		return fmt.Errorf("%w: `%s`", ErrKeyExists, alias)
+	}
+
+	if err := saveAliases(rdb, redirect.Path, append(redirect.Aliases, alias), user); err != nil {
+		rdb.Del(ctx, aliasKey(alias))
+		return err
+	}
+
+	rlog.Info("Alias added", rlog.String("key", redirect.Path), rlog.String("alias", alias), rlog.String("user", user))
+	return nil
+}
+
+// RemoveAlias removes an alias from the redirect behind key
+func RemoveAlias(rdb *redis.Client, key string, alias string, user string) error {
+	alias = NormalizeKey(alias)
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+
+	i := slices.Index(redirect.Aliases, alias)
+	if i < 0 {
+		return fmt.Errorf("%w: `%s`", ErrAliasNotFound, alias)
+	}
+
+	if err := rdb.Del(context.Background(), aliasKey(alias)).Err(); err != nil {
+		rlog.Error("Failed to delete alias", err, rlog.String("key", redirect.Path), rlog.String("alias", alias))
+		return err
+	}
+
+	return saveAliases(rdb, redirect.Path, slices.Delete(redirect.Aliases, i, i+1), user)
+}
+
+// saveAliases stores the alias list of a redirect together with edit information.
+// The key must be the stored key as resolved by GetRedirect.
+func saveAliases(rdb *redis.Client, key string, aliases []string, user string) error {
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	encoded, err := json.Marshal(aliases)
+	if err != nil {
+		return err
+	}
+
+	editTime := time.Now().Format(time.RFC3339)
+	err = rdb.HSet(context.Background(), "path:"+key,
+		"aliases", string(encoded),
+		"lastEditBy", user,
+		"lastEditTime", editTime,
+	).Err()
+	if err != nil {
+		rlog.Error("Failed to save aliases", err, rlog.String("key", key), rlog.String("user", user))
+		return err
+	}
+	return nil
+}
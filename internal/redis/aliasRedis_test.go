@@ -0,0 +1,122 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestAddAlias(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+
+	t.Run("Add success", func(t *testing.T) {
+		mock.ExpectHGetAll("path:onboarding").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectExists("path:onboard").SetVal(0)
+		mock.ExpectExists("reserved:onboard").SetVal(0)
+		mock.ExpectSetNX("alias:onboard", "onboarding", 0).SetVal(true)
+		mock.ExpectHSet("path:onboarding",
+			"aliases", `["onboard"]`,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(1)
+
+		if err := AddAlias(db, "onboarding", "onboard", user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Alias taken by a redirect", func(t *testing.T) {
+		mock.ExpectHGetAll("path:onboarding").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectExists("path:ny-ansatt").SetVal(1)
+
+		err := AddAlias(db, "onboarding", "ny-ansatt", user)
+		if !errors.Is(err, ErrKeyExists) {
+			t.Errorf("expected ErrKeyExists, got %v", err)
+		}
+	})
+
+	t.Run("Alias taken by another alias", func(t *testing.T) {
+		mock.ExpectHGetAll("path:onboarding").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectExists("path:onboard").SetVal(0)
+		mock.ExpectExists("reserved:onboard").SetVal(0)
+		mock.ExpectSetNX("alias:onboard", "onboarding", 0).SetVal(false)
+
+		err := AddAlias(db, "onboarding", "onboard", user)
+		if !errors.Is(err, ErrKeyExists) {
+			t.Errorf("expected ErrKeyExists, got %v", err)
+		}
+	})
+
+	t.Run("Reserved alias", func(t *testing.T) {
+		err := AddAlias(db, "onboarding", "admin", user)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestRemoveAlias(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+	stored := map[string]string{
+		"url":     "https://example.com",
+		"aliases": `["onboard","ny-ansatt"]`,
+	}
+
+	t.Run("Remove success", func(t *testing.T) {
+		mock.ExpectHGetAll("path:onboarding").SetVal(stored)
+		mock.ExpectDel("alias:onboard").SetVal(1)
+		mock.ExpectHSet("path:onboarding",
+			"aliases", `["ny-ansatt"]`,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+
+		if err := RemoveAlias(db, "onboarding", "onboard", user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Unknown alias", func(t *testing.T) {
+		mock.ExpectHGetAll("path:onboarding").SetVal(stored)
+
+		err := RemoveAlias(db, "onboarding", "welcome", user)
+		if !errors.Is(err, ErrAliasNotFound) {
+			t.Errorf("expected ErrAliasNotFound, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestGetRedirectFollowsAlias(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectHGetAll("path:onboard").SetVal(map[string]string{})
+	mock.ExpectGet("alias:onboard").SetVal("onboarding")
+	mock.ExpectHGetAll("path:onboarding").SetVal(map[string]string{
+		"url":     "https://example.com",
+		"aliases": `["onboard"]`,
+	})
+
+	redirect, err := GetRedirect(db, "onboard")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if redirect.Path != "onboarding" || redirect.URL != "https://example.com" {
+		t.Errorf("expected canonical redirect, got %+v", redirect)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
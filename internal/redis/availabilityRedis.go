@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+// maxSlugLength caps the length of suggestions built from a target page
+const maxSlugLength = 30
+
+var slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// CheckKey reports whether a key is free, taken, reserved for the service or invalid
+func CheckKey(rdb *redis.Client, key string) (models.KeyAvailability, error) {
+	availability := models.KeyAvailability{Path: key, Status: models.KeyAvailable, Available: true}
+
+	_, local, found := strings.Cut(key, ":")
+	if !found {
+		local = key
+	}
+
+	switch err := ValidateKey(key); {
+	case isReservedKey(local):
+		availability.Status = models.KeyReserved
+		availability.Reason = "key is reserved by the service"
+	case err != nil:
+		availability.Status = models.KeyInvalid
+		availability.Reason = err.Error()
+	default:
+		exists, err := URLExists(rdb, key)
+		if err != nil {
+			return models.KeyAvailability{}, err
+		}
+		if exists {
+			availability.Status = models.KeyTaken
+			availability.Reason = "key already in use"
+			break
+		}
+
+		reserved, err := IsReserved(rdb, key)
+		if err != nil {
+			return models.KeyAvailability{}, err
+		}
+		if reserved {
+			availability.Status = models.KeyReserved
+			availability.Reason = "key was recently renamed and is reserved for a grace period"
+		}
+	}
+
+	availability.Available = availability.Status == models.KeyAvailable
+	return availability, nil
+}
+
+// SuggestKeys proposes up to limit free keys based on the requested key and the target page.
+// Keys are given and returned in stored form, suggestions keep the namespace of the requested key.
+func SuggestKeys(rdb *redis.Client, key string, target string, limit int) ([]string, error) {
+	namespace, local, found := strings.Cut(key, ":")
+	if !found {
+		namespace, local = "", key
+	}
+
+	suggestions := []string{}
+	for _, candidate := range keyCandidates(slugify(local), target) {
+		if len(suggestions) >= limit {
+			break
+		}
+		if namespace != "" {
+			candidate = namespace + ":" + candidate
+		}
+		if ValidateKey(candidate) != nil || slices.Contains(suggestions, candidate) {
+			continue
+		}
+
+		// Suggestions must not be taken by a redirect or an alias, nor reserved after a rename
+		used, err := rdb.Exists(context.Background(), pathHashKey(candidate), aliasKey(candidate), reservedKey(candidate)).Result()
+		if err != nil {
+			return nil, err
+		}
+		if used == 0 {
+			suggestions = append(suggestions, candidate)
+		}
+	}
+	return suggestions, nil
+}
+
+// keyCandidates lists possible keys, best first, from the requested key and words in the target URL
+func keyCandidates(key string, target string) []string {
+	page, site := targetWords(target)
+
+	var candidates []string
+	add := func(parts ...string) {
+		var nonEmpty []string
+		for _, part := range parts {
+			if part != "" {
+				nonEmpty = append(nonEmpty, part)
+			}
+		}
+		if len(nonEmpty) == len(parts) {
+			candidates = append(candidates, truncateSlug(strings.Join(nonEmpty, "-")))
+		}
+	}
+
+	add(key, page)
+	add(key, site)
+	add(page)
+	add(site, page)
+	add(key, strconv.Itoa(time.Now().Year()))
+	for i := 2; i <= 5; i++ {
+		add(key, strconv.Itoa(i))
+	}
+	add(site)
+	return candidates
+}
+
+// targetWords returns slugs of the last path segment and the site name of a target URL
+func targetWords(target string) (string, string) {
+	u, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || u.Hostname() == "" {
+		return "", ""
+	}
+
+	page := path.Base(strings.Trim(u.Path, "/"))
+	page = strings.TrimSuffix(page, path.Ext(page))
+	if page == "." {
+		page = ""
+	}
+
+	labels := strings.Split(u.Hostname(), ".")
+	site := labels[0]
+	if len(labels) > 2 && (site == "www" || site == "m") {
+		site = labels[1]
+	}
+	if len(labels) == 1 {
+		site = ""
+	}
+
+	return slugify(page), slugify(site)
+}
+
+// slugify lowercases a word and replaces everything but letters and numbers with dashes
+func slugify(word string) string {
+	word = strings.ToLower(word)
+	word = strings.NewReplacer("æ", "ae", "ø", "o", "å", "a").Replace(word)
+	return truncateSlug(slugSeparators.ReplaceAllString(word, "-"))
+}
+
+// truncateSlug shortens a slug to maxSlugLength and trims leading and trailing dashes
+func truncateSlug(slug string) string {
+	if len(slug) > maxSlugLength {
+		slug = slug[:maxSlugLength]
+	}
+	return strings.Trim(slug, "-")
+}
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestCheckKey(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	tests := []struct {
+		name     string
+		key      string
+		exists   int64
+		reserved int64
+		want     string
+	}{
+		{name: "Free key", key: "onboarding", exists: 0, want: models.KeyAvailable},
+		{name: "Key reserved after rename", key: "onboarding", exists: 0, reserved: 1, want: models.KeyReserved},
+		{name: "Taken key", key: "onboarding", exists: 1, want: models.KeyTaken},
+		{name: "Reserved key", key: "swagger", want: models.KeyReserved},
+		{name: "Reserved key in namespace", key: "hr:admin", want: models.KeyReserved},
+		{name: "Invalid key", key: "on boarding", want: models.KeyInvalid},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.want == models.KeyAvailable || tc.want == models.KeyTaken || tc.reserved > 0 {
+				mock.ExpectExists("path:"+tc.key, "alias:"+tc.key).SetVal(tc.exists)
+			}
+			if tc.want == models.KeyAvailable || tc.reserved > 0 {
+				mock.ExpectExists("reserved:" + tc.key).SetVal(tc.reserved)
+			}
+
+			got, err := CheckKey(db, tc.key)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Status != tc.want {
+				t.Errorf("expected status %q, got %q (%s)", tc.want, got.Status, got.Reason)
+			}
+			if got.Available != (tc.want == models.KeyAvailable) {
+				t.Errorf("expected available to be %v", tc.want == models.KeyAvailable)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestSuggestKeys(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectExists("path:hr:vaccine-influensa", "alias:hr:vaccine-influensa", "reserved:hr:vaccine-influensa").SetVal(1)
+	mock.ExpectExists("path:hr:vaccine-helsenorge", "alias:hr:vaccine-helsenorge", "reserved:hr:vaccine-helsenorge").SetVal(0)
+	mock.ExpectExists("path:hr:influensa", "alias:hr:influensa", "reserved:hr:influensa").SetVal(0)
+
+	got, err := SuggestKeys(db, "hr:vaccine", "https://www.helsenorge.no/vaksiner/influensa.html", 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"hr:vaccine-helsenorge", "hr:influensa"}
+	if !slices.Equal(got, want) {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestKeyCandidates(t *testing.T) {
+	candidates := keyCandidates("", "https://www.nhn.no/om-nhn/Æresliste")
+
+	for _, want := range []string{"aeresliste", "nhn-aeresliste", "nhn"} {
+		if !slices.Contains(candidates, want) {
+			t.Errorf("expected candidate %q in %v", want, candidates)
+		}
+	}
+	if slices.ContainsFunc(candidates, func(c string) bool { return c == "" || c[0] == '-' }) {
+		t.Errorf("expected only non-empty candidates, got %v", candidates)
+	}
+}
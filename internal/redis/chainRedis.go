@@ -0,0 +1,225 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/NorskHelsenett/shorty/internal/config"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+)
+
+var (
+	// ErrRedirectLoop is returned when a target leads back to the key through our own short links
+	ErrRedirectLoop = errors.New("redirect loop")
+	// ErrRedirectChainTooLong is returned when a target passes through more short links than MAX_REDIRECT_CHAIN allows
+	ErrRedirectChainTooLong = errors.New("redirect chain too long")
+)
+
+// shortLinkFromURL returns the domain and path of a target that points at one of our own short link domains.
+// The path is a key on that domain when it has a single segment.
+func shortLinkFromURL(target string) (config.Domain, string, bool) {
+	u, err := url.Parse(strings.TrimSpace(target))
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return config.Domain{}, "", false
+	}
+	domain, ok := config.LookupDomain(u.Hostname())
+	if !ok {
+		return config.Domain{}, "", false
+	}
+
+	path := strings.Trim(u.Path, "/")
+	if path == "" {
+		return config.Domain{}, "", false
+	}
+	return domain, path, true
+}
+
+// shortKeyFromURL returns the stored key a target points at when it is one of our own short links
+func shortKeyFromURL(target string) (string, bool) {
+	domain, path, ok := shortLinkFromURL(target)
+	if !ok || strings.Contains(path, "/") {
+		return "", false
+	}
+	return domain.Key(path), true
+}
+
+// linkTargets returns every stored target of a redirect: the primary URL, the weighted, language
+// and device targets, and the alternate target of a paused redirect
+func linkTargets(redirect models.RedirectPath) []string {
+	targets := []string{redirect.URL}
+	for _, target := range redirect.Targets {
+		targets = append(targets, target.URL)
+	}
+	for _, lang := range slices.Sorted(maps.Keys(redirect.Languages)) {
+		targets = append(targets, redirect.Languages[lang])
+	}
+	for _, device := range slices.Sorted(maps.Keys(redirect.Devices)) {
+		targets = append(targets, redirect.Devices[device])
+	}
+	if redirect.Status != nil && redirect.Status.Target != "" {
+		targets = append(targets, redirect.Status.Target)
+	}
+	return targets
+}
+
+// resolveChain follows a target through our own short links, along every stored target of the links
+// it passes, and rejects it when any of them leads back to the key or into another loop.
+// Returns the final target along the primary URLs and the number of short links passed through.
+func resolveChain(rdb *redis.Client, key string, target string) (string, int, error) {
+	// links holds the redirects fetched so far by the normalized key pointing at them, empty when missing
+	links := make(map[string]models.RedirectPath)
+	done := make(map[string]bool)
+
+	var walk func(target string, visited []string) error
+	walk = func(target string, visited []string) error {
+		next, ok := shortKeyFromURL(target)
+		if !ok {
+			return nil
+		}
+		next = NormalizeKey(next)
+		if slices.Contains(visited, next) {
+			return fmt.Errorf("%w: %s -> %s", ErrRedirectLoop, strings.Join(visited, " -> "), next)
+		}
+
+		redirect, fetched := links[next]
+		if !fetched {
+			var err error
+			redirect, err = GetRedirect(rdb, next)
+			if err != nil && !errors.Is(err, ErrURLNotFound) {
+				return err
+			}
+			links[next] = redirect
+		}
+		if redirect.Path == "" {
+			return nil
+		}
+
+		stored := NormalizeKey(redirect.Path)
+		if slices.Contains(visited, stored) {
+			return fmt.Errorf("%w: %s -> %s", ErrRedirectLoop, strings.Join(visited, " -> "), stored)
+		}
+		if done[stored] {
+			return nil
+		}
+		visited = append(slices.Clip(visited), stored)
+		for _, following := range linkTargets(redirect) {
+			if err := walk(following, visited); err != nil {
+				return err
+			}
+		}
+		done[stored] = true
+		return nil
+	}
+	if err := walk(target, []string{NormalizeKey(key)}); err != nil {
+		return "", 0, err
+	}
+
+	// Every link along the primary URLs has been fetched above, and they hold no loop
+	current, depth := target, 0
+	for {
+		next, ok := shortKeyFromURL(current)
+		if !ok {
+			return current, depth, nil
+		}
+		redirect := links[NormalizeKey(next)]
+		if redirect.Path == "" {
+			return current, depth, nil
+		}
+		current = redirect.URL
+		depth++
+	}
+}
+
+// checkChain rejects targets that loop back to the key, and collapses or caps chains through
+// our own short links. With COLLAPSE_REDIRECT_CHAINS the primary URL at the end of the chain
+// is stored instead, otherwise chains longer than MAX_REDIRECT_CHAIN are rejected.
+func checkChain(rdb *redis.Client, key string, target string) (string, error) {
+	resolved, depth, err := resolveChain(rdb, key, target)
+	if err != nil || depth == 0 {
+		return target, err
+	}
+
+	if viper.GetBool("COLLAPSE_REDIRECT_CHAINS") {
+		return resolved, nil
+	}
+	if maxDepth := viper.GetInt("MAX_REDIRECT_CHAIN"); depth > maxDepth {
+		return "", fmt.Errorf("%w: target passes through %d short links, at most %d allowed", ErrRedirectChainTooLong, depth, maxDepth)
+	}
+	return target, nil
+}
+
+// FindRedirectLoops reports existing redirects whose targets form a loop through our own short links.
+// Each loop is reported once, starting at its alphabetically first key. Loops that share links are
+// found by a depth-first search, so not every combination of them is reported.
+func FindRedirectLoops(rdb *redis.Client) ([]models.RedirectLoop, error) {
+	redirects, err := GetAll(rdb, "path")
+	if err != nil && !errors.Is(err, ErrNoPathsFound) {
+		return nil, err
+	}
+
+	// canonical maps every key and alias to the normalized key of its stored redirect
+	canonical := make(map[string]string)
+	for _, redirect := range redirects {
+		stored := NormalizeKey(redirect.Path)
+		canonical[stored] = stored
+		for _, alias := range redirect.Aliases {
+			canonical[NormalizeKey(alias)] = stored
+		}
+	}
+
+	// next maps every redirect to the redirects its targets point at
+	next := make(map[string][]string)
+	for _, redirect := range redirects {
+		from := NormalizeKey(redirect.Path)
+		for _, target := range linkTargets(redirect) {
+			key, ok := shortKeyFromURL(target)
+			if !ok {
+				continue
+			}
+			if stored, ok := canonical[NormalizeKey(key)]; ok && !slices.Contains(next[from], stored) {
+				next[from] = append(next[from], stored)
+			}
+		}
+	}
+
+	// Depth-first search, where a link on the current path that is reached again closes a loop
+	loops := []models.RedirectLoop{}
+	seen := make(map[string]bool)
+	done := make(map[string]bool)
+	var walk func(current string, path []string)
+	walk = func(current string, path []string) {
+		if i := slices.Index(path, current); i >= 0 {
+			loop := newRedirectLoop(path[i:])
+			if !seen[strings.Join(loop.Keys, " ")] {
+				seen[strings.Join(loop.Keys, " ")] = true
+				loops = append(loops, loop)
+			}
+			return
+		}
+		if done[current] {
+			return
+		}
+		path = append(slices.Clip(path), current)
+		for _, following := range next[current] {
+			walk(following, path)
+		}
+		done[current] = true
+	}
+	for _, redirect := range redirects {
+		walk(NormalizeKey(redirect.Path), nil)
+	}
+
+	return loops, nil
+}
+
+// newRedirectLoop rotates the keys of a loop to start at its alphabetically first key
+func newRedirectLoop(keys []string) models.RedirectLoop {
+	first := slices.Index(keys, slices.Min(keys))
+	return models.RedirectLoop{Keys: append(slices.Clone(keys[first:]), keys[:first]...)}
+}
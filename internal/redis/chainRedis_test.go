@@ -0,0 +1,169 @@
+package redis
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/spf13/viper"
+)
+
+func setChainConfig(t *testing.T, collapse bool, maxDepth int) {
+	t.Helper()
+	viper.Set("SHORT_DOMAINS", "go.nhn.no")
+	viper.Set("COLLAPSE_REDIRECT_CHAINS", collapse)
+	viper.Set("MAX_REDIRECT_CHAIN", maxDepth)
+	t.Cleanup(func() {
+		viper.Set("SHORT_DOMAINS", "")
+		viper.Set("COLLAPSE_REDIRECT_CHAINS", false)
+		viper.Set("MAX_REDIRECT_CHAIN", 0)
+	})
+}
+
+func TestShortKeyFromURL(t *testing.T) {
+	setChainConfig(t, false, 3)
+
+	tests := []struct {
+		target string
+		want   string
+		ok     bool
+	}{
+		{target: "https://k.nhn.no/b", want: "b", ok: true},
+		{target: "http://K.NHN.NO/b/", want: "b", ok: true},
+		{target: "https://go.nhn.no/b?x=1", want: "b", ok: true},
+		{target: "https://k.nhn.no/admin/user", ok: false},
+		{target: "https://k.nhn.no/", ok: false},
+		{target: "https://example.com/b", ok: false},
+		{target: "myapp://k.nhn.no/b", ok: false},
+	}
+
+	for _, tc := range tests {
+		got, ok := shortKeyFromURL(tc.target)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("shortKeyFromURL(%q) = %q, %v, want %q, %v", tc.target, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestCheckChain(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	t.Run("External target", func(t *testing.T) {
+		setChainConfig(t, false, 3)
+
+		got, err := checkChain(db, "a", "https://example.com")
+		if err != nil || got != "https://example.com" {
+			t.Errorf("expected target unchanged, got %q, %v", got, err)
+		}
+	})
+
+	t.Run("Loop is rejected", func(t *testing.T) {
+		setChainConfig(t, false, 3)
+		mock.ExpectHGetAll("path:b").SetVal(map[string]string{"url": "https://go.nhn.no/a"})
+
+		_, err := checkChain(db, "a", "https://k.nhn.no/b")
+		if !errors.Is(err, ErrRedirectLoop) {
+			t.Errorf("expected ErrRedirectLoop, got %v", err)
+		}
+	})
+
+	t.Run("Loop through alias is rejected", func(t *testing.T) {
+		setChainConfig(t, false, 3)
+		mock.ExpectHGetAll("path:b").SetVal(map[string]string{"url": "https://k.nhn.no/a-alias"})
+		mock.ExpectHGetAll("path:a-alias").SetVal(map[string]string{})
+		mock.ExpectGet("alias:a-alias").SetVal("a")
+		mock.ExpectHGetAll("path:a").SetVal(map[string]string{"url": "https://k.nhn.no/b"})
+
+		_, err := checkChain(db, "a", "https://k.nhn.no/b")
+		if !errors.Is(err, ErrRedirectLoop) {
+			t.Errorf("expected ErrRedirectLoop, got %v", err)
+		}
+	})
+
+	t.Run("Loop through a device target is rejected", func(t *testing.T) {
+		setChainConfig(t, false, 3)
+		mock.ExpectHGetAll("path:b").SetVal(map[string]string{"url": "https://example.com", "devices": `{"ios":"https://k.nhn.no/c"}`})
+		mock.ExpectHGetAll("path:c").SetVal(map[string]string{"url": "https://example.com", "targets": `[{"url":"https://k.nhn.no/a","weight":1}]`})
+
+		_, err := checkChain(db, "a", "https://k.nhn.no/b")
+		if !errors.Is(err, ErrRedirectLoop) {
+			t.Errorf("expected ErrRedirectLoop, got %v", err)
+		}
+	})
+
+	t.Run("Links reached twice are fetched once", func(t *testing.T) {
+		setChainConfig(t, false, 3)
+		mock.ExpectHGetAll("path:b").SetVal(map[string]string{"url": "https://k.nhn.no/c", "languages": `{"en":"https://k.nhn.no/c"}`})
+		mock.ExpectHGetAll("path:c").SetVal(map[string]string{"url": "https://example.com"})
+
+		got, err := checkChain(db, "a", "https://k.nhn.no/b")
+		if err != nil || got != "https://k.nhn.no/b" {
+			t.Errorf("expected target unchanged, got %q, %v", got, err)
+		}
+	})
+
+	t.Run("Chain longer than the cap is rejected", func(t *testing.T) {
+		setChainConfig(t, false, 1)
+		mock.ExpectHGetAll("path:b").SetVal(map[string]string{"url": "https://k.nhn.no/c"})
+		mock.ExpectHGetAll("path:c").SetVal(map[string]string{"url": "https://example.com"})
+
+		_, err := checkChain(db, "a", "https://k.nhn.no/b")
+		if !errors.Is(err, ErrRedirectChainTooLong) {
+			t.Errorf("expected ErrRedirectChainTooLong, got %v", err)
+		}
+	})
+
+	t.Run("Chain is collapsed", func(t *testing.T) {
+		setChainConfig(t, true, 1)
+		mock.ExpectHGetAll("path:b").SetVal(map[string]string{"url": "https://k.nhn.no/c"})
+		mock.ExpectHGetAll("path:c").SetVal(map[string]string{"url": "https://example.com"})
+
+		got, err := checkChain(db, "a", "https://k.nhn.no/b")
+		if err != nil || got != "https://example.com" {
+			t.Errorf("expected collapsed target, got %q, %v", got, err)
+		}
+	})
+
+	t.Run("Link to missing key is kept", func(t *testing.T) {
+		setChainConfig(t, false, 3)
+		mock.ExpectHGetAll("path:missing").SetVal(map[string]string{})
+		mock.ExpectGet("alias:missing").RedisNil()
+
+		got, err := checkChain(db, "a", "https://k.nhn.no/missing")
+		if err != nil || got != "https://k.nhn.no/missing" {
+			t.Errorf("expected target unchanged, got %q, %v", got, err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestFindRedirectLoops(t *testing.T) {
+	setChainConfig(t, false, 3)
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectKeys("path*").SetVal([]string{"path:c", "path:a", "path:b", "path:d", "path:e", "path:f"})
+	mock.ExpectHGetAll("path:c").SetVal(map[string]string{"url": "https://k.nhn.no/a"})
+	mock.ExpectHGetAll("path:a").SetVal(map[string]string{"url": "https://k.nhn.no/b"})
+	mock.ExpectHGetAll("path:b").SetVal(map[string]string{"url": "https://k.nhn.no/c-alias"})
+	mock.ExpectHGetAll("path:d").SetVal(map[string]string{"url": "https://k.nhn.no/a", "aliases": `["c-alias"]`})
+	mock.ExpectHGetAll("path:e").SetVal(map[string]string{"url": "https://example.com", "enabled": "false", "pausedTarget": "https://k.nhn.no/f"})
+	mock.ExpectHGetAll("path:f").SetVal(map[string]string{"url": "https://example.com", "devices": `{"android":"https://k.nhn.no/e"}`})
+
+	loops, err := FindRedirectLoops(db)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// b points at d through its alias, so the loop is a -> b -> d. The paused e serves f, whose
+	// Android target is e again.
+	if len(loops) != 2 || !slices.Equal(loops[0].Keys, []string{"a", "b", "d"}) || !slices.Equal(loops[1].Keys, []string{"e", "f"}) {
+		t.Errorf("unexpected loops: %+v", loops)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrInvalidDevice is returned when a device class is not supported
+	ErrInvalidDevice = errors.New("invalid device class")
+	// ErrDeviceNotFound is returned when a device target is not configured on a redirect
+	ErrDeviceNotFound = errors.New("device target not found")
+)
+
+// GetDevices retrieves the per-device targets configured for a redirect
+func GetDevices(rdb *redis.Client, key string) (map[string]string, error) {
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if redirect.Devices == nil {
+		return map[string]string{}, nil
+	}
+	return redirect.Devices, nil
+}
+
+// SetDevices replaces all per-device targets of a redirect
+func SetDevices(rdb *redis.Client, key string, devices map[string]string, user string) error {
+	normalized := make(map[string]string, len(devices))
+	for device, target := range devices {
+		device, target, err := validateDevice(key, device, target)
+		if err != nil {
+			return err
+		}
+		normalized[device] = target
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+	for k, target := range normalized {
+		if normalized[k], err = checkChain(rdb, redirect.Path, target); err != nil {
+			return err
+		}
+	}
+
+	return saveDevices(rdb, redirect.Path, normalized, user)
+}
+
+// SetDevice adds or replaces the target for a single device class of a redirect
+func SetDevice(rdb *redis.Client, key string, device string, target string, user string) error {
+	device, target, err := validateDevice(key, device, target)
+	if err != nil {
+		return err
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+	if target, err = checkChain(rdb, redirect.Path, target); err != nil {
+		return err
+	}
+	devices := redirect.Devices
+	if devices == nil {
+		devices = map[string]string{}
+	}
+	devices[device] = target
+
+	return saveDevices(rdb, redirect.Path, devices, user)
+}
+
+// RemoveDevice removes the target for a single device class of a redirect
+func RemoveDevice(rdb *redis.Client, key string, device string, user string) error {
+	device = strings.ToLower(strings.TrimSpace(device))
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+	if _, ok := redirect.Devices[device]; !ok {
+		return fmt.Errorf("%w: `%s`", ErrDeviceNotFound, device)
+	}
+	delete(redirect.Devices, device)
+
+	return saveDevices(rdb, redirect.Path, redirect.Devices, user)
+}
+
+// validateDevice normalizes and validates a device class and its target
+func validateDevice(key, device, target string) (string, string, error) {
+	device = strings.ToLower(strings.TrimSpace(device))
+	if !slices.Contains(models.DeviceClasses, device) {
+		return "", "", fmt.Errorf("%w: `%s`, must be one of %s", ErrInvalidDevice, device, strings.Join(models.DeviceClasses, ", "))
+	}
+
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", "", fmt.Errorf("%w: target URL cannot be empty", ErrInvalidValue)
+	}
+	if err := validatePathInput(key, target); err != nil {
+		return "", "", err
+	}
+
+	return device, target, nil
+}
+
+// saveDevices stores the per-device targets of a redirect together with edit information.
+// The key must be the stored key as resolved by GetRedirect.
+func saveDevices(rdb *redis.Client, key string, devices map[string]string, user string) error {
+	encoded, err := json.Marshal(devices)
+	if err != nil {
+		return err
+	}
+
+	editTime := time.Now().Format(time.RFC3339)
+	err = rdb.HSet(context.Background(), "path:"+key,
+		"devices", string(encoded),
+		"lastEditBy", user,
+		"lastEditTime", editTime,
+	).Err()
+	if err != nil {
+		rlog.Error("Failed to save device targets", err, rlog.String("key", key), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("Device targets updated", rlog.String("key", key), rlog.Any("devices", len(devices)), rlog.String("user", user))
+	return nil
+}
@@ -0,0 +1,89 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// clickStreamKey is the redis stream holding the raw click events
+	clickStreamKey = "events:clicks"
+	// clickStreamLag is how much later than its click an event may be written, since clicks are queued
+	clickStreamLag = time.Minute
+	// clickExportPage is the number of events read from the stream at a time
+	clickExportPage = 1000
+)
+
+// addClickEvent queues appending a click to the click event stream. Events older than the
+// retention are trimmed as new ones are added.
+func addClickEvent(ctx context.Context, pipe redis.Pipeliner, event models.ClickEvent, retention time.Duration) {
+	pipe.XAdd(ctx, &redis.XAddArgs{
+		Stream: clickStreamKey,
+		MinID:  strconv.FormatInt(time.Now().Add(-retention).UnixMilli(), 10),
+		Approx: true,
+		Values: []interface{}{
+			"time", event.Click.Time.UnixMilli(),
+			"key", event.Key,
+			"target", event.Click.Target,
+			"referrer", event.Click.Referrer,
+			"device", event.Click.Device,
+			"bot", event.Click.Bot,
+		},
+	})
+}
+
+// ExportClicks calls each for every click event from from, included, up to to, in the order
+// they were written. Events are kept until they are older than CLICK_EVENT_RETENTION, so an
+// export of the same period returns the same events until then.
+func ExportClicks(rdb *redis.Client, from time.Time, to time.Time, each func(models.ExportedClick) error) error {
+	if !to.After(from) {
+		return ErrInvalidStatsRange
+	}
+
+	// Events are written after their click, so their ids can be somewhat later than the click time
+	ctx := context.Background()
+	start := strconv.FormatInt(from.UnixMilli(), 10)
+	end := strconv.FormatInt(to.Add(clickStreamLag).UnixMilli(), 10)
+	for {
+		messages, err := rdb.XRangeN(ctx, clickStreamKey, start, end, clickExportPage).Result()
+		if err != nil {
+			return err
+		}
+
+		for _, message := range messages {
+			click := exportedClick(message)
+			if click.Timestamp.Before(from) || !click.Timestamp.Before(to) {
+				continue
+			}
+			if err := each(click); err != nil {
+				return err
+			}
+		}
+
+		if len(messages) < clickExportPage {
+			return nil
+		}
+		start = "(" + messages[len(messages)-1].ID
+	}
+}
+
+// exportedClick decodes a click event from the stream
+func exportedClick(message redis.XMessage) models.ExportedClick {
+	field := func(name string) string {
+		value, _ := message.Values[name].(string)
+		return value
+	}
+	millis, _ := strconv.ParseInt(field("time"), 10, 64)
+	return models.ExportedClick{
+		Timestamp:      time.UnixMilli(millis).UTC(),
+		Key:            field("key"),
+		Target:         field("target"),
+		ReferrerDomain: field("referrer"),
+		DeviceClass:    field("device"),
+		Bot:            field("bot") != "",
+	}
+}
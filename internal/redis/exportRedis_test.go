@@ -0,0 +1,99 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/spf13/viper"
+)
+
+func TestRecordClicksAppendsEvents(t *testing.T) {
+	viper.Set("CLICK_EVENT_RETENTION", 24*time.Hour)
+	t.Cleanup(func() { viper.Set("CLICK_EVENT_RETENTION", 0) })
+
+	db, mock := redismock.NewClientMock()
+	at := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectHIncrBy("stats:kantine", "bots", 1).SetVal(1)
+	mock.ExpectHIncrBy("stats:kantine", "bots|2025-03-15", 1).SetVal(1)
+	// The trim id depends on the current time, so every argument but that one is compared
+	mock.CustomMatch(func(expected, actual []interface{}) error {
+		if len(expected) != len(actual) {
+			return fmt.Errorf("expected %v, got %v", expected, actual)
+		}
+		for i := range expected {
+			if i != 4 && fmt.Sprint(expected[i]) != fmt.Sprint(actual[i]) {
+				return fmt.Errorf("expected %v, got %v", expected, actual)
+			}
+		}
+		return nil
+	}).ExpectXAdd(&redis.XAddArgs{
+		Stream: "events:clicks",
+		MinID:  "0",
+		Approx: true,
+		Values: []interface{}{"time", at.UnixMilli(), "key", "kantine", "target", "https://example.com", "referrer", "", "device", "", "bot", "head"},
+	}).SetVal("1742040000000-0")
+
+	err := RecordClicks(db, []models.ClickEvent{{Key: "kantine", Click: models.Click{Time: at, Bot: "head", Target: "https://example.com"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestExportClicks(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	from := time.Date(2025, 3, 15, 0, 0, 0, 0, time.UTC)
+	to := from.Add(24 * time.Hour)
+
+	t.Run("Events in the period", func(t *testing.T) {
+		mock.ExpectXRangeN("events:clicks", "1741996800000", "1742083260000", clickExportPage).SetVal([]redis.XMessage{
+			{ID: "1741996801000-0", Values: map[string]interface{}{
+				"time": "1741996800500", "key": "onboarding", "target": "https://example.com", "referrer": "qr", "device": "mobile", "bot": "",
+			}},
+			{ID: "1742083201000-0", Values: map[string]interface{}{
+				"time": "1742083200000", "key": "onboarding", "target": "https://example.com", "referrer": "", "device": "", "bot": "head",
+			}},
+		})
+
+		var clicks []models.ExportedClick
+		err := ExportClicks(db, from, to, func(click models.ExportedClick) error {
+			clicks = append(clicks, click)
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		// The second event was written in the period, but clicked after it
+		want := models.ExportedClick{
+			Timestamp:      time.UnixMilli(1741996800500).UTC(),
+			Key:            "onboarding",
+			Target:         "https://example.com",
+			ReferrerDomain: "qr",
+			DeviceClass:    "mobile",
+		}
+		if len(clicks) != 1 || clicks[0] != want {
+			t.Errorf("unexpected clicks: %+v", clicks)
+		}
+	})
+
+	t.Run("Reversed period", func(t *testing.T) {
+		err := ExportClicks(db, to, from, func(models.ExportedClick) error { return nil })
+		if !errors.Is(err, ErrInvalidStatsRange) {
+			t.Errorf("expected ErrInvalidStatsRange, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
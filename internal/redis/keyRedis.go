@@ -0,0 +1,309 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+)
+
+// NormalizeKey folds a key according to the configured normalisation rules.
+// KEY_FOLD_CASE makes keys case-insensitive, and KEY_FOLD_SEPARATORS treats
+// underscore and dash as the same character.
+func NormalizeKey(key string) string {
+	if viper.GetBool("KEY_FOLD_CASE") {
+		key = strings.ToLower(key)
+	}
+	if viper.GetBool("KEY_FOLD_SEPARATORS") {
+		key = strings.ReplaceAll(key, "_", "-")
+	}
+	return key
+}
+
+// pathHashKey returns the Redis key of the path hash for a normalized key
+func pathHashKey(key string) string {
+	return "path:" + NormalizeKey(key)
+}
+
+// storedKey returns the key the redirect for key is stored under. That is the normalized key,
+// except for records stored before key normalization was enabled, which keep their exact key
+// until they are migrated. Keys that are not stored at all resolve to the normalized key.
+func storedKey(ctx context.Context, rdb *redis.Client, key string) (string, error) {
+	normalized := NormalizeKey(key)
+	if normalized == key {
+		return key, nil
+	}
+
+	var atNormalized, atExact *redis.IntCmd
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		atNormalized = pipe.Exists(ctx, "path:"+normalized)
+		atExact = pipe.Exists(ctx, "path:"+key)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if atNormalized.Val() == 0 && atExact.Val() > 0 {
+		return key, nil
+	}
+	return normalized, nil
+}
+
+// FindKeyCollisions groups existing keys that normalize to the same key.
+// Keys that are alone in their group but not yet stored in normalized form are
+// reported too, since they need to be renamed before they resolve reliably.
+func FindKeyCollisions(rdb *redis.Client) ([]models.KeyCollision, error) {
+	keys, err := rdb.Keys(context.Background(), "path:*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make(map[string][]string)
+	for _, key := range keys {
+		path := strings.TrimPrefix(key, "path:")
+		normalized := NormalizeKey(path)
+		groups[normalized] = append(groups[normalized], path)
+	}
+
+	collisions := make([]models.KeyCollision, 0)
+	for normalized, paths := range groups {
+		if len(paths) == 1 && paths[0] == normalized {
+			continue
+		}
+		sort.Strings(paths)
+		collisions = append(collisions, models.KeyCollision{
+			Normalized: normalized,
+			Keys:       paths,
+		})
+	}
+
+	sort.Slice(collisions, func(i, j int) bool {
+		return collisions[i].Normalized < collisions[j].Normalized
+	})
+	return collisions, nil
+}
+
+// MergeKeyCollisions resolves collisions found by FindKeyCollisions. For each
+// group one record is kept under the normalized key: the record already stored
+// there, or else the oldest one. The other records are moved to `merged:<key>`
+// so nothing is lost, and their aliases now point at the kept record. The click
+// statistics of every record in the group are added to those of the normalized key. When apply is false the plan is only reported.
+func MergeKeyCollisions(rdb *redis.Client, apply bool) ([]models.KeyCollision, error) {
+	ctx := context.Background()
+
+	collisions, err := FindKeyCollisions(rdb)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, collision := range collisions {
+		kept, err := pickKeptKey(ctx, rdb, collision)
+		if err != nil {
+			return nil, err
+		}
+		collisions[i].Kept = kept
+
+		for _, key := range collision.Keys {
+			if key != kept {
+				collisions[i].Merged = append(collisions[i].Merged, key)
+			}
+		}
+
+		if !apply {
+			continue
+		}
+
+		for _, key := range collisions[i].Merged {
+			if err := rdb.HSet(ctx, "path:"+key, "mergedInto", collision.Normalized).Err(); err != nil {
+				return nil, fmt.Errorf("failed to mark %s as merged: %w", key, err)
+			}
+			if err := rdb.Rename(ctx, "path:"+key, "merged:"+key).Err(); err != nil {
+				return nil, fmt.Errorf("failed to move %s: %w", key, err)
+			}
+			if err := mergeStats(ctx, rdb, key, collision.Normalized); err != nil {
+				return nil, fmt.Errorf("failed to merge the statistics of %s: %w", key, err)
+			}
+			rlog.Info("Merged colliding key", rlog.String("key", key), rlog.String("into", collision.Normalized))
+		}
+
+		if kept != collision.Normalized {
+			if err := rdb.Rename(ctx, "path:"+kept, "path:"+collision.Normalized).Err(); err != nil {
+				return nil, fmt.Errorf("failed to rename %s: %w", kept, err)
+			}
+			if err := mergeStats(ctx, rdb, kept, collision.Normalized); err != nil {
+				return nil, fmt.Errorf("failed to merge the statistics of %s: %w", kept, err)
+			}
+			rlog.Info("Renamed key to normalized form", rlog.String("key", kept), rlog.String("to", collision.Normalized))
+		}
+	}
+
+	if apply {
+		if err := normalizeAliases(ctx, rdb); err != nil {
+			return nil, err
+		}
+	}
+	return collisions, nil
+}
+
+// mergeStats adds the click statistics and ranking of a link stored under from to those of the
+// normalized key to, and removes them from from. Counters are summed, visitor estimates merged and
+// the latest access kept, so clicks already counted under the normalized key are not lost.
+func mergeStats(ctx context.Context, rdb *redis.Client, from string, to string) error {
+	counters, err := rdb.HGetAll(ctx, "stats:"+from).Result()
+	if err != nil {
+		return err
+	}
+	lastAccess, err := rdb.HGet(ctx, statsKey(to), statsLastAccessField).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+	clicks, err := rdb.ZScore(ctx, linkRankingKey, from).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	fields := slices.Sorted(maps.Keys(counters))
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, field := range fields {
+			if field == statsLastAccessField {
+				if counters[field] > lastAccess {
+					pipe.HSet(ctx, statsKey(to), field, counters[field])
+				}
+				continue
+			}
+			count, err := strconv.ParseInt(counters[field], 10, 64)
+			if err != nil {
+				rlog.Error("Failed to parse click counter", err, rlog.String("key", from), rlog.String("field", field))
+				continue
+			}
+			pipe.HIncrBy(ctx, statsKey(to), field, count)
+			if _, err := time.Parse(statsDateFormat, field); err == nil {
+				pipe.PFMerge(ctx, dailyVisitorsKey(to, field), dailyVisitorsKey(to, field), "visitors:"+from+"|"+field)
+			}
+		}
+		pipe.PFMerge(ctx, visitorsKey(to), visitorsKey(to), "visitors:"+from)
+		for _, dimension := range models.Dimensions {
+			pipe.ZUnionStore(ctx, breakdownKey(to, dimension), &redis.ZStore{
+				Keys: []string{breakdownKey(to, dimension), "breakdown:" + from + "|" + dimension},
+			})
+		}
+
+		pipe.Del(ctx, storedStatsKeys(from, fields)...)
+		pipe.ZRem(ctx, linkRankingKey, from)
+		if clicks > 0 {
+			pipe.ZIncrBy(ctx, linkRankingKey, clicks, to)
+		}
+		return nil
+	})
+	return err
+}
+
+// normalizeAliases rewrites alias pointers from before key normalisation was turned on, so they are
+// stored under the normalized alias and point at the normalized key of their link. The aliases are
+// added in normalized form to the alias list of that link, which now also holds the aliases of merged records.
+func normalizeAliases(ctx context.Context, rdb *redis.Client) error {
+	pointers, err := rdb.Keys(ctx, "alias:*").Result()
+	if err != nil {
+		return err
+	}
+
+	moved := make(map[string][]string)
+	for _, pointer := range pointers {
+		alias := strings.TrimPrefix(pointer, "alias:")
+		link, err := rdb.Get(ctx, pointer).Result()
+		if err == redis.Nil {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if alias == NormalizeKey(alias) && link == NormalizeKey(link) {
+			continue
+		}
+
+		_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Del(ctx, pointer)
+			pipe.Set(ctx, aliasKey(alias), NormalizeKey(link), 0)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to move alias %s: %w", alias, err)
+		}
+		moved[NormalizeKey(link)] = append(moved[NormalizeKey(link)], NormalizeKey(alias))
+		rlog.Info("Normalized alias", rlog.String("alias", alias), rlog.String("key", NormalizeKey(link)))
+	}
+
+	for _, link := range slices.Sorted(maps.Keys(moved)) {
+		path := "path:" + link
+		exists, err := rdb.Exists(ctx, path).Result()
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			continue
+		}
+
+		var aliases []string
+		encoded, err := rdb.HGet(ctx, path, "aliases").Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if encoded != "" {
+			if err := json.Unmarshal([]byte(encoded), &aliases); err != nil {
+				rlog.Error("Failed to decode aliases", err, rlog.String("key", link))
+			}
+		}
+
+		normalized := []string{}
+		for _, alias := range append(aliases, moved[link]...) {
+			if alias = NormalizeKey(alias); !slices.Contains(normalized, alias) {
+				normalized = append(normalized, alias)
+			}
+		}
+		encodedAliases, err := json.Marshal(normalized)
+		if err != nil {
+			return err
+		}
+		if err := rdb.HSet(ctx, path, "aliases", string(encodedAliases)).Err(); err != nil {
+			return fmt.Errorf("failed to update the aliases of %s: %w", link, err)
+		}
+	}
+	return nil
+}
+
+// pickKeptKey chooses which record of a collision group keeps the normalized key
+func pickKeptKey(ctx context.Context, rdb *redis.Client, collision models.KeyCollision) (string, error) {
+	if slices.Contains(collision.Keys, collision.Normalized) {
+		return collision.Normalized, nil
+	}
+	if len(collision.Keys) == 1 {
+		return collision.Keys[0], nil
+	}
+
+	kept := ""
+	keptCreated := ""
+	for _, key := range collision.Keys {
+		created, err := rdb.HGet(ctx, "path:"+key, "createdTime").Result()
+		if err != nil && err != redis.Nil {
+			return "", err
+		}
+
+		// RFC 3339 timestamps in the same zone sort chronologically as strings
+		if kept == "" || (created != "" && (keptCreated == "" || created < keptCreated)) {
+			kept = key
+			keptCreated = created
+		}
+	}
+	return kept, nil
+}
@@ -0,0 +1,246 @@
+package redis
+
+import (
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+	"github.com/spf13/viper"
+)
+
+// setKeyFolding configures key normalisation for the duration of a test
+func setKeyFolding(t *testing.T, foldCase, foldSeparators bool) {
+	t.Helper()
+	viper.Set("KEY_FOLD_CASE", foldCase)
+	viper.Set("KEY_FOLD_SEPARATORS", foldSeparators)
+	t.Cleanup(func() {
+		viper.Set("KEY_FOLD_CASE", false)
+		viper.Set("KEY_FOLD_SEPARATORS", false)
+	})
+}
+
+func TestNormalizeKey(t *testing.T) {
+	tests := []struct {
+		name           string
+		foldCase       bool
+		foldSeparators bool
+		key            string
+		want           string
+	}{
+		{name: "No folding", key: "Ny_Ansatt", want: "Ny_Ansatt"},
+		{name: "Fold case", foldCase: true, key: "Ny_Ansatt", want: "ny_ansatt"},
+		{name: "Fold separators", foldSeparators: true, key: "Ny_Ansatt", want: "Ny-Ansatt"},
+		{name: "Fold both", foldCase: true, foldSeparators: true, key: "Ny_Ansatt", want: "ny-ansatt"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			setKeyFolding(t, tt.foldCase, tt.foldSeparators)
+			if got := NormalizeKey(tt.key); got != tt.want {
+				t.Errorf("NormalizeKey(%q) = %q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetURLFallsBackToExactKey(t *testing.T) {
+	setKeyFolding(t, true, false)
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectExists("path:covid").SetVal(0)
+	mock.ExpectExists("path:Covid").SetVal(1)
+	mock.ExpectHGet("path:Covid", "url").SetVal("https://example.com")
+
+	got, err := GetURL(db, "Covid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "https://example.com" {
+		t.Errorf("GetURL() = %q, want %q", got, "https://example.com")
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestLegacyKeyIsUsedForEveryAccess(t *testing.T) {
+	setKeyFolding(t, true, false)
+
+	t.Run("Update keeps record and owner", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+
+		mock.ExpectExists("path:covid").SetVal(0)
+		mock.ExpectExists("path:Covid").SetVal(1)
+		mock.ExpectExists("path:Covid").SetVal(1)
+		mock.Regexp().ExpectHSet("path:Covid", "url", "https://example.com/new", "lastEditBy", "editor@example.com", "lastEditTime", ".*").SetVal(0)
+
+		msg, err := UpdateOrCreatePath(db, "Covid", "https://example.com/new", "editor@example.com")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if msg != "Path updated successfully" {
+			t.Errorf("expected update, got %q", msg)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("Owner and existence", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+
+		mock.ExpectExists("path:covid").SetVal(0)
+		mock.ExpectExists("path:Covid").SetVal(1)
+		mock.ExpectHGet("path:Covid", "createdBy").SetVal("owner@example.com")
+		mock.ExpectExists("path:covid").SetVal(0)
+		mock.ExpectExists("path:Covid").SetVal(1)
+		mock.ExpectExists("path:Covid", "alias:covid").SetVal(1)
+
+		owner, err := GetPathOwner(db, "Covid")
+		if err != nil || owner != "owner@example.com" {
+			t.Errorf("expected owner@example.com, got %q, %v", owner, err)
+		}
+		exists, err := URLExists(db, "Covid")
+		if err != nil || !exists {
+			t.Errorf("expected the legacy record to exist, got %v, %v", exists, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("Delete removes the legacy record", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+
+		mock.ExpectExists("path:covid").SetVal(0)
+		mock.ExpectExists("path:Covid").SetVal(1)
+		mock.ExpectHKeys("stats:covid").SetVal([]string{})
+		mock.ExpectHGet("path:Covid", "aliases").RedisNil()
+		keys := append([]string{"path:Covid"}, statsKeys("Covid", nil)...)
+		mock.ExpectDel(keys...).SetVal(1)
+		mock.ExpectZRem("ranking:clicks", "covid").SetVal(0)
+
+		deleted, err := Delete(db, "Covid")
+		if err != nil || !deleted {
+			t.Errorf("expected the legacy record to be deleted, got %v, %v", deleted, err)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unmet expectations: %v", err)
+		}
+	})
+}
+
+func TestMergeKeyCollisions(t *testing.T) {
+	setKeyFolding(t, true, true)
+
+	t.Run("Report only", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+
+		mock.ExpectKeys("path:*").SetVal([]string{"path:Covid", "path:covid", "path:ny_ansatt", "path:plain"})
+
+		collisions, err := MergeKeyCollisions(db, false)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(collisions) != 2 {
+			t.Fatalf("expected 2 collisions, got %+v", collisions)
+		}
+		if collisions[0].Normalized != "covid" || collisions[0].Kept != "covid" || len(collisions[0].Merged) != 1 || collisions[0].Merged[0] != "Covid" {
+			t.Errorf("unexpected collision: %+v", collisions[0])
+		}
+		if collisions[1].Normalized != "ny-ansatt" || collisions[1].Kept != "ny_ansatt" || len(collisions[1].Merged) != 0 {
+			t.Errorf("unexpected collision: %+v", collisions[1])
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unmet expectations: %v", err)
+		}
+	})
+
+	t.Run("Apply keeps oldest record", func(t *testing.T) {
+		db, mock := redismock.NewClientMock()
+
+		mock.ExpectKeys("path:*").SetVal([]string{"path:Onboarding", "path:ONBOARDING"})
+		mock.ExpectHGet("path:ONBOARDING", "createdTime").SetVal("2024-05-01T10:00:00Z")
+		mock.ExpectHGet("path:Onboarding", "createdTime").SetVal("2023-01-01T10:00:00Z")
+		mock.ExpectHSet("path:ONBOARDING", "mergedInto", "onboarding").SetVal(1)
+		mock.ExpectRename("path:ONBOARDING", "merged:ONBOARDING").SetVal("OK")
+
+		// The clicks of the merged record are added to the normalized key, keeping the latest access
+		mock.ExpectHGetAll("stats:ONBOARDING").SetVal(map[string]string{
+			"total": "3", "2025-03-01": "2", "2025-03-02": "1", "bots": "4", "lastAccess": "2025-03-02T08:00:00Z",
+		})
+		mock.ExpectHGet("stats:onboarding", "lastAccess").SetVal("2025-03-01T12:00:00Z")
+		mock.ExpectZScore("ranking:clicks", "ONBOARDING").SetVal(3)
+		mock.ExpectTxPipeline()
+		mock.ExpectHIncrBy("stats:onboarding", "2025-03-01", 2).SetVal(2)
+		mock.ExpectPFMerge("visitors:onboarding|2025-03-01", "visitors:onboarding|2025-03-01", "visitors:ONBOARDING|2025-03-01").SetVal("OK")
+		mock.ExpectHIncrBy("stats:onboarding", "2025-03-02", 1).SetVal(1)
+		mock.ExpectPFMerge("visitors:onboarding|2025-03-02", "visitors:onboarding|2025-03-02", "visitors:ONBOARDING|2025-03-02").SetVal("OK")
+		mock.ExpectHIncrBy("stats:onboarding", "bots", 4).SetVal(4)
+		mock.ExpectHSet("stats:onboarding", "lastAccess", "2025-03-02T08:00:00Z").SetVal(1)
+		mock.ExpectHIncrBy("stats:onboarding", "total", 3).SetVal(3)
+		expectMergedBreakdowns(mock, "ONBOARDING", "onboarding", []string{"2025-03-01", "2025-03-02", "bots", "lastAccess", "total"})
+		mock.ExpectZRem("ranking:clicks", "ONBOARDING").SetVal(1)
+		mock.ExpectZIncrBy("ranking:clicks", 3, "onboarding").SetVal(3)
+		mock.ExpectTxPipelineExec()
+
+		mock.ExpectRename("path:Onboarding", "path:onboarding").SetVal("OK")
+
+		// The clicks of the kept record are added on top, an older access does not replace the latest one
+		mock.ExpectHGetAll("stats:Onboarding").SetVal(map[string]string{
+			"total": "7", "2025-03-01": "7", "lastAccess": "2025-03-01T09:00:00Z",
+		})
+		mock.ExpectHGet("stats:onboarding", "lastAccess").SetVal("2025-03-02T08:00:00Z")
+		mock.ExpectZScore("ranking:clicks", "Onboarding").SetVal(7)
+		mock.ExpectTxPipeline()
+		mock.ExpectHIncrBy("stats:onboarding", "2025-03-01", 7).SetVal(9)
+		mock.ExpectPFMerge("visitors:onboarding|2025-03-01", "visitors:onboarding|2025-03-01", "visitors:Onboarding|2025-03-01").SetVal("OK")
+		mock.ExpectHIncrBy("stats:onboarding", "total", 7).SetVal(10)
+		expectMergedBreakdowns(mock, "Onboarding", "onboarding", []string{"2025-03-01", "lastAccess", "total"})
+		mock.ExpectZRem("ranking:clicks", "Onboarding").SetVal(1)
+		mock.ExpectZIncrBy("ranking:clicks", 7, "onboarding").SetVal(10)
+		mock.ExpectTxPipelineExec()
+
+		// Aliases of both records point at the normalized key, and are listed on the kept record
+		mock.ExpectKeys("alias:*").SetVal([]string{"alias:Intro", "alias:start", "alias:welcome"})
+		mock.ExpectGet("alias:Intro").SetVal("Onboarding")
+		mock.ExpectTxPipeline()
+		mock.ExpectDel("alias:Intro").SetVal(1)
+		mock.ExpectSet("alias:intro", "onboarding", 0).SetVal("OK")
+		mock.ExpectTxPipelineExec()
+		mock.ExpectGet("alias:start").SetVal("ONBOARDING")
+		mock.ExpectTxPipeline()
+		mock.ExpectDel("alias:start").SetVal(1)
+		mock.ExpectSet("alias:start", "onboarding", 0).SetVal("OK")
+		mock.ExpectTxPipelineExec()
+		mock.ExpectGet("alias:welcome").SetVal("other")
+		mock.ExpectExists("path:onboarding").SetVal(1)
+		mock.ExpectHGet("path:onboarding", "aliases").SetVal(`["Intro"]`)
+		mock.ExpectHSet("path:onboarding", "aliases", `["intro","start"]`).SetVal(0)
+
+		collisions, err := MergeKeyCollisions(db, true)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(collisions) != 1 || collisions[0].Kept != "Onboarding" {
+			t.Errorf("unexpected collisions: %+v", collisions)
+		}
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("there were unmet expectations: %v", err)
+		}
+	})
+}
+
+// expectMergedBreakdowns expects the visitor estimate and breakdowns of from to be merged into to,
+// and the statistics of from to be deleted
+func expectMergedBreakdowns(mock redismock.ClientMock, from string, to string, fields []string) {
+	mock.ExpectPFMerge("visitors:"+to, "visitors:"+to, "visitors:"+from).SetVal("OK")
+	for _, dimension := range models.Dimensions {
+		mock.ExpectZUnionStore("breakdown:"+to+"|"+dimension, &redis.ZStore{
+			Keys: []string{"breakdown:" + to + "|" + dimension, "breakdown:" + from + "|" + dimension},
+		}).SetVal(1)
+	}
+	mock.ExpectDel(storedStatsKeys(from, fields)...).SetVal(1)
+}
@@ -0,0 +1,150 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+	"golang.org/x/text/language"
+)
+
+var (
+	// ErrInvalidLanguage is returned when a language tag cannot be parsed
+	ErrInvalidLanguage = errors.New("invalid language tag")
+	// ErrLanguageNotFound is returned when a language variant is not configured on a redirect
+	ErrLanguageNotFound = errors.New("language variant not found")
+)
+
+// GetLanguages retrieves the per-language targets configured for a redirect
+func GetLanguages(rdb *redis.Client, key string) (map[string]string, error) {
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if redirect.Languages == nil {
+		return map[string]string{}, nil
+	}
+	return redirect.Languages, nil
+}
+
+// SetLanguages replaces all per-language targets of a redirect
+func SetLanguages(rdb *redis.Client, key string, languages map[string]string, user string) error {
+	normalized := make(map[string]string, len(languages))
+	for lang, target := range languages {
+		tag, target, err := validateLanguage(key, lang, target)
+		if err != nil {
+			return err
+		}
+		normalized[tag] = target
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+	for k, target := range normalized {
+		if normalized[k], err = checkChain(rdb, redirect.Path, target); err != nil {
+			return err
+		}
+	}
+
+	return saveLanguages(rdb, redirect.Path, normalized, user)
+}
+
+// SetLanguage adds or replaces the target for a single language of a redirect
+func SetLanguage(rdb *redis.Client, key string, lang string, target string, user string) error {
+	tag, target, err := validateLanguage(key, lang, target)
+	if err != nil {
+		return err
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+	if target, err = checkChain(rdb, redirect.Path, target); err != nil {
+		return err
+	}
+	languages := redirect.Languages
+	if languages == nil {
+		languages = map[string]string{}
+	}
+	languages[tag] = target
+
+	return saveLanguages(rdb, redirect.Path, languages, user)
+}
+
+// RemoveLanguage removes the target for a single language of a redirect
+func RemoveLanguage(rdb *redis.Client, key string, lang string, user string) error {
+	tag, err := NormalizeLanguage(lang)
+	if err != nil {
+		return err
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+	if _, ok := redirect.Languages[tag]; !ok {
+		return fmt.Errorf("%w: `%s`", ErrLanguageNotFound, tag)
+	}
+	delete(redirect.Languages, tag)
+
+	return saveLanguages(rdb, redirect.Path, redirect.Languages, user)
+}
+
+// NormalizeLanguage parses a BCP 47 language tag and returns its canonical form
+func NormalizeLanguage(lang string) (string, error) {
+	tag, err := language.Parse(strings.TrimSpace(lang))
+	if err != nil || tag == language.Und {
+		return "", fmt.Errorf("%w: `%s`", ErrInvalidLanguage, lang)
+	}
+	return tag.String(), nil
+}
+
+// validateLanguage normalizes and validates a language tag and its target
+func validateLanguage(key, lang, target string) (string, string, error) {
+	tag, err := NormalizeLanguage(lang)
+	if err != nil {
+		return "", "", err
+	}
+
+	target = strings.TrimSuffix(strings.TrimSpace(target), "/")
+	if target == "" {
+		return "", "", fmt.Errorf("%w: target URL cannot be empty", ErrInvalidValue)
+	}
+	if err := validatePathInput(key, target); err != nil {
+		return "", "", err
+	}
+
+	return tag, target, nil
+}
+
+// saveLanguages stores the per-language targets of a redirect together with edit information.
+// The key must be the stored key as resolved by GetRedirect.
+func saveLanguages(rdb *redis.Client, key string, languages map[string]string, user string) error {
+	encoded, err := json.Marshal(languages)
+	if err != nil {
+		return err
+	}
+
+	editTime := time.Now().Format(time.RFC3339)
+	err = rdb.HSet(context.Background(), "path:"+key,
+		"languages", string(encoded),
+		"lastEditBy", user,
+		"lastEditTime", editTime,
+	).Err()
+	if err != nil {
+		rlog.Error("Failed to save language variants", err, rlog.String("key", key), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("Language variants updated", rlog.String("key", key), rlog.Any("languages", len(languages)), rlog.String("user", user))
+	return nil
+}
@@ -0,0 +1,72 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		lang    string
+		want    string
+		wantErr bool
+	}{
+		{lang: "nb", want: "nb"},
+		{lang: " EN-gb ", want: "en-GB"},
+		{lang: "nn-NO", want: "nn-NO"},
+		{lang: "", wantErr: true},
+		{lang: "not a language", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			got, err := NormalizeLanguage(tt.lang)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeLanguage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeLanguage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetAndRemoveLanguage(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+
+	t.Run("Set language variant", func(t *testing.T) {
+		mock.ExpectHGetAll("path:vaksine").SetVal(map[string]string{
+			"url":       "https://example.com",
+			"languages": `{"nb":"https://example.com/nb"}`,
+		})
+		mock.ExpectHSet("path:vaksine",
+			"languages", `{"en":"https://example.com/en","nb":"https://example.com/nb"}`,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+
+		if err := SetLanguage(db, "vaksine", "EN", "https://example.com/en/", user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Remove unknown language variant", func(t *testing.T) {
+		mock.ExpectHGetAll("path:vaksine").SetVal(map[string]string{
+			"url":       "https://example.com",
+			"languages": `{"nb":"https://example.com/nb"}`,
+		})
+
+		err := RemoveLanguage(db, "vaksine", "de", user)
+		if !errors.Is(err, ErrLanguageNotFound) {
+			t.Errorf("expected ErrLanguageNotFound, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package redis
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// notFoundRankingKey is the sorted set of requested keys without a link, by number of hits
+	notFoundRankingKey = "ranking:notfound"
+	// maxNotFoundKeys caps the not-found ranking, since any path can be requested
+	maxNotFoundKeys = 1000
+)
+
+// addNotFound queues counting a hit on a key without a link. Only the most requested keys are kept.
+func addNotFound(ctx context.Context, pipe redis.Pipeliner, key string) {
+	pipe.ZIncrBy(ctx, notFoundRankingKey, 1, NormalizeKey(key))
+	pipe.ZRemRangeByRank(ctx, notFoundRankingKey, 0, -(maxNotFoundKeys + 1))
+}
+
+// RecordNotFound counts a redirect request for a key without a link
+func RecordNotFound(rdb *redis.Client, key string) error {
+	return RecordClicks(rdb, []models.ClickEvent{{Key: key, NotFound: true}})
+}
+
+// GetOverview returns the global usage of shorty: the number of links, the links created in each
+// of the last weeks (ISO weeks, oldest first), and the top most clicked links, most active creators,
+// most requested keys without a link, and most used target domains
+func GetOverview(rdb *redis.Client, top int, weeks int) (models.AdminOverview, error) {
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	keys, err := rdb.Keys(ctx, "path:*").Result()
+	if err != nil {
+		return models.AdminOverview{}, err
+	}
+
+	fields := make([]*redis.SliceCmd, len(keys))
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			fields[i] = pipe.HMGet(ctx, key, "url", "createdBy", "createdTime")
+		}
+		return nil
+	})
+	if err != nil {
+		return models.AdminOverview{}, err
+	}
+
+	// Weeks without new links are included, so the series has no gaps
+	perWeek := make(map[string]int64, weeks)
+	var weekOrder []string
+	for i := weeks - 1; i >= 0; i-- {
+		week := isoWeek(now.AddDate(0, 0, -7*i))
+		perWeek[week] = 0
+		weekOrder = append(weekOrder, week)
+	}
+
+	overview := models.AdminOverview{GeneratedAt: now}
+	creators, domains := map[string]int64{}, map[string]int64{}
+	for _, cmd := range fields {
+		values := cmd.Val()
+		if len(values) != 3 || values[0] == nil {
+			continue
+		}
+		overview.TotalLinks++
+
+		if creator, _ := values[1].(string); creator != "" {
+			creators[creator]++
+		}
+		if target, _ := values[0].(string); target != "" {
+			if u, err := url.Parse(target); err == nil && u.Hostname() != "" {
+				domains[strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")]++
+			}
+		}
+		if created, _ := values[2].(string); created != "" {
+			if t, err := time.Parse(time.RFC3339, created); err == nil {
+				if _, ok := perWeek[isoWeek(t)]; ok {
+					perWeek[isoWeek(t)]++
+				}
+			}
+		}
+	}
+
+	for _, week := range weekOrder {
+		overview.CreatedPerWeek = append(overview.CreatedPerWeek, models.Count{Value: week, Count: perWeek[week]})
+	}
+	overview.TopCreators = topCounts(creators, top)
+	overview.TargetDomains = topCounts(domains, top)
+
+	if overview.MostClicked, err = TopLinks(rdb, top); err != nil {
+		return models.AdminOverview{}, err
+	}
+
+	overview.NotFound = []models.Count{}
+	if top > 0 {
+		notFound, err := rdb.ZRevRangeWithScores(ctx, notFoundRankingKey, 0, int64(top-1)).Result()
+		if err != nil {
+			return models.AdminOverview{}, err
+		}
+		for _, member := range notFound {
+			key, _ := member.Member.(string)
+			overview.NotFound = append(overview.NotFound, models.Count{Value: key, Count: int64(member.Score)})
+		}
+	}
+	return overview, nil
+}
+
+// isoWeek returns the ISO 8601 week of t, such as 2025-W11
+func isoWeek(t time.Time) string {
+	year, week := t.ISOWeek()
+	return fmt.Sprintf("%d-W%02d", year, week)
+}
+
+// topCounts returns the limit values with the highest counts, highest first and by value on ties
+func topCounts(counts map[string]int64, limit int) []models.Count {
+	result := make([]models.Count, 0, len(counts))
+	for value, count := range counts {
+		result = append(result, models.Count{Value: value, Count: count})
+	}
+	slices.SortFunc(result, func(a, b models.Count) int {
+		if c := cmp.Compare(b.Count, a.Count); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Value, b.Value)
+	})
+	return result[:min(limit, len(result))]
+}
@@ -0,0 +1,77 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestRecordNotFound(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectZIncrBy("ranking:notfound", 1, "covid").SetVal(1)
+	mock.ExpectZRemRangeByRank("ranking:notfound", 0, -1001).SetVal(0)
+
+	if err := RecordNotFound(db, "covid"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestGetOverview(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	now := time.Now().UTC()
+	lastWeek := now.AddDate(0, 0, -7)
+
+	mock.ExpectKeys("path:*").SetVal([]string{"path:onboarding", "path:kantine", "path:vaksine", "path:broken"})
+	mock.ExpectHMGet("path:onboarding", "url", "createdBy", "createdTime").SetVal([]interface{}{"https://www.nhn.no/onboarding", "kari@nhn.no", now.Format(time.RFC3339)})
+	mock.ExpectHMGet("path:kantine", "url", "createdBy", "createdTime").SetVal([]interface{}{"https://nhn.no/kantine", "kari@nhn.no", lastWeek.Format(time.RFC3339)})
+	mock.ExpectHMGet("path:vaksine", "url", "createdBy", "createdTime").SetVal([]interface{}{"https://helsenorge.no/vaksine", "ola@nhn.no", "2020-01-01T00:00:00Z"})
+	mock.ExpectHMGet("path:broken", "url", "createdBy", "createdTime").SetVal([]interface{}{nil, nil, nil})
+	mock.ExpectZRevRangeWithScores("ranking:clicks", 0, 1).SetVal([]redis.Z{{Member: "vaksine", Score: 100}})
+	mock.ExpectZRevRangeWithScores("ranking:notfound", 0, 1).SetVal([]redis.Z{{Member: "covid", Score: 12}})
+
+	overview, err := GetOverview(db, 2, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if overview.TotalLinks != 3 {
+		t.Errorf("expected 3 links, got %d", overview.TotalLinks)
+	}
+	wantWeeks := []models.Count{
+		{Value: isoWeek(now.AddDate(0, 0, -14)), Count: 0},
+		{Value: isoWeek(lastWeek), Count: 1},
+		{Value: isoWeek(now), Count: 1},
+	}
+	if len(overview.CreatedPerWeek) != 3 {
+		t.Fatalf("expected 3 weeks, got %+v", overview.CreatedPerWeek)
+	}
+	for i, want := range wantWeeks {
+		if overview.CreatedPerWeek[i] != want {
+			t.Errorf("expected week %+v, got %+v", want, overview.CreatedPerWeek[i])
+		}
+	}
+	if len(overview.TopCreators) != 2 || overview.TopCreators[0] != (models.Count{Value: "kari@nhn.no", Count: 2}) {
+		t.Errorf("unexpected creators: %+v", overview.TopCreators)
+	}
+	if len(overview.TargetDomains) != 2 || overview.TargetDomains[0] != (models.Count{Value: "nhn.no", Count: 2}) {
+		t.Errorf("unexpected target domains: %+v", overview.TargetDomains)
+	}
+	if len(overview.MostClicked) != 1 || overview.MostClicked[0].Path != "vaksine" {
+		t.Errorf("unexpected most clicked: %+v", overview.MostClicked)
+	}
+	if len(overview.NotFound) != 1 || overview.NotFound[0] != (models.Count{Value: "covid", Count: 12}) {
+		t.Errorf("unexpected not found: %+v", overview.NotFound)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,175 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/go-redis/redis/v8"
+)
+
+// renameAttempts is how often a rename is retried when the keys change while it runs
+const renameAttempts = 3
+
+var (
+	// ErrKeyReserved is returned when a key was recently renamed and is kept free for a grace period
+	ErrKeyReserved = errors.New("key is reserved")
+	// ErrRenameConflict is returned when the link kept changing while it was renamed
+	ErrRenameConflict = errors.New("link changed during rename")
+	// ErrRenameAlias is returned when renaming through an alias, which would rename the link it points at
+	ErrRenameAlias = errors.New("aliases cannot be renamed")
+)
+
+// reservedKey returns the redis key that keeps a renamed key free for a grace period
+func reservedKey(key string) string {
+	return "reserved:" + NormalizeKey(key)
+}
+
+// IsReserved reports whether a key is kept free after a rename
+func IsReserved(rdb *redis.Client, key string) (bool, error) {
+	n, err := rdb.Exists(context.Background(), reservedKey(key)).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// RenameKey moves the redirect at key to newKey together with its metadata, aliases and click statistics.
+// Aliases cannot be renamed.
+// The old key can be kept as an alias, or reserved for the given period so it is not reused
+// right away. Returns the stored key of the renamed redirect.
+func RenameKey(rdb *redis.Client, key string, newKey string, user string, keepAlias bool, reserveFor time.Duration) (string, error) {
+	if err := ValidateKey(newKey); err != nil {
+		return "", err
+	}
+	newKey = NormalizeKey(newKey)
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return "", err
+	}
+	oldKey := redirect.Path
+	if NormalizeKey(key) != NormalizeKey(oldKey) {
+		return "", fmt.Errorf("%w: `%s` is an alias of `%s`, remove it and add the new alias instead", ErrRenameAlias, key, oldKey)
+	}
+	if NormalizeKey(oldKey) == newKey {
+		return "", fmt.Errorf("%w: `%s` is already the key of this link", ErrSameKeyValue, newKey)
+	}
+
+	ctx := context.Background()
+	oldPath, newPath := "path:"+oldKey, pathHashKey(newKey)
+
+	rename := func(tx *redis.Tx) error {
+		exists, err := tx.Exists(ctx, oldPath).Result()
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrURLNotFound
+		}
+
+		exists, err = tx.Exists(ctx, newPath).Result()
+		if err != nil {
+			return err
+		}
+		if exists > 0 {
+			return fmt.Errorf("%w: `%s`", ErrKeyExists, newKey)
+		}
+
+		// The new key may already be an alias or a reservation of this link, but not of another one
+		for _, pointer := range []string{aliasKey(newKey), reservedKey(newKey)} {
+			target, err := tx.Get(ctx, pointer).Result()
+			if err != nil && err != redis.Nil {
+				return err
+			}
+			if err == nil && NormalizeKey(target) != NormalizeKey(oldKey) {
+				if pointer == reservedKey(newKey) {
+					return fmt.Errorf("%w: `%s`", ErrKeyReserved, newKey)
+				}
+				return fmt.Errorf("%w: `%s`", ErrKeyExists, newKey)
+			}
+		}
+
+		var aliases []string
+		if encoded, err := tx.HGet(ctx, oldPath, "aliases").Result(); err == nil && encoded != "" {
+			if err := json.Unmarshal([]byte(encoded), &aliases); err != nil {
+				return err
+			}
+		} else if err != nil && err != redis.Nil {
+			return err
+		}
+		aliases = slices.DeleteFunc(aliases, func(alias string) bool { return alias == newKey })
+		if keepAlias {
+			aliases = append(aliases, NormalizeKey(oldKey))
+		}
+		if aliases == nil {
+			aliases = []string{}
+		}
+		encodedAliases, err := json.Marshal(aliases)
+		if err != nil {
+			return err
+		}
+
+		days, err := tx.HKeys(ctx, statsKey(oldKey)).Result()
+		if err != nil {
+			return err
+		}
+		oldStats, newStats := statsKeys(oldKey, days), statsKeys(newKey, days)
+
+		clicks, err := tx.ZScore(ctx, linkRankingKey, NormalizeKey(oldKey)).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Rename(ctx, oldPath, newPath)
+			// Statistics keys may be missing, COPY skips those where RENAME would fail
+			for i := range oldStats {
+				pipe.Copy(ctx, oldStats[i], newStats[i], 0, true)
+			}
+			pipe.Del(ctx, oldStats...)
+			pipe.ZRem(ctx, linkRankingKey, NormalizeKey(oldKey))
+			if clicks > 0 {
+				pipe.ZAdd(ctx, linkRankingKey, &redis.Z{Score: clicks, Member: newKey})
+			}
+			pipe.HSet(ctx, newPath,
+				"aliases", string(encodedAliases),
+				"lastEditBy", user,
+				"lastEditTime", time.Now().Format(time.RFC3339),
+			)
+			pipe.Del(ctx, aliasKey(newKey), reservedKey(newKey))
+			for _, alias := range aliases {
+				pipe.Set(ctx, aliasKey(alias), newKey, 0)
+			}
+			if !keepAlias && reserveFor > 0 {
+				pipe.Set(ctx, reservedKey(oldKey), newKey, reserveFor)
+			}
+			return nil
+		})
+		return err
+	}
+
+	// Clicks on the link change its statistics, which must not be lost while they are moved
+	watched := append([]string{oldPath, newPath, aliasKey(newKey), reservedKey(newKey)}, statsKeys(oldKey, nil)...)
+	for range renameAttempts {
+		err = rdb.Watch(ctx, rename, watched...)
+		if err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err == redis.TxFailedErr {
+		err = ErrRenameConflict
+	}
+	if err != nil {
+		rlog.Error("Failed to rename key", err, rlog.String("key", oldKey), rlog.String("newKey", newKey), rlog.String("user", user))
+		return "", err
+	}
+
+	rlog.Info("Key renamed", rlog.String("key", oldKey), rlog.String("newKey", newKey), rlog.String("user", user),
+		rlog.Any("keepAlias", keepAlias), rlog.Any("reserveFor", reserveFor))
+	return newKey, nil
+}
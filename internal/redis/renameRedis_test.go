@@ -0,0 +1,162 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestRenameKey(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+	renameWatched := []string{"path:old", "path:new", "alias:new", "reserved:new", "stats:old", "visitors:old",
+		"breakdown:old|referrer", "breakdown:old|device", "breakdown:old|browser", "breakdown:old|country", "breakdown:old|target"}
+
+	t.Run("Rename keeping old key as alias", func(t *testing.T) {
+		mock.ExpectHGetAll("path:old").SetVal(map[string]string{"url": "https://example.com", "aliases": `["other"]`})
+		mock.ExpectWatch(renameWatched...)
+		mock.ExpectExists("path:old").SetVal(1)
+		mock.ExpectExists("path:new").SetVal(0)
+		mock.ExpectGet("alias:new").RedisNil()
+		mock.ExpectGet("reserved:new").RedisNil()
+		mock.ExpectHGet("path:old", "aliases").SetVal(`["other"]`)
+		mock.ExpectHKeys("stats:old").SetVal([]string{"total", "2025-03-01", "bots", "bots|2025-03-01"})
+		mock.ExpectZScore("ranking:clicks", "old").SetVal(12)
+		mock.ExpectTxPipeline()
+		mock.ExpectRename("path:old", "path:new").SetVal("OK")
+		mock.ExpectCopy("stats:old", "stats:new", 0, true).SetVal(1)
+		mock.ExpectCopy("visitors:old", "visitors:new", 0, true).SetVal(1)
+		for _, dimension := range models.Dimensions {
+			mock.ExpectCopy("breakdown:old|"+dimension, "breakdown:new|"+dimension, 0, true).SetVal(1)
+		}
+		mock.ExpectCopy("visitors:old|2025-03-01", "visitors:new|2025-03-01", 0, true).SetVal(1)
+		mock.ExpectDel("stats:old", "visitors:old",
+			"breakdown:old|referrer", "breakdown:old|device", "breakdown:old|browser", "breakdown:old|country", "breakdown:old|target",
+			"visitors:old|2025-03-01").SetVal(8)
+		mock.ExpectZRem("ranking:clicks", "old").SetVal(1)
+		mock.ExpectZAdd("ranking:clicks", &redis.Z{Score: 12, Member: "new"}).SetVal(1)
+		mock.ExpectHSet("path:new",
+			"aliases", `["other","old"]`,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+		mock.ExpectDel("alias:new", "reserved:new").SetVal(0)
+		mock.ExpectSet("alias:other", "new", 0).SetVal("OK")
+		mock.ExpectSet("alias:old", "new", 0).SetVal("OK")
+		mock.ExpectTxPipelineExec()
+
+		key, err := RenameKey(db, "old", "new", user, true, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if key != "new" {
+			t.Errorf("expected new key, got %q", key)
+		}
+	})
+
+	t.Run("Rename promoting an alias and reserving the old key", func(t *testing.T) {
+		mock.ExpectHGetAll("path:old").SetVal(map[string]string{"url": "https://example.com", "aliases": `["new"]`})
+		mock.ExpectWatch(renameWatched...)
+		mock.ExpectExists("path:old").SetVal(1)
+		mock.ExpectExists("path:new").SetVal(0)
+		mock.ExpectGet("alias:new").SetVal("old")
+		mock.ExpectGet("reserved:new").RedisNil()
+		mock.ExpectHGet("path:old", "aliases").SetVal(`["new"]`)
+		mock.ExpectHKeys("stats:old").SetVal([]string{})
+		mock.ExpectZScore("ranking:clicks", "old").RedisNil()
+		mock.ExpectTxPipeline()
+		mock.ExpectRename("path:old", "path:new").SetVal("OK")
+		mock.ExpectCopy("stats:old", "stats:new", 0, true).SetVal(0)
+		mock.ExpectCopy("visitors:old", "visitors:new", 0, true).SetVal(0)
+		for _, dimension := range models.Dimensions {
+			mock.ExpectCopy("breakdown:old|"+dimension, "breakdown:new|"+dimension, 0, true).SetVal(0)
+		}
+		mock.ExpectDel("stats:old", "visitors:old",
+			"breakdown:old|referrer", "breakdown:old|device", "breakdown:old|browser", "breakdown:old|country", "breakdown:old|target").SetVal(0)
+		mock.ExpectZRem("ranking:clicks", "old").SetVal(0)
+		mock.ExpectHSet("path:new",
+			"aliases", `[]`,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+		mock.ExpectDel("alias:new", "reserved:new").SetVal(1)
+		mock.ExpectSet("reserved:old", "new", 24*time.Hour).SetVal("OK")
+		mock.ExpectTxPipelineExec()
+
+		if _, err := RenameKey(db, "old", "new", user, false, 24*time.Hour); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("New key taken", func(t *testing.T) {
+		mock.ExpectHGetAll("path:old").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectWatch(renameWatched...)
+		mock.ExpectExists("path:old").SetVal(1)
+		mock.ExpectExists("path:new").SetVal(1)
+
+		_, err := RenameKey(db, "old", "new", user, false, 0)
+		if !errors.Is(err, ErrKeyExists) {
+			t.Errorf("expected ErrKeyExists, got %v", err)
+		}
+	})
+
+	t.Run("New key reserved for another link", func(t *testing.T) {
+		mock.ExpectHGetAll("path:old").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectWatch(renameWatched...)
+		mock.ExpectExists("path:old").SetVal(1)
+		mock.ExpectExists("path:new").SetVal(0)
+		mock.ExpectGet("alias:new").RedisNil()
+		mock.ExpectGet("reserved:new").SetVal("elsewhere")
+
+		_, err := RenameKey(db, "old", "new", user, false, 0)
+		if !errors.Is(err, ErrKeyReserved) {
+			t.Errorf("expected ErrKeyReserved, got %v", err)
+		}
+	})
+
+	t.Run("Link changed during every attempt", func(t *testing.T) {
+		mock.ExpectHGetAll("path:old").SetVal(map[string]string{"url": "https://example.com"})
+		for range renameAttempts {
+			mock.ExpectWatch(renameWatched...).SetErr(redis.TxFailedErr)
+		}
+
+		_, err := RenameKey(db, "old", "new", user, false, 0)
+		if !errors.Is(err, ErrRenameConflict) {
+			t.Errorf("expected ErrRenameConflict, got %v", err)
+		}
+	})
+
+	t.Run("Alias of another link", func(t *testing.T) {
+		mock.ExpectHGetAll("path:intro").SetVal(map[string]string{})
+		mock.ExpectGet("alias:intro").SetVal("old")
+		mock.ExpectHGetAll("path:old").SetVal(map[string]string{"url": "https://example.com", "aliases": `["intro"]`})
+
+		if _, err := RenameKey(db, "intro", "new", user, false, 0); !errors.Is(err, ErrRenameAlias) {
+			t.Errorf("expected ErrRenameAlias, got %v", err)
+		}
+	})
+
+	t.Run("Same key", func(t *testing.T) {
+		mock.ExpectHGetAll("path:old").SetVal(map[string]string{"url": "https://example.com"})
+
+		_, err := RenameKey(db, "old", "old", user, false, 0)
+		if !errors.Is(err, ErrSameKeyValue) {
+			t.Errorf("expected ErrSameKeyValue, got %v", err)
+		}
+	})
+
+	t.Run("Invalid new key", func(t *testing.T) {
+		_, err := RenameKey(db, "old", "swagger", user, false, 0)
+		if !errors.Is(err, ErrInvalidKey) {
+			t.Errorf("expected ErrInvalidKey, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,266 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// ServiceUserPrefix starts the user name of a service account, as stored in createdBy of its links
+	ServiceUserPrefix = "service:"
+	// DefaultServiceDailyLinks is how many links a service account can create a day when no limit is given
+	DefaultServiceDailyLinks = 100
+)
+
+var (
+	// ErrServiceAccountNotFound is returned when no service account is registered for a name, client id or subject
+	ErrServiceAccountNotFound = errors.New("service account not found")
+	// ErrInvalidServiceAccount is returned when a service account name, identity or settings are not allowed
+	ErrInvalidServiceAccount = errors.New("invalid service account")
+)
+
+var serviceNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// serviceKey returns the redis key of a service account
+func serviceKey(name string) string {
+	return "service:" + name
+}
+
+// serviceClientKey returns the redis key pointing from a client id to its service account
+func serviceClientKey(clientID string) string {
+	return "service-client:" + clientID
+}
+
+// serviceSubjectKey returns the redis key pointing from a subject to its service account
+func serviceSubjectKey(subject string) string {
+	return "service-subject:" + subject
+}
+
+// ServiceUser returns the user name of a service account
+func ServiceUser(name string) string {
+	return ServiceUserPrefix + name
+}
+
+// GetServiceAccounts retrieves all service accounts sorted by name
+func GetServiceAccounts(rdb *redis.Client) ([]models.ServiceAccount, error) {
+	ctx := context.Background()
+	keys, err := rdb.Keys(ctx, "service:*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	accounts := make([]models.ServiceAccount, 0, len(keys))
+	for _, key := range keys {
+		fields, err := rdb.HGetAll(ctx, key).Result()
+		if err != nil {
+			rlog.Error("Failed to get service account", err, rlog.String("key", key))
+			continue
+		}
+		accounts = append(accounts, serviceAccountFromHash(strings.TrimPrefix(key, "service:"), fields))
+	}
+
+	sort.Slice(accounts, func(i, j int) bool { return accounts[i].Name < accounts[j].Name })
+	return accounts, nil
+}
+
+// GetServiceAccount retrieves a single service account by name
+func GetServiceAccount(rdb *redis.Client, name string) (models.ServiceAccount, error) {
+	fields, err := rdb.HGetAll(context.Background(), serviceKey(name)).Result()
+	if err != nil {
+		return models.ServiceAccount{}, err
+	}
+	if len(fields) == 0 {
+		return models.ServiceAccount{}, fmt.Errorf("%w: `%s`", ErrServiceAccountNotFound, name)
+	}
+	return serviceAccountFromHash(name, fields), nil
+}
+
+// FindServiceAccount returns the service account registered for a client id, or else for a subject
+func FindServiceAccount(rdb *redis.Client, clientID string, subject string) (models.ServiceAccount, error) {
+	ctx := context.Background()
+	for _, key := range []string{serviceClientKey(clientID), serviceSubjectKey(subject)} {
+		if strings.HasSuffix(key, ":") {
+			continue
+		}
+		name, err := rdb.Get(ctx, key).Result()
+		if err == redis.Nil {
+			continue
+		} else if err != nil {
+			return models.ServiceAccount{}, err
+		}
+		return GetServiceAccount(rdb, name)
+	}
+	return models.ServiceAccount{}, ErrServiceAccountNotFound
+}
+
+// ServiceAccountExists reports whether a service account is registered under name
+func ServiceAccountExists(rdb *redis.Client, name string) bool {
+	exists, err := rdb.Exists(context.Background(), serviceKey(name)).Result()
+	if err != nil {
+		rlog.Error("Error checking service account", err, rlog.String("name", name))
+		return false
+	}
+	return exists == 1
+}
+
+// SetServiceAccount registers or replaces a service account. A client id or subject can only belong to one account.
+func SetServiceAccount(rdb *redis.Client, account models.ServiceAccount, user string) error {
+	account.ClientID = strings.TrimSpace(account.ClientID)
+	account.Subject = strings.TrimSpace(account.Subject)
+	account.Owner = strings.TrimSpace(account.Owner)
+	if !serviceNamePattern.MatchString(account.Name) {
+		return fmt.Errorf("%w: name can only contain lowercase letters, numbers, dash and underscore", ErrInvalidServiceAccount)
+	}
+	if account.Owner == "" {
+		return fmt.Errorf("%w: an owner must be given", ErrInvalidServiceAccount)
+	}
+	if account.ClientID == "" && account.Subject == "" {
+		return fmt.Errorf("%w: a client id or subject must be given", ErrInvalidServiceAccount)
+	}
+	if len(account.Scopes) == 0 {
+		return fmt.Errorf("%w: at least one scope must be given", ErrInvalidServiceAccount)
+	}
+	for _, scope := range account.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return fmt.Errorf("%w: unknown scope `%s`", ErrInvalidServiceAccount, scope)
+		}
+	}
+	if account.DailyLinkLimit < 0 {
+		return fmt.Errorf("%w: the daily link limit cannot be negative", ErrInvalidServiceAccount)
+	}
+	if account.DailyLinkLimit == 0 {
+		account.DailyLinkLimit = DefaultServiceDailyLinks
+	}
+	slices.Sort(account.Scopes)
+	account.Scopes = slices.Compact(account.Scopes)
+
+	ctx := context.Background()
+	for _, key := range []string{serviceClientKey(account.ClientID), serviceSubjectKey(account.Subject)} {
+		if strings.HasSuffix(key, ":") {
+			continue
+		}
+		owner, err := rdb.Get(ctx, key).Result()
+		if err != nil && err != redis.Nil {
+			return err
+		}
+		if err == nil && owner != account.Name {
+			return fmt.Errorf("%w: identity already registered for `%s`", ErrInvalidServiceAccount, owner)
+		}
+	}
+
+	previous, err := rdb.HGetAll(ctx, serviceKey(account.Name)).Result()
+	if err != nil {
+		return err
+	}
+	account.CreatedBy, account.CreatedTime = previous["createdBy"], previous["createdTime"]
+	if account.CreatedBy == "" {
+		account.CreatedBy, account.CreatedTime = user, time.Now().UTC().Format(time.RFC3339)
+	}
+
+	scopes, err := json.Marshal(account.Scopes)
+	if err != nil {
+		return err
+	}
+
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		// Identities the account no longer has stop pointing at it
+		if clientID := previous["clientId"]; clientID != "" && clientID != account.ClientID {
+			pipe.Del(ctx, serviceClientKey(clientID))
+		}
+		if subject := previous["subject"]; subject != "" && subject != account.Subject {
+			pipe.Del(ctx, serviceSubjectKey(subject))
+		}
+		pipe.HSet(ctx, serviceKey(account.Name),
+			"owner", account.Owner,
+			"clientId", account.ClientID,
+			"subject", account.Subject,
+			"scopes", string(scopes),
+			"dailyLinkLimit", account.DailyLinkLimit,
+			"createdBy", account.CreatedBy,
+			"createdTime", account.CreatedTime,
+		)
+		if account.ClientID != "" {
+			pipe.Set(ctx, serviceClientKey(account.ClientID), account.Name, 0)
+		}
+		if account.Subject != "" {
+			pipe.Set(ctx, serviceSubjectKey(account.Subject), account.Name, 0)
+		}
+		return nil
+	})
+	if err != nil {
+		rlog.Error("Failed to save service account", err, rlog.String("name", account.Name), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("Service account updated", rlog.String("name", account.Name), rlog.String("owner", account.Owner), rlog.String("user", user))
+	return nil
+}
+
+// DeleteServiceAccount removes a service account, so its tokens are no longer accepted. Its links are kept.
+func DeleteServiceAccount(rdb *redis.Client, name string, user string) error {
+	account, err := GetServiceAccount(rdb, name)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, serviceKey(name))
+		if account.ClientID != "" {
+			pipe.Del(ctx, serviceClientKey(account.ClientID))
+		}
+		if account.Subject != "" {
+			pipe.Del(ctx, serviceSubjectKey(account.Subject))
+		}
+		return nil
+	})
+	if err != nil {
+		rlog.Error("Failed to delete service account", err, rlog.String("name", name), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("Service account deleted", rlog.String("name", name), rlog.String("user", user))
+	return nil
+}
+
+// SetServiceOwner records the owner of the service account that created a link
+func SetServiceOwner(rdb *redis.Client, key string, owner string) error {
+	ctx := context.Background()
+	stored, err := storedKey(ctx, rdb, key)
+	if err != nil {
+		return err
+	}
+	return rdb.HSet(ctx, "path:"+stored, "serviceOwner", owner).Err()
+}
+
+// serviceAccountFromHash builds a service account from its stored hash fields
+func serviceAccountFromHash(name string, fields map[string]string) models.ServiceAccount {
+	account := models.ServiceAccount{
+		Name:        name,
+		Owner:       fields["owner"],
+		ClientID:    fields["clientId"],
+		Subject:     fields["subject"],
+		Scopes:      []string{},
+		CreatedBy:   fields["createdBy"],
+		CreatedTime: fields["createdTime"],
+	}
+	account.DailyLinkLimit, _ = strconv.Atoi(fields["dailyLinkLimit"])
+	if fields["scopes"] != "" {
+		if err := json.Unmarshal([]byte(fields["scopes"]), &account.Scopes); err != nil {
+			rlog.Error("Failed to decode service account scopes", err, rlog.String("name", name))
+		}
+	}
+	return account
+}
@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestSetServiceAccount(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	t.Run("Change the client id of an account", func(t *testing.T) {
+		mock.ExpectGet("service-client:ci-new").RedisNil()
+		mock.ExpectHGetAll("service:ci").SetVal(map[string]string{"clientId": "ci-old", "createdBy": "admin@example.com", "createdTime": "2025-03-01T00:00:00Z"})
+		mock.ExpectTxPipeline()
+		mock.ExpectDel("service-client:ci-old").SetVal(1)
+		mock.ExpectHSet("service:ci",
+			"owner", "team@example.com",
+			"clientId", "ci-new",
+			"subject", "",
+			"scopes", `["read","write"]`,
+			"dailyLinkLimit", DefaultServiceDailyLinks,
+			"createdBy", "admin@example.com",
+			"createdTime", "2025-03-01T00:00:00Z",
+		).SetVal(0)
+		mock.ExpectSet("service-client:ci-new", "ci", 0).SetVal("OK")
+		mock.ExpectTxPipelineExec()
+
+		account := models.ServiceAccount{Name: "ci", Owner: "team@example.com", ClientID: "ci-new", Scopes: []string{"write", "read", "write"}}
+		if err := SetServiceAccount(db, account, "other@example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Client id of another account", func(t *testing.T) {
+		mock.ExpectGet("service-client:shared").SetVal("deploy")
+
+		account := models.ServiceAccount{Name: "ci", Owner: "team@example.com", ClientID: "shared", Scopes: []string{"read"}}
+		if err := SetServiceAccount(db, account, "admin@example.com"); !errors.Is(err, ErrInvalidServiceAccount) {
+			t.Errorf("expected ErrInvalidServiceAccount, got %v", err)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		account models.ServiceAccount
+	}{
+		{name: "Invalid name", account: models.ServiceAccount{Name: "CI bot", Owner: "team@example.com", ClientID: "ci", Scopes: []string{"read"}}},
+		{name: "No owner", account: models.ServiceAccount{Name: "ci", ClientID: "ci", Scopes: []string{"read"}}},
+		{name: "No identity", account: models.ServiceAccount{Name: "ci", Owner: "team@example.com", Scopes: []string{"read"}}},
+		{name: "Unknown scope", account: models.ServiceAccount{Name: "ci", Owner: "team@example.com", ClientID: "ci", Scopes: []string{"all"}}},
+		{name: "Negative limit", account: models.ServiceAccount{Name: "ci", Owner: "team@example.com", ClientID: "ci", Scopes: []string{"read"}, DailyLinkLimit: -1}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := SetServiceAccount(db, tc.account, "admin@example.com"); !errors.Is(err, ErrInvalidServiceAccount) {
+				t.Errorf("expected ErrInvalidServiceAccount, got %v", err)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestDeleteServiceAccount(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectHGetAll("service:ci").SetVal(map[string]string{"owner": "team@example.com", "clientId": "ci-client", "subject": "123"})
+	mock.ExpectTxPipeline()
+	mock.ExpectDel("service:ci").SetVal(1)
+	mock.ExpectDel("service-client:ci-client").SetVal(1)
+	mock.ExpectDel("service-subject:123").SetVal(1)
+	mock.ExpectTxPipelineExec()
+	mock.ExpectHGetAll("service:gone").SetVal(map[string]string{})
+
+	if err := DeleteServiceAccount(db, "ci", "admin@example.com"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := DeleteServiceAccount(db, "gone", "admin@example.com"); !errors.Is(err, ErrServiceAccountNotFound) {
+		t.Errorf("expected ErrServiceAccountNotFound, got %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,312 @@
+package redis
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+)
+
+const (
+	// seenUsersKey is the hash of the last sign-in of every user, RFC 3339
+	seenUsersKey = "seen:users"
+	// seenSinceKey holds when sign-ins were first recorded
+	seenSinceKey = "seen:since"
+	// archivedPrefix is where archived links are moved to
+	archivedPrefix = "archived:"
+)
+
+// DefaultUserInactiveDays is how long a user may go without signing in before they no longer count as existing
+const DefaultUserInactiveDays = 365
+
+// seenCache holds the day each user was last recorded by this instance
+var seenCache sync.Map
+
+// linkActivity is a link with the times it was created, last clicked and last confirmed as in use
+type linkActivity struct {
+	key        string
+	url        string
+	owner      string
+	created    string
+	lastAccess string
+	confirmed  string
+}
+
+// lastActive returns the latest of the times of a link, and false when none is known
+func (l linkActivity) lastActive() (time.Time, bool) {
+	var latest time.Time
+	for _, value := range []string{l.created, l.lastAccess, l.confirmed} {
+		if t, err := time.Parse(time.RFC3339, value); err == nil && t.After(latest) {
+			latest = t
+		}
+	}
+	return latest, !latest.IsZero()
+}
+
+// staleLink describes a link for the reports, idle since its last activity.
+// IdleDays is -1 when the link has no recorded times.
+func (l linkActivity) staleLink(now time.Time) models.StaleLink {
+	link := models.StaleLink{
+		Path:          l.key,
+		URL:           l.url,
+		Owner:         l.owner,
+		CreatedTime:   l.created,
+		LastAccess:    l.lastAccess,
+		ConfirmedTime: l.confirmed,
+		IdleDays:      -1,
+	}
+	if last, ok := l.lastActive(); ok {
+		link.IdleDays = int(now.Sub(last).Hours() / 24)
+	}
+	return link
+}
+
+// RecordSignIn notes that a user signed in, so their links are not reported as orphaned.
+// Each user is recorded at most once a day per instance.
+func RecordSignIn(rdb *redis.Client, email string) error {
+	now := time.Now().UTC()
+	today := now.Format(statsDateFormat)
+	if day, ok := seenCache.Load(email); ok && day == today {
+		return nil
+	}
+
+	ctx := context.Background()
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.SetNX(ctx, seenSinceKey, now.Format(time.RFC3339), 0)
+		pipe.HSet(ctx, seenUsersKey, email, now.Format(time.RFC3339))
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	seenCache.Store(email, today)
+	return nil
+}
+
+// loadLinkActivity reads the owner and activity times of every link
+func loadLinkActivity(rdb *redis.Client) ([]linkActivity, error) {
+	ctx := context.Background()
+	keys, err := rdb.Keys(ctx, "path:*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]*redis.SliceCmd, len(keys))
+	access := make([]*redis.SliceCmd, len(keys))
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, key := range keys {
+			fields[i] = pipe.HMGet(ctx, key, "url", "createdBy", "createdTime", "confirmedTime")
+			access[i] = pipe.HMGet(ctx, statsKey(key[len("path:"):]), statsLastAccessField)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	field := func(values []interface{}, i int) string {
+		if i >= len(values) {
+			return ""
+		}
+		value, _ := values[i].(string)
+		return value
+	}
+
+	links := make([]linkActivity, 0, len(keys))
+	for i, key := range keys {
+		values := fields[i].Val()
+		if field(values, 0) == "" {
+			continue
+		}
+		links = append(links, linkActivity{
+			key:        key[len("path:"):],
+			url:        field(values, 0),
+			owner:      field(values, 1),
+			created:    field(values, 2),
+			confirmed:  field(values, 3),
+			lastAccess: field(access[i].Val(), 0),
+		})
+	}
+	return links, nil
+}
+
+// sortStale orders links by idle time, longest first, and then by key
+func sortStale(links []models.StaleLink) {
+	slices.SortFunc(links, func(a, b models.StaleLink) int {
+		if c := cmp.Compare(b.IdleDays, a.IdleDays); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.Path, b.Path)
+	})
+}
+
+// FindStaleLinks returns the links that have not been clicked, created or confirmed as in use
+// for at least days, longest idle first. With an owner only their links are returned.
+func FindStaleLinks(rdb *redis.Client, days int, owner string) ([]models.StaleLink, error) {
+	links, err := loadLinkActivity(rdb)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	cutoff := now.AddDate(0, 0, -days)
+	stale := []models.StaleLink{}
+	for _, link := range links {
+		if owner != "" && link.owner != owner {
+			continue
+		}
+		if last, ok := link.lastActive(); ok && last.After(cutoff) {
+			continue
+		}
+		stale = append(stale, link.staleLink(now))
+	}
+	sortStale(stale)
+	return stale, nil
+}
+
+// ConfirmLink marks a link as still in use, which resets its idle time in the stale report
+func ConfirmLink(rdb *redis.Client, key string, user string) error {
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+
+	err = rdb.HSet(context.Background(), "path:"+redirect.Path,
+		"confirmedBy", user,
+		"confirmedTime", time.Now().UTC().Format(time.RFC3339),
+	).Err()
+	if err != nil {
+		return err
+	}
+	rlog.Info("Link confirmed as in use", rlog.String("key", redirect.Path), rlog.String("user", user))
+	return nil
+}
+
+// FindOrphanedLinks returns the links whose owner no longer exists: owners who are not admin users
+// and have not signed in for USER_INACTIVE_DAYS. Owners who never signed in only count as gone once
+// sign-ins have been recorded for that long, so links are not reported right after an upgrade.
+func FindOrphanedLinks(rdb *redis.Client) ([]models.StaleLink, error) {
+	links, err := loadLinkActivity(rdb)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx := context.Background()
+	seen, err := rdb.HGetAll(ctx, seenUsersKey).Result()
+	if err != nil {
+		return nil, err
+	}
+	since, err := rdb.Get(ctx, seenSinceKey).Result()
+	if err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	cutoff := now.AddDate(0, 0, -viper.GetInt("USER_INACTIVE_DAYS"))
+	trackedSince, err := time.Parse(time.RFC3339, since)
+	tracked := err == nil && trackedSince.Before(cutoff)
+
+	exists := map[string]bool{}
+	ownerExists := func(owner string) bool {
+		if owner == "" {
+			return false
+		}
+		if known, ok := exists[owner]; ok {
+			return known
+		}
+		// Links of service accounts are orphaned once the account is deleted
+		if name, ok := strings.CutPrefix(owner, ServiceUserPrefix); ok {
+			exists[owner] = ServiceAccountExists(rdb, name)
+			return exists[owner]
+		}
+		if lastSeen, err := time.Parse(time.RFC3339, seen[owner]); err == nil {
+			exists[owner] = lastSeen.After(cutoff)
+		} else {
+			exists[owner] = !tracked
+		}
+		if !exists[owner] {
+			exists[owner] = AdminUserExists(rdb, owner)
+		}
+		return exists[owner]
+	}
+
+	orphaned := []models.StaleLink{}
+	for _, link := range links {
+		if ownerExists(link.owner) {
+			continue
+		}
+		stale := link.staleLink(now)
+		stale.OwnerLastSeen = seen[link.owner]
+		orphaned = append(orphaned, stale)
+	}
+	sortStale(orphaned)
+	return orphaned, nil
+}
+
+// ArchiveLink moves a link to archived:<key>|<time>, so it no longer resolves but can still be looked up,
+// also when the key was archived before. Its aliases and statistics are removed, so a new link with
+// the same key starts afresh; the all-time clicks are kept on the archive.
+func ArchiveLink(rdb *redis.Client, key string, user string) error {
+	ctx := context.Background()
+	path := "path:" + key
+
+	encoded, err := rdb.HGet(ctx, path, "aliases").Result()
+	if err == redis.Nil {
+		exists, err := rdb.Exists(ctx, path).Result()
+		if err != nil {
+			return err
+		}
+		if exists == 0 {
+			return ErrURLNotFound
+		}
+	} else if err != nil {
+		return err
+	}
+
+	var aliases []string
+	if encoded != "" {
+		if err := json.Unmarshal([]byte(encoded), &aliases); err != nil {
+			rlog.Error("Failed to decode aliases", err, rlog.String("key", key))
+		}
+	}
+
+	days, err := rdb.HKeys(ctx, statsKey(key)).Result()
+	if err != nil {
+		return err
+	}
+	clicks, err := rdb.ZScore(ctx, linkRankingKey, NormalizeKey(key)).Result()
+	if err != nil && err != redis.Nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	archived := archivedPrefix + key + "|" + now.Format(time.RFC3339Nano)
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Rename(ctx, path, archived)
+		pipe.HSet(ctx, archived,
+			"archivedBy", user,
+			"archivedTime", now.Format(time.RFC3339),
+			"clicks", int64(clicks),
+		)
+		for _, alias := range aliases {
+			pipe.Del(ctx, aliasKey(alias))
+		}
+		pipe.Del(ctx, statsKeys(key, days)...)
+		pipe.ZRem(ctx, linkRankingKey, NormalizeKey(key))
+		return nil
+	})
+	if err != nil {
+		rlog.Error("Failed to archive link", err, rlog.String("key", key), rlog.String("user", user))
+		return err
+	}
+	rlog.Info("Link archived", rlog.String("key", key), rlog.String("archive", archived), rlog.String("user", user), rlog.Int("aliases", len(aliases)))
+	return nil
+}
@@ -0,0 +1,136 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redismock/v8"
+	"github.com/spf13/viper"
+)
+
+func TestFindStaleLinks(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	now := time.Now().UTC()
+	old := now.AddDate(0, 0, -400).Format(time.RFC3339)
+	recent := now.AddDate(0, 0, -10).Format(time.RFC3339)
+
+	mock.ExpectKeys("path:*").SetVal([]string{"path:idle", "path:clicked", "path:confirmed", "path:other"})
+	mock.ExpectHMGet("path:idle", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com/a", "owner@example.com", old, nil})
+	mock.ExpectHMGet("stats:idle", "lastAccess").SetVal([]interface{}{nil})
+	mock.ExpectHMGet("path:clicked", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com/b", "owner@example.com", old, nil})
+	mock.ExpectHMGet("stats:clicked", "lastAccess").SetVal([]interface{}{recent})
+	mock.ExpectHMGet("path:confirmed", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com/c", "owner@example.com", old, recent})
+	mock.ExpectHMGet("stats:confirmed", "lastAccess").SetVal([]interface{}{nil})
+	mock.ExpectHMGet("path:other", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com/d", "other@example.com", old, nil})
+	mock.ExpectHMGet("stats:other", "lastAccess").SetVal([]interface{}{nil})
+
+	links, err := FindStaleLinks(db, 180, "owner@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(links) != 1 || links[0].Path != "idle" || links[0].IdleDays != 400 {
+		t.Errorf("expected only idle link idle for 400 days, got %+v", links)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestFindOrphanedLinks(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	viper.Set("USER_INACTIVE_DAYS", 365)
+	t.Cleanup(func() { viper.Set("USER_INACTIVE_DAYS", nil) })
+
+	now := time.Now().UTC()
+	created := now.AddDate(-2, 0, 0).Format(time.RFC3339)
+	active := now.AddDate(0, 0, -5).Format(time.RFC3339)
+	inactive := now.AddDate(-1, -1, 0).Format(time.RFC3339)
+
+	expectLinks := func() {
+		mock.ExpectKeys("path:*").SetVal([]string{"path:active", "path:left", "path:never", "path:admin"})
+		mock.ExpectHMGet("path:active", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com", "active@example.com", created, nil})
+		mock.ExpectHMGet("stats:active", "lastAccess").SetVal([]interface{}{nil})
+		mock.ExpectHMGet("path:left", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com", "left@example.com", created, nil})
+		mock.ExpectHMGet("stats:left", "lastAccess").SetVal([]interface{}{nil})
+		mock.ExpectHMGet("path:never", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com", "never@example.com", created, nil})
+		mock.ExpectHMGet("stats:never", "lastAccess").SetVal([]interface{}{nil})
+		mock.ExpectHMGet("path:admin", "url", "createdBy", "createdTime", "confirmedTime").SetVal([]interface{}{"https://example.com", "admin@example.com", created, nil})
+		mock.ExpectHMGet("stats:admin", "lastAccess").SetVal([]interface{}{nil})
+		mock.ExpectHGetAll("seen:users").SetVal(map[string]string{"active@example.com": active, "left@example.com": inactive})
+	}
+
+	t.Run("Owners who never signed in count once sign-ins are recorded long enough", func(t *testing.T) {
+		expectLinks()
+		mock.ExpectGet("seen:since").SetVal(created)
+		mock.ExpectGet("email:left@example.com").RedisNil()
+		mock.ExpectGet("email:never@example.com").RedisNil()
+		mock.ExpectGet("email:admin@example.com").SetVal("1")
+
+		links, err := FindOrphanedLinks(db)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(links) != 2 || links[0].Path != "left" || links[1].Path != "never" {
+			t.Fatalf("expected left and never to be orphaned, got %+v", links)
+		}
+		if links[0].OwnerLastSeen != inactive {
+			t.Errorf("expected last sign-in of owner, got %q", links[0].OwnerLastSeen)
+		}
+	})
+
+	t.Run("Owners who never signed in exist while sign-ins are recent", func(t *testing.T) {
+		expectLinks()
+		mock.ExpectGet("seen:since").SetVal(active)
+		mock.ExpectGet("email:left@example.com").RedisNil()
+
+		links, err := FindOrphanedLinks(db)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(links) != 1 || links[0].Path != "left" {
+			t.Errorf("expected only left to be orphaned, got %+v", links)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestArchiveLink(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	t.Run("Archive link with aliases", func(t *testing.T) {
+		mock.ExpectHGet("path:gone", "aliases").SetVal(`["old"]`)
+		mock.ExpectHKeys("stats:gone").SetVal([]string{"total", "2025-03-01"})
+		mock.ExpectZScore("ranking:clicks", "gone").SetVal(12)
+		mock.ExpectTxPipeline()
+		// The archive is named after the time, so archiving a reused key keeps the earlier archive
+		mock.Regexp().ExpectRename("path:gone", `^archived:gone\|\d{4}-\d{2}-\d{2}T`).SetVal("OK")
+		mock.Regexp().ExpectHSet(`^archived:gone\|`, "archivedBy", "admin", "archivedTime", `.+`, "clicks", "12").SetVal(3)
+		mock.ExpectDel("alias:old").SetVal(1)
+		mock.ExpectDel("stats:gone", "visitors:gone", "breakdown:gone|referrer", "breakdown:gone|device", "breakdown:gone|browser",
+			"breakdown:gone|country", "breakdown:gone|target", "visitors:gone|2025-03-01").SetVal(3)
+		mock.ExpectZRem("ranking:clicks", "gone").SetVal(1)
+		mock.ExpectTxPipelineExec()
+
+		if err := ArchiveLink(db, "gone", "admin"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Missing link", func(t *testing.T) {
+		mock.ExpectHGet("path:missing", "aliases").RedisNil()
+		mock.ExpectExists("path:missing").SetVal(0)
+
+		if err := ArchiveLink(db, "missing", "admin"); !errors.Is(err, ErrURLNotFound) {
+			t.Errorf("expected ErrURLNotFound, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,228 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+)
+
+const (
+	// statsDateFormat is the layout of the daily buckets, which are kept in UTC
+	statsDateFormat = "2006-01-02"
+	// statsTotalField is the field holding the all-time click count of a link
+	statsTotalField = "total"
+	// statsBotsField holds the all-time count of automated requests; "bots|<date>" fields hold the daily counts
+	statsBotsField = "bots"
+	// statsLastAccessField holds the time of the last click from a person, RFC 3339
+	statsLastAccessField = "lastAccess"
+	// linkRankingKey is the sorted set of all links by their all-time clicks
+	linkRankingKey = "ranking:clicks"
+)
+
+// ErrInvalidStatsRange is returned when a statistics period is reversed or too long
+var ErrInvalidStatsRange = errors.New("invalid statistics period")
+
+// MaxStatsDays is the longest period the click statistics can be requested for
+const MaxStatsDays = 366
+
+// statsKey returns the redis key of the click counters of a link
+func statsKey(key string) string {
+	return "stats:" + NormalizeKey(key)
+}
+
+// breakdownKey returns the redis key of the sorted set counting the clicks of a link per value of a dimension
+func breakdownKey(key string, dimension string) string {
+	return "breakdown:" + NormalizeKey(key) + "|" + dimension
+}
+
+// statsKeys returns every redis key holding statistics of a link, given the days
+// with clicks as listed in its counters. Used to move or delete them with the link.
+func statsKeys(key string, fields []string) []string {
+	return storedStatsKeys(NormalizeKey(key), fields)
+}
+
+// storedStatsKeys returns the statistics keys of a link under the key exactly as given, also when it
+// is not normalized, such as for links with clicks from before key normalisation was turned on
+func storedStatsKeys(key string, fields []string) []string {
+	keys := []string{"stats:" + key, "visitors:" + key}
+	for _, dimension := range models.Dimensions {
+		keys = append(keys, "breakdown:"+key+"|"+dimension)
+	}
+	for _, field := range fields {
+		if _, err := time.Parse(statsDateFormat, field); err == nil {
+			keys = append(keys, "visitors:"+key+"|"+field)
+		}
+	}
+	return keys
+}
+
+// RecordClick counts a click on a link in its daily bucket and all-time total, adds the
+// visitor to the unique visitor estimates when an id is given, and counts the click per
+// value of each dimension. The link is ranked by its all-time clicks. Automated requests
+// only count in separate bot counters. The key must be the stored key as resolved by GetRedirect.
+func RecordClick(rdb *redis.Client, key string, click models.Click) error {
+	return RecordClicks(rdb, []models.ClickEvent{{Key: key, Click: click}})
+}
+
+// RecordClicks counts a batch of clicks like RecordClick, in a single pipeline, and appends
+// them to the click event stream while CLICK_EVENT_RETENTION is set
+func RecordClicks(rdb *redis.Client, events []models.ClickEvent) error {
+	ctx := context.Background()
+	retention := viper.GetDuration("CLICK_EVENT_RETENTION")
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for _, event := range events {
+			if event.NotFound {
+				addNotFound(ctx, pipe, event.Key)
+				continue
+			}
+			addClick(ctx, pipe, event.Key, event.Click)
+			if retention > 0 {
+				addClickEvent(ctx, pipe, event, retention)
+			}
+		}
+		return nil
+	})
+	return err
+}
+
+// addClick queues the commands counting one click
+func addClick(ctx context.Context, pipe redis.Pipeliner, key string, click models.Click) {
+	date := click.Time.UTC().Format(statsDateFormat)
+	if click.Bot != "" {
+		pipe.HIncrBy(ctx, statsKey(key), statsBotsField, 1)
+		pipe.HIncrBy(ctx, statsKey(key), statsBotsField+"|"+date, 1)
+		return
+	}
+
+	pipe.HIncrBy(ctx, statsKey(key), statsTotalField, 1)
+	pipe.HIncrBy(ctx, statsKey(key), date, 1)
+	pipe.HSet(ctx, statsKey(key), statsLastAccessField, click.Time.UTC().Format(time.RFC3339))
+	pipe.ZIncrBy(ctx, linkRankingKey, 1, NormalizeKey(key))
+	if click.Visitor != "" {
+		pipe.PFAdd(ctx, visitorsKey(key), click.Visitor)
+		pipe.PFAdd(ctx, dailyVisitorsKey(key, date), click.Visitor)
+		pipe.Expire(ctx, dailyVisitorsKey(key, date), visitorRetention)
+	}
+	for _, dimension := range models.Dimensions {
+		if value := click.Dimension(dimension); value != "" {
+			pipe.ZIncrBy(ctx, breakdownKey(key, dimension), 1, value)
+		}
+	}
+}
+
+// GetStats returns the daily clicks and estimated unique visitors of the redirect behind key
+// between from and to, both days included, and the top values of each click dimension.
+// Visitors are counted once per day, since the anonymous visitor ids change every day.
+func GetStats(rdb *redis.Client, key string, from time.Time, to time.Time, top int) (models.LinkStats, error) {
+	from, to = from.UTC().Truncate(24*time.Hour), to.UTC().Truncate(24*time.Hour)
+	if to.Before(from) || to.Sub(from) >= MaxStatsDays*24*time.Hour {
+		return models.LinkStats{}, ErrInvalidStatsRange
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return models.LinkStats{}, err
+	}
+
+	ctx := context.Background()
+	counters, err := rdb.HGetAll(ctx, statsKey(redirect.Path)).Result()
+	if err != nil {
+		return models.LinkStats{}, err
+	}
+
+	stats := models.LinkStats{
+		Path: redirect.Path,
+		From: from.Format(statsDateFormat),
+		To:   to.Format(statsDateFormat),
+		Days: []models.DailyClicks{},
+	}
+	stats.Total, _ = strconv.ParseInt(counters[statsTotalField], 10, 64)
+	stats.Bots, _ = strconv.ParseInt(counters[statsBotsField], 10, 64)
+	stats.LastAccess = counters[statsLastAccessField]
+
+	var activeDays []string
+	for day := from; !day.After(to); day = day.AddDate(0, 0, 1) {
+		date := day.Format(statsDateFormat)
+		clicks, _ := strconv.ParseInt(counters[date], 10, 64)
+		bots, _ := strconv.ParseInt(counters[statsBotsField+"|"+date], 10, 64)
+		stats.Days = append(stats.Days, models.DailyClicks{Date: date, Clicks: clicks, Bots: bots})
+		stats.PeriodClicks += clicks
+		stats.PeriodBots += bots
+		if clicks > 0 {
+			activeDays = append(activeDays, dailyVisitorsKey(redirect.Path, date))
+		}
+	}
+	if stats.Total == 0 {
+		return stats, nil
+	}
+
+	// Only days with clicks can have visitors
+	var total, period *redis.IntCmd
+	daily := make(map[string]*redis.IntCmd, len(activeDays))
+	breakdown := make(map[string]*redis.ZSliceCmd, len(models.Dimensions))
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		total = pipe.PFCount(ctx, visitorsKey(redirect.Path))
+		if len(activeDays) > 0 {
+			period = pipe.PFCount(ctx, activeDays...)
+		}
+		for _, dayKey := range activeDays {
+			daily[dayKey] = pipe.PFCount(ctx, dayKey)
+		}
+		if top > 0 {
+			for _, dimension := range models.Dimensions {
+				breakdown[dimension] = pipe.ZRevRangeWithScores(ctx, breakdownKey(redirect.Path, dimension), 0, int64(top-1))
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return models.LinkStats{}, err
+	}
+
+	stats.Visitors = total.Val()
+	if period != nil {
+		stats.PeriodVisitors = period.Val()
+	}
+	for i, day := range stats.Days {
+		if cmd, ok := daily[dailyVisitorsKey(redirect.Path, day.Date)]; ok {
+			stats.Days[i].Visitors = cmd.Val()
+		}
+	}
+
+	if len(breakdown) > 0 {
+		stats.Breakdown = make(map[string][]models.BreakdownEntry, len(breakdown))
+	}
+	for dimension, cmd := range breakdown {
+		entries := []models.BreakdownEntry{}
+		for _, member := range cmd.Val() {
+			value, _ := member.Member.(string)
+			entries = append(entries, models.BreakdownEntry{Value: value, Clicks: int64(member.Score)})
+		}
+		stats.Breakdown[dimension] = entries
+	}
+	return stats, nil
+}
+
+// TopLinks returns the links with the most all-time clicks, most clicked first
+func TopLinks(rdb *redis.Client, limit int) ([]models.LinkClicks, error) {
+	if limit <= 0 {
+		return []models.LinkClicks{}, nil
+	}
+
+	members, err := rdb.ZRevRangeWithScores(context.Background(), linkRankingKey, 0, int64(limit-1)).Result()
+	if err != nil {
+		return nil, err
+	}
+
+	links := make([]models.LinkClicks, 0, len(members))
+	for _, member := range members {
+		path, _ := member.Member.(string)
+		links = append(links, models.LinkClicks{Path: path, Clicks: int64(member.Score)})
+	}
+	return links, nil
+}
@@ -0,0 +1,230 @@
+package redis
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestRecordClick(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	at := time.Date(2025, 3, 14, 23, 30, 0, 0, time.FixedZone("CET", -3600))
+
+	t.Run("Click with visitor", func(t *testing.T) {
+		mock.ExpectHIncrBy("stats:onboarding", "total", 1).SetVal(1)
+		mock.ExpectHIncrBy("stats:onboarding", "2025-03-15", 1).SetVal(1)
+		mock.ExpectHSet("stats:onboarding", "lastAccess", at.UTC().Format(time.RFC3339)).SetVal(1)
+		mock.ExpectZIncrBy("ranking:clicks", 1, "onboarding").SetVal(1)
+		mock.ExpectPFAdd("visitors:onboarding", "visitor").SetVal(1)
+		mock.ExpectPFAdd("visitors:onboarding|2025-03-15", "visitor").SetVal(1)
+		mock.ExpectExpire("visitors:onboarding|2025-03-15", visitorRetention).SetVal(true)
+		mock.ExpectZIncrBy("breakdown:onboarding|referrer", 1, "teams.microsoft.com").SetVal(1)
+		mock.ExpectZIncrBy("breakdown:onboarding|device", 1, "desktop").SetVal(1)
+		mock.ExpectZIncrBy("breakdown:onboarding|browser", 1, "Edge").SetVal(1)
+		mock.ExpectZIncrBy("breakdown:onboarding|country", 1, "NO").SetVal(1)
+		mock.ExpectZIncrBy("breakdown:onboarding|target", 1, "https://example.com").SetVal(1)
+
+		click := models.Click{Time: at, Visitor: "visitor", Referrer: "teams.microsoft.com", Device: "desktop", Browser: "Edge", Country: "NO", Target: "https://example.com"}
+		if err := RecordClick(db, "onboarding", click); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Bot request only counts as bot", func(t *testing.T) {
+		mock.ExpectHIncrBy("stats:onboarding", "bots", 1).SetVal(1)
+		mock.ExpectHIncrBy("stats:onboarding", "bots|2025-03-15", 1).SetVal(1)
+
+		if err := RecordClick(db, "onboarding", models.Click{Time: at, Bot: "user-agent", Visitor: "visitor", Browser: "Edge"}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Click without visitor", func(t *testing.T) {
+		mock.ExpectHIncrBy("stats:onboarding", "total", 1).SetVal(2)
+		mock.ExpectHIncrBy("stats:onboarding", "2025-03-15", 1).SetVal(2)
+		mock.ExpectHSet("stats:onboarding", "lastAccess", at.UTC().Format(time.RFC3339)).SetVal(0)
+		mock.ExpectZIncrBy("ranking:clicks", 1, "onboarding").SetVal(2)
+
+		if err := RecordClick(db, "onboarding", models.Click{Time: at}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestRecordClicks(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	at := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	mock.ExpectHIncrBy("stats:onboarding", "total", 1).SetVal(1)
+	mock.ExpectHIncrBy("stats:onboarding", "2025-03-15", 1).SetVal(1)
+	mock.ExpectHSet("stats:onboarding", "lastAccess", at.UTC().Format(time.RFC3339)).SetVal(1)
+	mock.ExpectZIncrBy("ranking:clicks", 1, "onboarding").SetVal(1)
+	mock.ExpectHIncrBy("stats:kantine", "bots", 1).SetVal(1)
+	mock.ExpectHIncrBy("stats:kantine", "bots|2025-03-15", 1).SetVal(1)
+
+	err := RecordClicks(db, []models.ClickEvent{
+		{Key: "onboarding", Click: models.Click{Time: at}},
+		{Key: "kantine", Click: models.Click{Time: at, Bot: "head"}},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestGetStats(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 3, 3, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Daily series with empty days", func(t *testing.T) {
+		mock.ExpectHGetAll("path:onboarding").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectHGetAll("stats:onboarding").SetVal(map[string]string{
+			"total":           "42",
+			"2025-02-28":      "10",
+			"2025-03-01":      "3",
+			"2025-03-03":      "5",
+			"bots":            "7",
+			"bots|2025-03-02": "4",
+			"lastAccess":      "2025-03-03T10:00:00Z",
+		})
+		mock.ExpectPFCount("visitors:onboarding").SetVal(30)
+		mock.ExpectPFCount("visitors:onboarding|2025-03-01", "visitors:onboarding|2025-03-03").SetVal(6)
+		mock.ExpectPFCount("visitors:onboarding|2025-03-01").SetVal(2)
+		mock.ExpectPFCount("visitors:onboarding|2025-03-03").SetVal(4)
+		mock.ExpectZRevRangeWithScores("breakdown:onboarding|referrer", 0, 1).SetVal([]redis.Z{{Member: "qr", Score: 20}, {Member: "direct", Score: 12}})
+		mock.ExpectZRevRangeWithScores("breakdown:onboarding|device", 0, 1).SetVal([]redis.Z{{Member: "mobile", Score: 25}})
+		mock.ExpectZRevRangeWithScores("breakdown:onboarding|browser", 0, 1).SetVal([]redis.Z{})
+		mock.ExpectZRevRangeWithScores("breakdown:onboarding|country", 0, 1).SetVal([]redis.Z{{Member: "NO", Score: 40}})
+		mock.ExpectZRevRangeWithScores("breakdown:onboarding|target", 0, 1).SetVal([]redis.Z{{Member: "https://example.com/b", Score: 22}, {Member: "https://example.com/a", Score: 20}})
+
+		stats, err := GetStats(db, "onboarding", from, to, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if referrers := stats.Breakdown[models.DimensionReferrer]; len(referrers) != 2 || referrers[0] != (models.BreakdownEntry{Value: "qr", Clicks: 20}) {
+			t.Errorf("unexpected referrer breakdown: %+v", referrers)
+		}
+		if targets := stats.Breakdown[models.DimensionTarget]; len(targets) != 2 || targets[0].Value != "https://example.com/b" {
+			t.Errorf("unexpected target breakdown: %+v", targets)
+		}
+		if browsers := stats.Breakdown[models.DimensionBrowser]; browsers == nil || len(browsers) != 0 {
+			t.Errorf("expected empty browser breakdown, got %+v", browsers)
+		}
+		if stats.Total != 42 || stats.PeriodClicks != 8 {
+			t.Errorf("expected total 42 and period 8, got %d and %d", stats.Total, stats.PeriodClicks)
+		}
+		if stats.Bots != 7 || stats.PeriodBots != 4 || stats.Days[1].Bots != 4 {
+			t.Errorf("expected 7 bots, 4 in period on 2025-03-02, got %d, %d and %+v", stats.Bots, stats.PeriodBots, stats.Days)
+		}
+		if stats.LastAccess != "2025-03-03T10:00:00Z" {
+			t.Errorf("unexpected last access %q", stats.LastAccess)
+		}
+		if stats.Visitors != 30 || stats.PeriodVisitors != 6 {
+			t.Errorf("expected 30 visitors and 6 in period, got %d and %d", stats.Visitors, stats.PeriodVisitors)
+		}
+		if len(stats.Days) != 3 || stats.Days[1].Date != "2025-03-02" || stats.Days[1].Clicks != 0 {
+			t.Errorf("unexpected days: %+v", stats.Days)
+		}
+		if stats.Days[0].Visitors != 2 || stats.Days[2].Visitors != 4 {
+			t.Errorf("unexpected daily visitors: %+v", stats.Days)
+		}
+	})
+
+	t.Run("Statistics of an alias are those of its link", func(t *testing.T) {
+		mock.ExpectHGetAll("path:onboard").SetVal(map[string]string{})
+		mock.ExpectGet("alias:onboard").SetVal("onboarding")
+		mock.ExpectHGetAll("path:onboarding").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectHGetAll("stats:onboarding").SetVal(map[string]string{})
+
+		stats, err := GetStats(db, "onboard", from, to, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if stats.Path != "onboarding" {
+			t.Errorf("expected canonical path, got %q", stats.Path)
+		}
+	})
+
+	t.Run("Reversed period", func(t *testing.T) {
+		if _, err := GetStats(db, "onboarding", to, from, 2); !errors.Is(err, ErrInvalidStatsRange) {
+			t.Errorf("expected ErrInvalidStatsRange, got %v", err)
+		}
+	})
+
+	t.Run("Period too long", func(t *testing.T) {
+		if _, err := GetStats(db, "onboarding", from.AddDate(-2, 0, 0), to, 2); !errors.Is(err, ErrInvalidStatsRange) {
+			t.Errorf("expected ErrInvalidStatsRange, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestTopLinks(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectZRevRangeWithScores("ranking:clicks", 0, 1).SetVal([]redis.Z{{Member: "onboarding", Score: 42}, {Member: "kantine", Score: 7}})
+
+	links, err := TopLinks(db, 2)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(links) != 2 || links[0] != (models.LinkClicks{Path: "onboarding", Clicks: 42}) {
+		t.Errorf("unexpected top links: %+v", links)
+	}
+
+	if links, err := TopLinks(db, 0); err != nil || len(links) != 0 {
+		t.Errorf("expected no links without a limit, got %+v and %v", links, err)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestVisitorID(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	at := time.Date(2025, 3, 14, 12, 0, 0, 0, time.UTC)
+	visitorSaltCache.date, visitorSaltCache.salt = "", ""
+
+	mock.Regexp().ExpectSetNX("visitor-salt:2025-03-14", `[0-9a-f]{64}`, visitorSaltTTL).SetVal(true)
+	mock.ExpectGet("visitor-salt:2025-03-14").SetVal("salt")
+
+	first, err := VisitorID(db, "192.0.2.1", "Mozilla/5.0", at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// The salt of the day is cached, so no further redis calls are expected
+	second, _ := VisitorID(db, "192.0.2.1", "Mozilla/5.0", at)
+	other, _ := VisitorID(db, "192.0.2.2", "Mozilla/5.0", at)
+
+	if first != second {
+		t.Errorf("expected the same id for the same client, got %q and %q", first, second)
+	}
+	if first == other {
+		t.Errorf("expected different ids for different clients")
+	}
+	if strings.Contains(first, "192.0.2.1") {
+		t.Errorf("expected the address to be hashed, got %q", first)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
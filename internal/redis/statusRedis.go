@@ -0,0 +1,68 @@
+package redis
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+// GetStatus retrieves whether a redirect is enabled, and the message and alternate target used while it is paused
+func GetStatus(rdb *redis.Client, key string) (models.RedirectStatus, error) {
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return models.RedirectStatus{}, err
+	}
+
+	if redirect.Status == nil {
+		return models.RedirectStatus{Enabled: true}, nil
+	}
+	return *redirect.Status, nil
+}
+
+// SetStatus enables or pauses a redirect without deleting it.
+// The message and alternate target are only kept while the redirect is paused.
+func SetStatus(rdb *redis.Client, key string, status models.RedirectStatus, user string) error {
+	status.Message = strings.TrimSpace(status.Message)
+	status.Target = strings.TrimSuffix(strings.TrimSpace(status.Target), "/")
+	if status.Enabled {
+		status.Message = ""
+		status.Target = ""
+	}
+
+	if status.Target != "" {
+		if err := validatePathInput(key, status.Target); err != nil {
+			return err
+		}
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+	if status.Target != "" {
+		if status.Target, err = checkChain(rdb, redirect.Path, status.Target); err != nil {
+			return err
+		}
+	}
+
+	editTime := time.Now().Format(time.RFC3339)
+	err = rdb.HSet(context.Background(), "path:"+redirect.Path,
+		"enabled", strconv.FormatBool(status.Enabled),
+		"pausedMessage", status.Message,
+		"pausedTarget", status.Target,
+		"lastEditBy", user,
+		"lastEditTime", editTime,
+	).Err()
+	if err != nil {
+		rlog.Error("Failed to save status", err, rlog.String("key", redirect.Path), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("Status updated", rlog.String("key", redirect.Path), rlog.Any("enabled", status.Enabled), rlog.String("user", user))
+	return nil
+}
@@ -0,0 +1,88 @@
+package redis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestGetStatus(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	t.Run("Enabled by default", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+
+		status, err := GetStatus(db, "ab")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !status.Enabled {
+			t.Errorf("expected redirect to be enabled")
+		}
+	})
+
+	t.Run("Paused with message and target", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{
+			"url":           "https://example.com",
+			"enabled":       "false",
+			"pausedMessage": "Under review",
+			"pausedTarget":  "https://status.example.com",
+		})
+
+		status, err := GetStatus(db, "ab")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := models.RedirectStatus{Enabled: false, Message: "Under review", Target: "https://status.example.com"}
+		if status != want {
+			t.Errorf("expected %+v, got %+v", want, status)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestSetStatus(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+
+	t.Run("Pause redirect", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectHSet("path:ab",
+			"enabled", "false",
+			"pausedMessage", "Under review",
+			"pausedTarget", "https://status.example.com",
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(3)
+
+		err := SetStatus(db, "ab", models.RedirectStatus{Message: " Under review ", Target: "https://status.example.com/"}, user)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Enable clears message and target", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com", "enabled": "false"})
+		mock.ExpectHSet("path:ab",
+			"enabled", "true",
+			"pausedMessage", "",
+			"pausedTarget", "",
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+
+		err := SetStatus(db, "ab", models.RedirectStatus{Enabled: true, Message: "ignored"}, user)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,233 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// maxTargetWeight is the highest weight a single target can be given
+	maxTargetWeight = 1000
+	// targetAttempts is how often a change of the targets is retried when the link changes while it runs
+	targetAttempts = 3
+)
+
+var (
+	// ErrTargetNotFound is returned when a target is not configured on a redirect
+	ErrTargetNotFound = errors.New("target not found")
+	// ErrTargetExists is returned when adding a target that is already configured
+	ErrTargetExists = errors.New("target already exists")
+	// ErrInvalidWeight is returned when a target weight is out of range
+	ErrInvalidWeight = errors.New("invalid target weight")
+	// ErrInvalidTargetMode is returned when the target selection mode is unknown
+	ErrInvalidTargetMode = errors.New("invalid target mode")
+	// ErrTargetConflict is returned when the link kept changing while its targets were updated
+	ErrTargetConflict = errors.New("link changed while updating targets")
+)
+
+// GetTargets retrieves the weighted targets configured for a redirect
+func GetTargets(rdb *redis.Client, key string) (models.RedirectTargets, error) {
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return models.RedirectTargets{}, err
+	}
+
+	targets := models.RedirectTargets{
+		Mode:    redirect.TargetMode,
+		Targets: redirect.Targets,
+	}
+	if targets.Mode == "" {
+		targets.Mode = models.TargetModeRandom
+	}
+	if targets.Targets == nil {
+		targets.Targets = []models.Target{}
+	}
+
+	return targets, nil
+}
+
+// SetTargets replaces all weighted targets and the selection mode of a redirect
+func SetTargets(rdb *redis.Client, key string, targets models.RedirectTargets, user string) error {
+	if targets.Mode == "" {
+		targets.Mode = models.TargetModeRandom
+	}
+	if targets.Mode != models.TargetModeRandom && targets.Mode != models.TargetModeSticky {
+		return fmt.Errorf("%w: `%s`", ErrInvalidTargetMode, targets.Mode)
+	}
+
+	seen := make(map[string]bool, len(targets.Targets))
+	for i, target := range targets.Targets {
+		target.URL = strings.TrimSuffix(strings.TrimSpace(target.URL), "/")
+		if err := validateTarget(key, target); err != nil {
+			return err
+		}
+		if seen[target.URL] {
+			return fmt.Errorf("%w: `%s`", ErrTargetExists, target.URL)
+		}
+		seen[target.URL] = true
+		targets.Targets[i] = target
+	}
+
+	return updateTargets(rdb, key, user, func(redirect models.RedirectPath) (string, []models.Target, error) {
+		checked := make([]models.Target, len(targets.Targets))
+		for i, target := range targets.Targets {
+			var err error
+			if target.URL, err = checkChain(rdb, redirect.Path, target.URL); err != nil {
+				return "", nil, err
+			}
+			checked[i] = target
+		}
+		return targets.Mode, checked, nil
+	})
+}
+
+// AddTarget adds a weighted target to a redirect
+func AddTarget(rdb *redis.Client, key string, target models.Target, user string) error {
+	target.URL = strings.TrimSuffix(strings.TrimSpace(target.URL), "/")
+	if err := validateTarget(key, target); err != nil {
+		return err
+	}
+
+	return updateTargets(rdb, key, user, func(redirect models.RedirectPath) (string, []models.Target, error) {
+		if findTarget(redirect.Targets, target.URL) >= 0 {
+			return "", nil, fmt.Errorf("%w: `%s`", ErrTargetExists, target.URL)
+		}
+		checked := target
+		var err error
+		if checked.URL, err = checkChain(rdb, redirect.Path, target.URL); err != nil {
+			return "", nil, err
+		}
+		return redirect.TargetMode, append(redirect.Targets, checked), nil
+	})
+}
+
+// UpdateTargetWeight changes the weight of an existing target of a redirect
+func UpdateTargetWeight(rdb *redis.Client, key string, targetURL string, weight int, user string) error {
+	targetURL = strings.TrimSuffix(strings.TrimSpace(targetURL), "/")
+	if weight < 0 || weight > maxTargetWeight {
+		return fmt.Errorf("%w: must be between 0 and %d", ErrInvalidWeight, maxTargetWeight)
+	}
+
+	return updateTargets(rdb, key, user, func(redirect models.RedirectPath) (string, []models.Target, error) {
+		i := findTarget(redirect.Targets, targetURL)
+		if i < 0 {
+			return "", nil, fmt.Errorf("%w: `%s`", ErrTargetNotFound, targetURL)
+		}
+		redirect.Targets[i].Weight = weight
+		return redirect.TargetMode, redirect.Targets, nil
+	})
+}
+
+// RemoveTarget removes a target from a redirect
+func RemoveTarget(rdb *redis.Client, key string, targetURL string, user string) error {
+	targetURL = strings.TrimSuffix(strings.TrimSpace(targetURL), "/")
+
+	return updateTargets(rdb, key, user, func(redirect models.RedirectPath) (string, []models.Target, error) {
+		i := findTarget(redirect.Targets, targetURL)
+		if i < 0 {
+			return "", nil, fmt.Errorf("%w: `%s`", ErrTargetNotFound, targetURL)
+		}
+		return redirect.TargetMode, slices.Delete(redirect.Targets, i, i+1), nil
+	})
+}
+
+// updateTargets reads the targets of a redirect, applies change and stores the result in one
+// transaction, so concurrent edits of the same link do not overwrite each other. The change is
+// applied again to the new targets when the link changes while it runs.
+func updateTargets(rdb *redis.Client, key string, user string, change func(redirect models.RedirectPath) (string, []models.Target, error)) error {
+	// Resolve aliases and keys stored before normalization to the stored key first
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	path := "path:" + redirect.Path
+	var mode string
+	var targets []models.Target
+
+	update := func(tx *redis.Tx) error {
+		fields, err := tx.HGetAll(ctx, path).Result()
+		if err != nil {
+			return err
+		}
+		if fields["url"] == "" {
+			return ErrURLNotFound
+		}
+
+		mode, targets, err = change(redirectFromHash(redirect.Path, fields))
+		if err != nil {
+			return err
+		}
+		if targets == nil {
+			targets = []models.Target{}
+		}
+		if mode == "" {
+			mode = models.TargetModeRandom
+		}
+		encoded, err := json.Marshal(targets)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.HSet(ctx, path,
+				"targets", string(encoded),
+				"targetMode", mode,
+				"lastEditBy", user,
+				"lastEditTime", time.Now().Format(time.RFC3339),
+			)
+			return nil
+		})
+		if err != nil && err != redis.TxFailedErr {
+			rlog.Error("Failed to save targets", err, rlog.String("key", redirect.Path), rlog.String("user", user))
+		}
+		return err
+	}
+
+	for range targetAttempts {
+		err = rdb.Watch(ctx, update, path)
+		if err != redis.TxFailedErr {
+			break
+		}
+	}
+	if err == redis.TxFailedErr {
+		err = ErrTargetConflict
+	}
+	if err != nil {
+		return err
+	}
+
+	rlog.Info("Targets updated", rlog.String("key", redirect.Path), rlog.Any("targets", len(targets)), rlog.String("mode", mode), rlog.String("user", user))
+	return nil
+}
+
+// validateTarget checks the URL and weight of a single target
+func validateTarget(key string, target models.Target) error {
+	if target.URL == "" {
+		return fmt.Errorf("%w: target URL cannot be empty", ErrInvalidValue)
+	}
+	if target.Weight < 0 || target.Weight > maxTargetWeight {
+		return fmt.Errorf("%w: must be between 0 and %d", ErrInvalidWeight, maxTargetWeight)
+	}
+	return validatePathInput(key, target.URL)
+}
+
+// findTarget returns the index of the target with the given URL, or -1
+func findTarget(targets []models.Target, targetURL string) int {
+	for i, target := range targets {
+		if target.URL == targetURL {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,256 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestGetTargets(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	t.Run("Targets and mode returned", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{
+			"url":        "https://example.com",
+			"targets":    `[{"url":"https://a.example.com","weight":3},{"url":"https://b.example.com","weight":1}]`,
+			"targetMode": "sticky",
+		})
+
+		targets, err := GetTargets(db, "ab")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if targets.Mode != models.TargetModeSticky {
+			t.Errorf("expected mode %q, got %q", models.TargetModeSticky, targets.Mode)
+		}
+		if len(targets.Targets) != 2 || targets.Targets[0].Weight != 3 {
+			t.Errorf("unexpected targets: %+v", targets.Targets)
+		}
+	})
+
+	t.Run("Defaults when no targets are configured", func(t *testing.T) {
+		mock.ExpectHGetAll("path:single").SetVal(map[string]string{"url": "https://example.com"})
+
+		targets, err := GetTargets(db, "single")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if targets.Mode != models.TargetModeRandom {
+			t.Errorf("expected mode %q, got %q", models.TargetModeRandom, targets.Mode)
+		}
+		if targets.Targets == nil || len(targets.Targets) != 0 {
+			t.Errorf("expected empty targets, got %+v", targets.Targets)
+		}
+	})
+
+	t.Run("Redirect not found", func(t *testing.T) {
+		mock.ExpectHGetAll("path:missing").SetVal(map[string]string{})
+		mock.ExpectGet("alias:missing").RedisNil()
+
+		_, err := GetTargets(db, "missing")
+		if !errors.Is(err, ErrURLNotFound) {
+			t.Errorf("expected ErrURLNotFound, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestAddTarget(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+
+	t.Run("Add success", func(t *testing.T) {
+		stored := map[string]string{
+			"url":     "https://example.com",
+			"targets": `[{"url":"https://a.example.com","weight":1}]`,
+		}
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectTxPipeline()
+		mock.ExpectHSet("path:ab",
+			"targets", `[{"url":"https://a.example.com","weight":1},{"url":"https://b.example.com","weight":2}]`,
+			"targetMode", models.TargetModeRandom,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(1)
+		mock.ExpectTxPipelineExec()
+
+		err := AddTarget(db, "ab", models.Target{URL: "https://b.example.com/", Weight: 2}, user)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Duplicate target", func(t *testing.T) {
+		stored := map[string]string{
+			"url":     "https://example.com",
+			"targets": `[{"url":"https://a.example.com","weight":1}]`,
+		}
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+
+		err := AddTarget(db, "ab", models.Target{URL: "https://a.example.com", Weight: 2}, user)
+		if !errors.Is(err, ErrTargetExists) {
+			t.Errorf("expected ErrTargetExists, got %v", err)
+		}
+	})
+
+	t.Run("Target looping back through another link", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectHGetAll("path:cd").SetVal(map[string]string{"url": "https://k.nhn.no/ab"})
+
+		err := AddTarget(db, "ab", models.Target{URL: "https://k.nhn.no/cd", Weight: 1}, user)
+		if !errors.Is(err, ErrRedirectLoop) {
+			t.Errorf("expected ErrRedirectLoop, got %v", err)
+		}
+	})
+
+	t.Run("Invalid weight", func(t *testing.T) {
+		err := AddTarget(db, "ab", models.Target{URL: "https://c.example.com", Weight: -1}, user)
+		if !errors.Is(err, ErrInvalidWeight) {
+			t.Errorf("expected ErrInvalidWeight, got %v", err)
+		}
+	})
+
+	t.Run("Target added concurrently is kept", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectTxPipeline()
+		mock.ExpectHSet("path:ab",
+			"targets", `[{"url":"https://b.example.com","weight":2}]`,
+			"targetMode", models.TargetModeRandom,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(1)
+		mock.ExpectTxPipelineExec().SetErr(redis.TxFailedErr)
+
+		// The second attempt sees the target added by the other edit
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{
+			"url":     "https://example.com",
+			"targets": `[{"url":"https://a.example.com","weight":1}]`,
+		})
+		mock.ExpectTxPipeline()
+		mock.ExpectHSet("path:ab",
+			"targets", `[{"url":"https://a.example.com","weight":1},{"url":"https://b.example.com","weight":2}]`,
+			"targetMode", models.TargetModeRandom,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(1)
+		mock.ExpectTxPipelineExec()
+
+		if err := AddTarget(db, "ab", models.Target{URL: "https://b.example.com", Weight: 2}, user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Link keeps changing", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+		for range targetAttempts {
+			mock.ExpectWatch("path:ab").SetErr(redis.TxFailedErr)
+		}
+
+		err := AddTarget(db, "ab", models.Target{URL: "https://b.example.com", Weight: 2}, user)
+		if !errors.Is(err, ErrTargetConflict) {
+			t.Errorf("expected ErrTargetConflict, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestUpdateTargetWeightAndRemoveTarget(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+	stored := map[string]string{
+		"url":        "https://example.com",
+		"targets":    `[{"url":"https://a.example.com","weight":1},{"url":"https://b.example.com","weight":1}]`,
+		"targetMode": "sticky",
+	}
+
+	t.Run("Reweight target", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectTxPipeline()
+		mock.ExpectHSet("path:ab",
+			"targets", `[{"url":"https://a.example.com","weight":1},{"url":"https://b.example.com","weight":5}]`,
+			"targetMode", models.TargetModeSticky,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+		mock.ExpectTxPipelineExec()
+
+		if err := UpdateTargetWeight(db, "ab", "https://b.example.com", 5, user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Reweight unknown target", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+
+		err := UpdateTargetWeight(db, "ab", "https://c.example.com", 5, user)
+		if !errors.Is(err, ErrTargetNotFound) {
+			t.Errorf("expected ErrTargetNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Remove target", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectWatch("path:ab")
+		mock.ExpectHGetAll("path:ab").SetVal(stored)
+		mock.ExpectTxPipeline()
+		mock.ExpectHSet("path:ab",
+			"targets", `[{"url":"https://b.example.com","weight":1}]`,
+			"targetMode", models.TargetModeSticky,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+		mock.ExpectTxPipelineExec()
+
+		if err := RemoveTarget(db, "ab", "https://a.example.com", user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestSetTargets(t *testing.T) {
+	db, _ := redismock.NewClientMock()
+
+	t.Run("Unknown mode", func(t *testing.T) {
+		err := SetTargets(db, "ab", models.RedirectTargets{Mode: "roundrobin"}, "testuser")
+		if !errors.Is(err, ErrInvalidTargetMode) {
+			t.Errorf("expected ErrInvalidTargetMode, got %v", err)
+		}
+	})
+
+	t.Run("Duplicate targets", func(t *testing.T) {
+		err := SetTargets(db, "ab", models.RedirectTargets{Targets: []models.Target{
+			{URL: "https://a.example.com", Weight: 1},
+			{URL: "https://a.example.com/", Weight: 1},
+		}}, "testuser")
+		if !errors.Is(err, ErrTargetExists) {
+			t.Errorf("expected ErrTargetExists, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,294 @@
+package redis
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+	"github.com/spf13/viper"
+)
+
+const (
+	// AccessTokenPrefix starts every personal access token, so they are told apart from OIDC tokens
+	// and can be found by secret scanners
+	AccessTokenPrefix = "shorty_pat_"
+	// DefaultTokenDays is how long a personal access token is valid when no expiry is given
+	DefaultTokenDays = 90
+	// DefaultTokenMaxDays is the longest a personal access token can be valid, unless TOKEN_MAX_DAYS is set
+	DefaultTokenMaxDays = 365
+	// tokenNameLength is the longest allowed token name
+	tokenNameLength = 100
+	// tokenLastUsedInterval limits how often the last use of a busy token is written
+	tokenLastUsedInterval = time.Minute
+)
+
+var (
+	// ErrTokenNotFound is returned when a personal access token does not exist
+	ErrTokenNotFound = errors.New("token not found")
+	// ErrInvalidTokenRequest is returned when a token name, its scopes or expiry are not allowed
+	ErrInvalidTokenRequest = errors.New("invalid token request")
+	// ErrInvalidAccessToken is returned when a personal access token is malformed, unknown or revoked
+	ErrInvalidAccessToken = errors.New("invalid access token")
+	// ErrAccessTokenExpired is returned when a personal access token has expired
+	ErrAccessTokenExpired = errors.New("access token has expired")
+)
+
+// tokenKey returns the redis key of a personal access token
+func tokenKey(id string) string {
+	return "token:" + id
+}
+
+// userTokensKey returns the redis key of the set of token ids of a user
+func userTokensKey(owner string) string {
+	return "tokens:" + owner
+}
+
+// hashTokenSecret returns the stored hash of the secret part of a token
+func hashTokenSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// IsAccessToken reports whether a bearer token is a personal access token
+func IsAccessToken(token string) bool {
+	return strings.HasPrefix(token, AccessTokenPrefix)
+}
+
+// CreateAccessToken creates a personal access token for owner. Only a hash of the secret is stored,
+// so the returned token cannot be shown again. The token is removed from redis when it expires.
+func CreateAccessToken(rdb *redis.Client, owner string, request models.AccessTokenRequest) (models.CreatedAccessToken, error) {
+	name := strings.TrimSpace(request.Name)
+	if name == "" || len(name) > tokenNameLength {
+		return models.CreatedAccessToken{}, fmt.Errorf("%w: name must be 1-%d characters", ErrInvalidTokenRequest, tokenNameLength)
+	}
+
+	scopes := []string{}
+	for _, scope := range request.Scopes {
+		if !slices.Contains(models.Scopes, scope) {
+			return models.CreatedAccessToken{}, fmt.Errorf("%w: unknown scope `%s`", ErrInvalidTokenRequest, scope)
+		}
+		if !slices.Contains(scopes, scope) {
+			scopes = append(scopes, scope)
+		}
+	}
+	if len(scopes) == 0 {
+		return models.CreatedAccessToken{}, fmt.Errorf("%w: at least one scope must be given", ErrInvalidTokenRequest)
+	}
+	slices.Sort(scopes)
+
+	days := request.ExpiresInDays
+	if days == 0 {
+		days = DefaultTokenDays
+	}
+	maxDays := viper.GetInt("TOKEN_MAX_DAYS")
+	if maxDays <= 0 {
+		maxDays = DefaultTokenMaxDays
+	}
+	if days < 0 || days > maxDays {
+		return models.CreatedAccessToken{}, fmt.Errorf("%w: expiry must be 1-%d days", ErrInvalidTokenRequest, maxDays)
+	}
+
+	random := make([]byte, 40)
+	if _, err := rand.Read(random); err != nil {
+		return models.CreatedAccessToken{}, err
+	}
+	id := hex.EncodeToString(random[:8])
+	secret := base64.RawURLEncoding.EncodeToString(random[8:])
+
+	encodedScopes, err := json.Marshal(scopes)
+	if err != nil {
+		return models.CreatedAccessToken{}, err
+	}
+
+	now := time.Now().UTC()
+	expires := now.AddDate(0, 0, days)
+	token := models.AccessToken{
+		ID:          id,
+		Name:        name,
+		Owner:       owner,
+		Scopes:      scopes,
+		CreatedTime: now.Format(time.RFC3339),
+		ExpiresTime: expires.Format(time.RFC3339),
+	}
+
+	ctx := context.Background()
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.HSet(ctx, tokenKey(id),
+			"name", token.Name,
+			"owner", token.Owner,
+			"scopes", string(encodedScopes),
+			"hash", hashTokenSecret(secret),
+			"createdTime", token.CreatedTime,
+			"expiresTime", token.ExpiresTime,
+		)
+		pipe.ExpireAt(ctx, tokenKey(id), expires)
+		pipe.SAdd(ctx, userTokensKey(owner), id)
+		return nil
+	})
+	if err != nil {
+		rlog.Error("Failed to save access token", err, rlog.String("owner", owner))
+		return models.CreatedAccessToken{}, err
+	}
+
+	rlog.Info("Access token created", rlog.String("id", id), rlog.String("owner", owner), rlog.Any("scopes", scopes))
+	return models.CreatedAccessToken{AccessToken: token, Token: AccessTokenPrefix + id + "_" + secret}, nil
+}
+
+// GetAccessTokens returns the personal access tokens of owner, or of every user when owner is empty,
+// oldest first
+func GetAccessTokens(rdb *redis.Client, owner string) ([]models.AccessToken, error) {
+	ctx := context.Background()
+
+	var ids []string
+	if owner != "" {
+		members, err := rdb.SMembers(ctx, userTokensKey(owner)).Result()
+		if err != nil {
+			return nil, err
+		}
+		ids = members
+	} else {
+		keys, err := rdb.Keys(ctx, "token:*").Result()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			ids = append(ids, strings.TrimPrefix(key, "token:"))
+		}
+	}
+
+	cmds := make([]*redis.StringStringMapCmd, len(ids))
+	_, err := rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		for i, id := range ids {
+			cmds[i] = pipe.HGetAll(ctx, tokenKey(id))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	tokens := []models.AccessToken{}
+	expired := []interface{}{}
+	for i, id := range ids {
+		fields := cmds[i].Val()
+		if len(fields) == 0 {
+			expired = append(expired, id)
+			continue
+		}
+		tokens = append(tokens, tokenFromHash(id, fields))
+	}
+
+	// Expired tokens are removed by redis, their ids are cleaned up from the owner's set here
+	if owner != "" && len(expired) > 0 {
+		if err := rdb.SRem(ctx, userTokensKey(owner), expired...).Err(); err != nil {
+			rlog.Error("Failed to remove expired token ids", err, rlog.String("owner", owner))
+		}
+	}
+
+	slices.SortFunc(tokens, func(a, b models.AccessToken) int {
+		if c := cmp.Compare(a.CreatedTime, b.CreatedTime); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	})
+	return tokens, nil
+}
+
+// GetAccessToken returns a personal access token by id, without its secret
+func GetAccessToken(rdb *redis.Client, id string) (models.AccessToken, error) {
+	fields, err := rdb.HGetAll(context.Background(), tokenKey(id)).Result()
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+	if len(fields) == 0 {
+		return models.AccessToken{}, fmt.Errorf("%w: `%s`", ErrTokenNotFound, id)
+	}
+	return tokenFromHash(id, fields), nil
+}
+
+// RevokeAccessToken deletes a personal access token, so it can no longer be used
+func RevokeAccessToken(rdb *redis.Client, id string, user string) error {
+	ctx := context.Background()
+	owner, err := rdb.HGet(ctx, tokenKey(id), "owner").Result()
+	if err == redis.Nil {
+		return fmt.Errorf("%w: `%s`", ErrTokenNotFound, id)
+	} else if err != nil {
+		return err
+	}
+
+	_, err = rdb.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+		pipe.Del(ctx, tokenKey(id))
+		pipe.SRem(ctx, userTokensKey(owner), id)
+		return nil
+	})
+	if err != nil {
+		rlog.Error("Failed to revoke access token", err, rlog.String("id", id), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("Access token revoked", rlog.String("id", id), rlog.String("owner", owner), rlog.String("user", user))
+	return nil
+}
+
+// ValidateAccessToken checks a personal access token and returns it, and notes when it was last used
+func ValidateAccessToken(rdb *redis.Client, raw string) (models.AccessToken, error) {
+	id, secret, ok := strings.Cut(strings.TrimPrefix(raw, AccessTokenPrefix), "_")
+	if !IsAccessToken(raw) || !ok || id == "" || secret == "" {
+		return models.AccessToken{}, ErrInvalidAccessToken
+	}
+
+	ctx := context.Background()
+	fields, err := rdb.HGetAll(ctx, tokenKey(id)).Result()
+	if err != nil {
+		return models.AccessToken{}, err
+	}
+	if len(fields) == 0 || subtle.ConstantTimeCompare([]byte(fields["hash"]), []byte(hashTokenSecret(secret))) != 1 {
+		return models.AccessToken{}, ErrInvalidAccessToken
+	}
+
+	now := time.Now().UTC()
+	token := tokenFromHash(id, fields)
+	if expires, err := time.Parse(time.RFC3339, token.ExpiresTime); err != nil || !now.Before(expires) {
+		return models.AccessToken{}, ErrAccessTokenExpired
+	}
+
+	if lastUsed, err := time.Parse(time.RFC3339, token.LastUsed); err != nil || now.Sub(lastUsed) >= tokenLastUsedInterval {
+		token.LastUsed = now.Format(time.RFC3339)
+		if err := rdb.HSet(ctx, tokenKey(id), "lastUsed", token.LastUsed).Err(); err != nil {
+			rlog.Error("Failed to record token use", err, rlog.String("id", id))
+		}
+	}
+	return token, nil
+}
+
+// tokenFromHash builds a personal access token from its stored hash fields
+func tokenFromHash(id string, fields map[string]string) models.AccessToken {
+	token := models.AccessToken{
+		ID:          id,
+		Name:        fields["name"],
+		Owner:       fields["owner"],
+		Scopes:      []string{},
+		CreatedTime: fields["createdTime"],
+		ExpiresTime: fields["expiresTime"],
+		LastUsed:    fields["lastUsed"],
+	}
+	if fields["scopes"] != "" {
+		if err := json.Unmarshal([]byte(fields["scopes"]), &token.Scopes); err != nil {
+			rlog.Error("Failed to decode token scopes", err, rlog.String("id", id))
+		}
+	}
+	return token
+}
@@ -0,0 +1,199 @@
+package redis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestCreateAccessToken(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	t.Run("Token is stored hashed", func(t *testing.T) {
+		// The token id, secret and times are random or depend on the current time,
+		// so only the stored fields are compared
+		var storedHash string
+		sameCommand := func(expected, actual []interface{}) error {
+			if expected[0] != actual[0] || !strings.HasPrefix(fmt.Sprint(actual[1]), fmt.Sprint(expected[1])) {
+				return fmt.Errorf("expected %v, got %v", expected, actual)
+			}
+			return nil
+		}
+		mock.ExpectTxPipeline()
+		mock.CustomMatch(func(expected, actual []interface{}) error {
+			for _, i := range []int{2, 3, 4, 5, 6, 7} {
+				if expected[i] != actual[i] {
+					return fmt.Errorf("expected %v, got %v", expected, actual)
+				}
+			}
+			storedHash = fmt.Sprint(actual[9])
+			return sameCommand(expected, actual)
+		}).ExpectHSet("token:", "name", "ci", "owner", "user@example.com", "scopes", `["read","write"]`,
+			"hash", "", "createdTime", "", "expiresTime", "").SetVal(6)
+		mock.CustomMatch(sameCommand).ExpectExpireAt("token:", time.Time{}).SetVal(true)
+		mock.CustomMatch(sameCommand).ExpectSAdd("tokens:user@example.com", "").SetVal(1)
+		mock.ExpectTxPipelineExec()
+
+		request := models.AccessTokenRequest{Name: " ci ", Scopes: []string{"write", "read", "write"}}
+		created, err := CreateAccessToken(db, "user@example.com", request)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(created.Token, AccessTokenPrefix+created.ID+"_") {
+			t.Errorf("unexpected token %q for id %q", created.Token, created.ID)
+		}
+		if created.Name != "ci" || strings.Join(created.Scopes, ",") != "read,write" {
+			t.Errorf("unexpected name or scopes: %+v", created.AccessToken)
+		}
+		secret := strings.TrimPrefix(created.Token, AccessTokenPrefix+created.ID+"_")
+		if storedHash != hashTokenSecret(secret) || strings.Contains(storedHash, secret) {
+			t.Errorf("expected the hash of the secret to be stored, got %q", storedHash)
+		}
+		if expires, err := time.Parse(time.RFC3339, created.ExpiresTime); err != nil || expires.Sub(time.Now()) < 89*24*time.Hour {
+			t.Errorf("expected the token to expire in 90 days, got %q", created.ExpiresTime)
+		}
+	})
+
+	tests := []struct {
+		name    string
+		request models.AccessTokenRequest
+	}{
+		{name: "No name", request: models.AccessTokenRequest{Scopes: []string{"read"}}},
+		{name: "No scopes", request: models.AccessTokenRequest{Name: "ci"}},
+		{name: "Unknown scope", request: models.AccessTokenRequest{Name: "ci", Scopes: []string{"delete"}}},
+		{name: "Too long", request: models.AccessTokenRequest{Name: "ci", Scopes: []string{"read"}, ExpiresInDays: 1000}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := CreateAccessToken(db, "user@example.com", tc.request); !errors.Is(err, ErrInvalidTokenRequest) {
+				t.Errorf("expected ErrInvalidTokenRequest, got %v", err)
+			}
+		})
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestValidateAccessToken(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	future := time.Now().UTC().Add(time.Hour).Format(time.RFC3339)
+	recent := time.Now().UTC().Add(-10 * time.Second).Format(time.RFC3339)
+	stored := func(expires string, lastUsed string) map[string]string {
+		return map[string]string{
+			"name": "ci", "owner": "user@example.com", "scopes": `["read"]`, "hash": hashTokenSecret("secret"),
+			"createdTime": "2025-03-01T00:00:00Z", "expiresTime": expires, "lastUsed": lastUsed,
+		}
+	}
+
+	t.Run("Valid token records its use", func(t *testing.T) {
+		mock.ExpectHGetAll("token:abc").SetVal(stored(future, ""))
+		mock.Regexp().ExpectHSet("token:abc", "lastUsed", `.+`).SetVal(1)
+
+		token, err := ValidateAccessToken(db, "shorty_pat_abc_secret")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if token.Owner != "user@example.com" || len(token.Scopes) != 1 || token.Scopes[0] != models.ScopeRead {
+			t.Errorf("unexpected token: %+v", token)
+		}
+	})
+
+	t.Run("Recent use is not written again", func(t *testing.T) {
+		mock.ExpectHGetAll("token:abc").SetVal(stored(future, recent))
+
+		if _, err := ValidateAccessToken(db, "shorty_pat_abc_secret"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Wrong secret", func(t *testing.T) {
+		mock.ExpectHGetAll("token:abc").SetVal(stored(future, ""))
+
+		if _, err := ValidateAccessToken(db, "shorty_pat_abc_guess"); !errors.Is(err, ErrInvalidAccessToken) {
+			t.Errorf("expected ErrInvalidAccessToken, got %v", err)
+		}
+	})
+
+	t.Run("Revoked token", func(t *testing.T) {
+		mock.ExpectHGetAll("token:gone").SetVal(map[string]string{})
+
+		if _, err := ValidateAccessToken(db, "shorty_pat_gone_secret"); !errors.Is(err, ErrInvalidAccessToken) {
+			t.Errorf("expected ErrInvalidAccessToken, got %v", err)
+		}
+	})
+
+	t.Run("Expired token", func(t *testing.T) {
+		mock.ExpectHGetAll("token:abc").SetVal(stored("2025-01-01T00:00:00Z", ""))
+
+		if _, err := ValidateAccessToken(db, "shorty_pat_abc_secret"); !errors.Is(err, ErrAccessTokenExpired) {
+			t.Errorf("expected ErrAccessTokenExpired, got %v", err)
+		}
+	})
+
+	t.Run("Malformed token", func(t *testing.T) {
+		if _, err := ValidateAccessToken(db, "shorty_pat_abc"); !errors.Is(err, ErrInvalidAccessToken) {
+			t.Errorf("expected ErrInvalidAccessToken, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestGetAccessTokens(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	mock.ExpectSMembers("tokens:user@example.com").SetVal([]string{"new", "expired", "old"})
+	mock.ExpectHGetAll("token:new").SetVal(map[string]string{"name": "deploy", "owner": "user@example.com", "scopes": `["write"]`, "hash": "h", "createdTime": "2025-03-02T00:00:00Z"})
+	mock.ExpectHGetAll("token:expired").SetVal(map[string]string{})
+	mock.ExpectHGetAll("token:old").SetVal(map[string]string{"name": "ci", "owner": "user@example.com", "scopes": `["read"]`, "hash": "h", "createdTime": "2025-03-01T00:00:00Z"})
+	mock.ExpectSRem("tokens:user@example.com", "expired").SetVal(1)
+
+	tokens, err := GetAccessTokens(db, "user@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tokens) != 2 || tokens[0].ID != "old" || tokens[1].Name != "deploy" {
+		t.Errorf("expected the tokens oldest first, got %+v", tokens)
+	}
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestRevokeAccessToken(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+
+	t.Run("Revoke token", func(t *testing.T) {
+		mock.ExpectHGet("token:abc", "owner").SetVal("user@example.com")
+		mock.ExpectTxPipeline()
+		mock.ExpectDel("token:abc").SetVal(1)
+		mock.ExpectSRem("tokens:user@example.com", "abc").SetVal(1)
+		mock.ExpectTxPipelineExec()
+
+		if err := RevokeAccessToken(db, "abc", "admin@example.com"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Unknown token", func(t *testing.T) {
+		mock.ExpectHGet("token:gone", "owner").RedisNil()
+
+		if err := RevokeAccessToken(db, "gone", "user@example.com"); !errors.Is(err, ErrTokenNotFound) {
+			t.Errorf("expected ErrTokenNotFound, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
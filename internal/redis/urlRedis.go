@@ -2,6 +2,7 @@ package redis
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"regexp"
@@ -16,6 +17,8 @@ import (
 var (
 	// ErrURLNotFound is returned when a URL is not found in the database
 	ErrURLNotFound = errors.New("URL not found")
+	// ErrOwnerNotFound is returned when a redirect, or the canonical redirect of an alias, has no owner because it does not exist
+	ErrOwnerNotFound = errors.New("owner not found")
 	// ErrNoPathsFound is returned when no paths are found in the database
 	ErrNoPathsFound = errors.New("no paths found")
 	// ErrInvalidKey is returned when a key is not allowed
@@ -28,8 +31,22 @@ var (
 
 // GetURL retrieves a URL by its key ID
 func GetURL(rdb *redis.Client, keyID string) (string, error) {
-	pathKey := "path:" + keyID
-	url, err := rdb.HGet(context.Background(), pathKey, "url").Result()
+	ctx := context.Background()
+	key, err := storedKey(ctx, rdb, keyID)
+	if err != nil {
+		return "", err
+	}
+	url, err := rdb.HGet(ctx, "path:"+key, "url").Result()
+
+	// Follow aliases to their canonical redirect
+	if err == redis.Nil {
+		canonical, aliasErr := rdb.Get(ctx, aliasKey(keyID)).Result()
+		if aliasErr == nil {
+			url, err = rdb.HGet(ctx, "path:"+canonical, "url").Result()
+		} else if aliasErr != redis.Nil {
+			err = aliasErr
+		}
+	}
 
 	if err == redis.Nil {
 		return "", ErrURLNotFound
@@ -40,6 +57,93 @@ func GetURL(rdb *redis.Client, keyID string) (string, error) {
 	return url, nil
 }
 
+// GetRedirect retrieves the full redirect record stored for a key ID
+func GetRedirect(rdb *redis.Client, keyID string) (models.RedirectPath, error) {
+	ctx := context.Background()
+	key, err := storedKey(ctx, rdb, keyID)
+	if err != nil {
+		return models.RedirectPath{}, err
+	}
+
+	fields, err := rdb.HGetAll(ctx, "path:"+key).Result()
+	if err != nil {
+		return models.RedirectPath{}, err
+	}
+
+	// Follow aliases to their canonical redirect
+	if fields["url"] == "" {
+		canonical, err := rdb.Get(ctx, aliasKey(keyID)).Result()
+		if err != nil && err != redis.Nil {
+			return models.RedirectPath{}, err
+		}
+		if err == nil {
+			key = canonical
+			fields, err = rdb.HGetAll(ctx, "path:"+key).Result()
+			if err != nil {
+				return models.RedirectPath{}, err
+			}
+		}
+	}
+
+	if fields["url"] == "" {
+		return models.RedirectPath{}, ErrURLNotFound
+	}
+
+	return redirectFromHash(key, fields), nil
+}
+
+// redirectFromHash builds a RedirectPath from the fields of a path hash
+func redirectFromHash(path string, fields map[string]string) models.RedirectPath {
+	redirect := models.RedirectPath{
+		Path:         path,
+		URL:          fields["url"],
+		Owner:        fields["createdBy"],
+		ServiceOwner: fields["serviceOwner"],
+		TargetMode:   fields["targetMode"],
+	}
+
+	if raw := fields["targets"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &redirect.Targets); err != nil {
+			rlog.Error("Failed to decode targets", err, rlog.String("path", path))
+		}
+	}
+
+	if raw := fields["languages"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &redirect.Languages); err != nil {
+			rlog.Error("Failed to decode language variants", err, rlog.String("path", path))
+		}
+	}
+
+	if raw := fields["devices"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &redirect.Devices); err != nil {
+			rlog.Error("Failed to decode device targets", err, rlog.String("path", path))
+		}
+	}
+
+	// Only paused redirects carry a status, enabled is the default
+	if fields["enabled"] == "false" {
+		redirect.Status = &models.RedirectStatus{
+			Enabled: false,
+			Message: fields["pausedMessage"],
+			Target:  fields["pausedTarget"],
+		}
+	}
+
+	if raw := fields["utm"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &redirect.UTM); err != nil {
+			rlog.Error("Failed to decode UTM settings", err, rlog.String("path", path))
+		}
+	}
+
+	if raw := fields["aliases"]; raw != "" {
+		if err := json.Unmarshal([]byte(raw), &redirect.Aliases); err != nil {
+			rlog.Error("Failed to decode aliases", err, rlog.String("path", path))
+		}
+	}
+
+	return redirect
+}
+
 // UpdateOrCreate creates or updates a URL in Redis
 // Returns a descriptive message and any error that occurred
 func UpdateOrCreatePath(rdb *redis.Client, key string, newValue string, user string) (string, error) {
@@ -60,6 +164,22 @@ func UpdateOrCreatePath(rdb *redis.Client, key string, newValue string, user str
 		return "", err
 	}
 
+	// Existing records are updated under the key they are stored under, new ones are created normalized
+	key, err = storedKey(ctx, rdb, key)
+	if err != nil {
+		rlog.Error("Failed to resolve stored key", err, rlog.Any("key", key))
+		return "", err
+	}
+
+	newValue, err = checkChain(rdb, key, newValue)
+	if err != nil {
+		rlog.Error("Redirect chain check failed", err,
+			rlog.String("key", key),
+			rlog.String("value", newValue),
+			rlog.String("user", user))
+		return "", err
+	}
+
 	pathKey := "path:" + key
 
 	// Check if the key exists
@@ -87,6 +207,16 @@ func UpdateOrCreatePath(rdb *redis.Client, key string, newValue string, user str
 		return "Path updated successfully", nil
 	}
 
+	// Keys freed by a rename stay reserved for a grace period
+	reserved, err := IsReserved(rdb, key)
+	if err != nil {
+		rlog.Error("Failed to check if key is reserved", err, rlog.Any("key", key))
+		return "", err
+	}
+	if reserved {
+		return "", fmt.Errorf("%w: `%s`", ErrKeyReserved, key)
+	}
+
 	// Create new record
 	err = rdb.HSet(ctx, pathKey,
 		"url", newValue,
@@ -103,24 +233,60 @@ func UpdateOrCreatePath(rdb *redis.Client, key string, newValue string, user str
 	return "Path created successfully", nil
 }
 
-// URLExists checks if a URL with the given key exists in the database
+// URLExists checks if a URL or an alias with the given key exists in the database
 func URLExists(rdb *redis.Client, key string) (bool, error) {
-	exist, err := rdb.Exists(context.Background(), "path:"+key).Result()
+	ctx := context.Background()
+	stored, err := storedKey(ctx, rdb, key)
+	if err != nil {
+		return false, err
+	}
+	exist, err := rdb.Exists(ctx, "path:"+stored, aliasKey(key)).Result()
 	if err != nil {
 		return false, err
 	}
 	return exist > 0, nil
 }
 
-// Delete removes a redirect by key
+// Delete removes a redirect by key together with its aliases and click statistics
 // Returns true if the key was deleted, false if it didn't exist
 func Delete(rdb *redis.Client, key string) (bool, error) {
-	path := "path:" + key
-	nDeleted, err := rdb.Del(context.Background(), path).Result()
+	ctx := context.Background()
+	stored, err := storedKey(ctx, rdb, key)
+	if err != nil {
+		return false, err
+	}
+	path := "path:" + stored
+
+	days, err := rdb.HKeys(ctx, statsKey(key)).Result()
+	if err != nil {
+		return false, err
+	}
+
+	keys := append([]string{path}, statsKeys(key, days)...)
+	aliases, err := rdb.HGet(ctx, path, "aliases").Result()
+	if err != nil && err != redis.Nil {
+		return false, err
+	}
+	if aliases != "" {
+		var names []string
+		if err := json.Unmarshal([]byte(aliases), &names); err != nil {
+			rlog.Error("Failed to decode aliases", err, rlog.String("key", key))
+		}
+		for _, name := range names {
+			keys = append(keys, aliasKey(name))
+		}
+	}
+
+	var nDeleted *redis.IntCmd
+	_, err = rdb.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+		nDeleted = pipe.Del(ctx, keys...)
+		pipe.ZRem(ctx, linkRankingKey, NormalizeKey(key))
+		return nil
+	})
 	if err != nil {
 		return false, err
 	}
-	return nDeleted > 0, nil
+	return nDeleted.Val() > 0, nil
 }
 
 // GetAll retrieves all redirects with the given prefix
@@ -136,29 +302,20 @@ func GetAll(rdb *redis.Client, prefix string) ([]models.RedirectPath, error) {
 	redirectPaths := make([]models.RedirectPath, 0, len(keys))
 
 	for _, key := range keys {
-		url, err := rdb.HGet(ctx, key, "url").Result()
+		fields, err := rdb.HGetAll(ctx, key).Result()
 		if err != nil {
-			if err == redis.Nil {
-				continue
-			}
 			return nil, err
 		}
 
-		createdBy, err := rdb.HGet(ctx, key, "createdBy").Result()
-		if err != nil && err != redis.Nil {
-			return nil, err
+		if fields["url"] == "" {
+			continue
 		}
 
 		// Extract path from key
 		path := strings.TrimPrefix(key, prefix)
 		path = strings.TrimLeft(path, ":")
 
-		redirect := models.RedirectPath{
-			Path:  path,
-			URL:   url,
-			Owner: createdBy,
-		}
-		redirectPaths = append(redirectPaths, redirect)
+		redirectPaths = append(redirectPaths, redirectFromHash(path, fields))
 	}
 	return redirectPaths, nil
 }
@@ -166,12 +323,25 @@ func GetAll(rdb *redis.Client, prefix string) ([]models.RedirectPath, error) {
 // GetPathOwner retrieves the owner of a path
 // Returns the owner's email or an error if not found
 func GetPathOwner(rdb *redis.Client, key string) (string, error) {
-	const errOwnerNotFound = "owner not found"
-	pathKey := "path:" + key
+	stored, err := storedKey(context.Background(), rdb, key)
+	if err != nil {
+		return "", err
+	}
+
+	createdBy, err := rdb.HGet(context.Background(), "path:"+stored, "createdBy").Result()
+
+	// Aliases are owned by the owner of their canonical redirect
+	if err == redis.Nil {
+		canonical, aliasErr := rdb.Get(context.Background(), aliasKey(key)).Result()
+		if aliasErr == nil {
+			createdBy, err = rdb.HGet(context.Background(), "path:"+canonical, "createdBy").Result()
+		} else if aliasErr != redis.Nil {
+			err = aliasErr
+		}
+	}
 
-	createdBy, err := rdb.HGet(context.Background(), pathKey, "createdBy").Result()
 	if err == redis.Nil {
-		return "", errors.New(errOwnerNotFound)
+		return "", ErrOwnerNotFound
 	} else if err != nil {
 		return "", err
 	}
@@ -184,6 +354,36 @@ func validatePathInput(key, newValue string) error {
 	key = strings.TrimSpace(key)
 	newValue = strings.TrimSpace(newValue)
 
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	// Targets on our own domains may not point at the API, the admin pages or the key itself
+	if domain, path, ok := shortLinkFromURL(newValue); ok {
+		first, _, _ := strings.Cut(path, "/")
+		if isReservedKey(first) || strings.EqualFold(first, "v1") || strings.EqualFold(first, "qr") {
+			return fmt.Errorf("%w: cannot redirect to `%s`", ErrInvalidKey, newValue)
+		}
+		if NormalizeKey(domain.Key(path)) == NormalizeKey(key) {
+			return fmt.Errorf("%w: cannot redirect to itself `%s`", ErrRedirectLoop, key)
+		}
+	}
+
+	return nil
+}
+
+var validNamespacePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+
+// ValidateKey checks that a key has a valid format and is not reserved
+func ValidateKey(key string) error {
+	// Keys on namespaced domains are stored as <namespace>:<key>
+	if namespace, local, found := strings.Cut(key, ":"); found {
+		if !validNamespacePattern.MatchString(namespace) {
+			return fmt.Errorf("%w: invalid namespace `%s`", ErrInvalidKey, namespace)
+		}
+		key = local
+	}
+
 	// Key format validation - only allow alphanumeric, dash, underscore
 	validKeyPattern := regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
 	if !validKeyPattern.MatchString(key) {
@@ -195,27 +395,20 @@ func validatePathInput(key, newValue string) error {
 		return fmt.Errorf("%w: key cannot start with dash or underscore", ErrInvalidKey)
 	}
 
-	reservedKeys := []string{"admin", "api", "health", "metrics", "swagger"}
-	for _, reserved := range reservedKeys {
-		if strings.EqualFold(key, reserved) {
-			return fmt.Errorf("%w: is a reserved key`%s`", ErrInvalidKey, key)
-		}
+	if isReservedKey(key) {
+		return fmt.Errorf("%w: is a reserved key`%s`", ErrInvalidKey, key)
 	}
 
-	forbiddenTargets := []string{
-		"https://k.nhn.no/admin/user",
-		"https://k.nhn.no/admin",
-		"http://k.nhn.no/admin/user",
-		"http://k.nhn.no/admin",
-		"https://k.nhn.no/" + key,
-		"http://k.nhn.no/" + key,
-	}
+	return nil
+}
 
-	for _, forbidden := range forbiddenTargets {
-		if strings.EqualFold(newValue, forbidden) {
-			return fmt.Errorf("%w: cannot redirect to `%s`", ErrInvalidKey, key)
+// isReservedKey reports whether a key collides with a route of the service itself
+func isReservedKey(key string) bool {
+	reservedKeys := []string{"admin", "api", "health", "metrics", "swagger"}
+	for _, reserved := range reservedKeys {
+		if strings.EqualFold(key, reserved) {
+			return true
 		}
 	}
-
-	return nil
+	return false
 }
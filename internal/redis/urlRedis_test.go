@@ -42,6 +42,7 @@ func TestGetURL(t *testing.T) {
 
 	// When key "nonexistent" is used, redis returns nil
 	mock.ExpectHGet("path:nonexistent", "url").RedisNil()
+	mock.ExpectGet("alias:nonexistent").RedisNil()
 	// When key "existing" is used, redis returns the valid URL
 	mock.ExpectHGet("path:existing", "url").SetVal("https://example.com")
 
@@ -102,6 +103,7 @@ func TestUpdateOrCreatePath(t *testing.T) {
 
 		// Expect Exists to return 0 (key does not exist)
 		mock.ExpectExists(pathKey).SetVal(0)
+		mock.ExpectExists("reserved:" + key).SetVal(0)
 
 		// Capture expected timestamp for creation.
 		expectedTime := time.Now().Format(time.RFC3339)
@@ -122,6 +124,18 @@ func TestUpdateOrCreatePath(t *testing.T) {
 		}
 	})
 
+	t.Run("Key reserved after rename", func(t *testing.T) {
+		pathKey := "path:" + key
+
+		mock.ExpectExists(pathKey).SetVal(0)
+		mock.ExpectExists("reserved:" + key).SetVal(1)
+
+		_, err := UpdateOrCreatePath(db, key, newValue, user)
+		if !errors.Is(err, ErrKeyReserved) {
+			t.Errorf("expected ErrKeyReserved, got %v", err)
+		}
+	})
+
 	t.Run("Exists error", func(t *testing.T) {
 		pathKey := "path:" + key
 
@@ -164,6 +178,7 @@ func TestUpdateOrCreatePath(t *testing.T) {
 
 		// Expect Exists to return 0 (create branch)
 		mock.ExpectExists(pathKey).SetVal(0)
+		mock.ExpectExists("reserved:" + key).SetVal(0)
 		expectedTime := time.Now().Format(time.RFC3339)
 		// Simulate an error during HSet call in create branch.
 		mock.ExpectHSet(pathKey,
@@ -197,7 +212,7 @@ func TestURLExists(t *testing.T) {
 		pathKey := "path:" + key
 
 		// Expect Exists to return 1 (key exists)
-		mock.ExpectExists(pathKey).SetVal(1)
+		mock.ExpectExists(pathKey, "alias:"+key).SetVal(1)
 
 		exists, err := URLExists(db, key)
 		if err != nil {
@@ -213,7 +228,7 @@ func TestURLExists(t *testing.T) {
 		pathKey := "path:" + key
 
 		// Expect Exists to return 0 (key does not exist)
-		mock.ExpectExists(pathKey).SetVal(0)
+		mock.ExpectExists(pathKey, "alias:"+key).SetVal(0)
 
 		exists, err := URLExists(db, key)
 		if err != nil {
@@ -229,7 +244,7 @@ func TestURLExists(t *testing.T) {
 		pathKey := "path:" + key
 
 		// Expect Exists to return an error
-		mock.ExpectExists(pathKey).SetErr(errors.New("exists error"))
+		mock.ExpectExists(pathKey, "alias:"+key).SetErr(errors.New("exists error"))
 
 		exists, err := URLExists(db, key)
 		if err == nil {
@@ -255,7 +270,11 @@ func TestDelete(t *testing.T) {
 		path := "path:" + key
 
 		// Expect Del to return 1 as the number of deleted keys.
-		mock.ExpectDel(path).SetVal(1)
+		mock.ExpectHKeys("stats:" + key).SetVal([]string{})
+		mock.ExpectHGet(path, "aliases").RedisNil()
+		mock.ExpectDel(path, "stats:"+key, "visitors:"+key,
+			"breakdown:"+key+"|referrer", "breakdown:"+key+"|device", "breakdown:"+key+"|browser", "breakdown:"+key+"|country", "breakdown:"+key+"|target").SetVal(1)
+		mock.ExpectZRem("ranking:clicks", key).SetVal(1)
 
 		deleted, err := Delete(db, key)
 		if err != nil {
@@ -271,7 +290,11 @@ func TestDelete(t *testing.T) {
 		path := "path:" + key
 
 		// Expect Del to return 0 as no keys were deleted.
-		mock.ExpectDel(path).SetVal(0)
+		mock.ExpectHKeys("stats:" + key).SetVal([]string{})
+		mock.ExpectHGet(path, "aliases").RedisNil()
+		mock.ExpectDel(path, "stats:"+key, "visitors:"+key,
+			"breakdown:"+key+"|referrer", "breakdown:"+key+"|device", "breakdown:"+key+"|browser", "breakdown:"+key+"|country", "breakdown:"+key+"|target").SetVal(0)
+		mock.ExpectZRem("ranking:clicks", key).SetVal(1)
 
 		deleted, err := Delete(db, key)
 		if err != nil {
@@ -287,7 +310,10 @@ func TestDelete(t *testing.T) {
 		path := "path:" + key
 
 		// Expect Del to return an error.
-		mock.ExpectDel(path).SetErr(errors.New("delete error"))
+		mock.ExpectHKeys("stats:" + key).SetVal([]string{})
+		mock.ExpectHGet(path, "aliases").RedisNil()
+		mock.ExpectDel(path, "stats:"+key, "visitors:"+key,
+			"breakdown:"+key+"|referrer", "breakdown:"+key+"|device", "breakdown:"+key+"|browser", "breakdown:"+key+"|country", "breakdown:"+key+"|target").SetErr(errors.New("delete error"))
 
 		deleted, err := Delete(db, key)
 		if err == nil {
@@ -351,10 +377,11 @@ func TestGetAll(t *testing.T) {
 		fullKey := prefix + ":somepath"
 		mock.ExpectKeys(prefix + "*").SetVal([]string{fullKey})
 
-		// Expect HGet for url returns a valid URL.
-		mock.ExpectHGet(fullKey, "url").SetVal("https://example.com")
-		// Expect HGet for createdBy returns the owner.
-		mock.ExpectHGet(fullKey, "createdBy").SetVal("owner1")
+		// Expect HGetAll to return the url and owner fields.
+		mock.ExpectHGetAll(fullKey).SetVal(map[string]string{
+			"url":       "https://example.com",
+			"createdBy": "owner1",
+		})
 
 		results, err := GetAll(db, prefix)
 		if err != nil {
@@ -385,7 +412,7 @@ func TestGetAll(t *testing.T) {
 		// Return one key whose url field does not exist.
 		fullKey := prefix + ":nopage"
 		mock.ExpectKeys(prefix + "*").SetVal([]string{fullKey})
-		mock.ExpectHGet(fullKey, "url").RedisNil()
+		mock.ExpectHGetAll(fullKey).SetVal(map[string]string{})
 
 		results, err := GetAll(db, prefix)
 		if err != nil {
@@ -405,15 +432,15 @@ func TestGetAll(t *testing.T) {
 
 		fullKey := prefix + ":errorpage"
 		mock.ExpectKeys(prefix + "*").SetVal([]string{fullKey})
-		// Simulate a non-nil error (other than redis.Nil) on HGet for url.
-		mock.ExpectHGet(fullKey, "url").SetErr(errors.New("hget url error"))
+		// Simulate an error on HGetAll for the path hash.
+		mock.ExpectHGetAll(fullKey).SetErr(errors.New("hgetall error"))
 
 		_, err := GetAll(db, prefix)
 		if err == nil {
 			t.Fatal("expected error, got nil")
 		}
-		if err.Error() != "hget url error" {
-			t.Errorf("unexpected error: got %q, want %q", err.Error(), "hget url error")
+		if err.Error() != "hgetall error" {
+			t.Errorf("unexpected error: got %q, want %q", err.Error(), "hgetall error")
 		}
 		if err := mock.ExpectationsWereMet(); err != nil {
 			t.Errorf("unmet expectations: %v", err)
@@ -447,6 +474,7 @@ func TestGetPathOwner(t *testing.T) {
 
 		// Simulate redis.Nil which means the field was not found.
 		mock.ExpectHGet(pathKey, "createdBy").RedisNil()
+		mock.ExpectGet("alias:" + key).RedisNil()
 
 		owner, err := GetPathOwner(db, key)
 		if err == nil {
@@ -594,6 +622,26 @@ func TestValidatePathInput(t *testing.T) {
 			value:   "https://k.nhn.no/test",
 			wantErr: false,
 		},
+		{
+			name:    "Namespaced key",
+			key:     "hr:test",
+			value:   "https://example.com",
+			wantErr: false,
+		},
+		{
+			name:        "Invalid namespace",
+			key:         "HR Team:test",
+			value:       "https://example.com",
+			wantErr:     true,
+			errContains: "invalid namespace",
+		},
+		{
+			name:        "Reserved key in namespace",
+			key:         "hr:admin",
+			value:       "https://example.com",
+			wantErr:     true,
+			errContains: "reserved key",
+		},
 		// NOTE: URL format validation (empty, invalid protocol, etc.)
 		// is handled by IsURL() in the handler layer
 	}
@@ -0,0 +1,183 @@
+package redis
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/NorskHelsenett/ror/pkg/rlog"
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redis/v8"
+)
+
+var (
+	// ErrCampaignNotFound is returned when a campaign preset does not exist
+	ErrCampaignNotFound = errors.New("campaign not found")
+	// ErrInvalidCampaign is returned when a campaign preset name or its parameters are not allowed
+	ErrInvalidCampaign = errors.New("invalid campaign")
+)
+
+var campaignNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]*$`)
+
+// campaignKey returns the redis key of a campaign preset
+func campaignKey(name string) string {
+	return "campaign:" + strings.ToLower(name)
+}
+
+// GetUTM retrieves the UTM settings of a redirect
+func GetUTM(rdb *redis.Client, key string) (models.UTM, error) {
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return models.UTM{}, err
+	}
+
+	if redirect.UTM == nil {
+		return models.UTM{}, nil
+	}
+	return *redirect.UTM, nil
+}
+
+// SetUTM replaces the UTM settings of a redirect. Empty settings turn tagging off.
+func SetUTM(rdb *redis.Client, key string, utm models.UTM, user string) error {
+	utm.Preset = strings.ToLower(strings.TrimSpace(utm.Preset))
+	utm.UTMParams = trimUTMParams(utm.UTMParams)
+
+	if utm.Preset != "" {
+		if _, err := GetCampaign(rdb, utm.Preset); err != nil {
+			return err
+		}
+	}
+
+	redirect, err := GetRedirect(rdb, key)
+	if err != nil {
+		return err
+	}
+
+	encoded := ""
+	if utm != (models.UTM{}) {
+		raw, err := json.Marshal(utm)
+		if err != nil {
+			return err
+		}
+		encoded = string(raw)
+	}
+
+	editTime := time.Now().Format(time.RFC3339)
+	err = rdb.HSet(context.Background(), "path:"+redirect.Path,
+		"utm", encoded,
+		"lastEditBy", user,
+		"lastEditTime", editTime,
+	).Err()
+	if err != nil {
+		rlog.Error("Failed to save UTM settings", err, rlog.String("key", redirect.Path), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("UTM settings updated", rlog.String("key", redirect.Path), rlog.String("preset", utm.Preset), rlog.String("user", user))
+	return nil
+}
+
+// GetCampaigns retrieves all campaign presets sorted by name
+func GetCampaigns(rdb *redis.Client) ([]models.Campaign, error) {
+	ctx := context.Background()
+	keys, err := rdb.Keys(ctx, "campaign:*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	campaigns := make([]models.Campaign, 0, len(keys))
+	for _, key := range keys {
+		fields, err := rdb.HGetAll(ctx, key).Result()
+		if err != nil {
+			rlog.Error("Failed to get campaign", err, rlog.String("key", key))
+			continue
+		}
+		campaigns = append(campaigns, campaignFromHash(strings.TrimPrefix(key, "campaign:"), fields))
+	}
+
+	sort.Slice(campaigns, func(i, j int) bool { return campaigns[i].Name < campaigns[j].Name })
+	return campaigns, nil
+}
+
+// GetCampaign retrieves a single campaign preset by name
+func GetCampaign(rdb *redis.Client, name string) (models.Campaign, error) {
+	fields, err := rdb.HGetAll(context.Background(), campaignKey(name)).Result()
+	if err != nil {
+		return models.Campaign{}, err
+	}
+	if len(fields) == 0 {
+		return models.Campaign{}, fmt.Errorf("%w: `%s`", ErrCampaignNotFound, name)
+	}
+	return campaignFromHash(strings.ToLower(name), fields), nil
+}
+
+// SetCampaign creates or replaces a campaign preset
+func SetCampaign(rdb *redis.Client, campaign models.Campaign, user string) error {
+	if !campaignNamePattern.MatchString(campaign.Name) {
+		return fmt.Errorf("%w: name can only contain letters, numbers, dash and underscore", ErrInvalidCampaign)
+	}
+	campaign.UTMParams = trimUTMParams(campaign.UTMParams)
+	if campaign.UTMParams == (models.UTMParams{}) {
+		return fmt.Errorf("%w: at least one UTM parameter must be set", ErrInvalidCampaign)
+	}
+
+	editTime := time.Now().Format(time.RFC3339)
+	err := rdb.HSet(context.Background(), campaignKey(campaign.Name),
+		"source", campaign.Source,
+		"medium", campaign.Medium,
+		"campaign", campaign.Campaign,
+		"term", campaign.Term,
+		"content", campaign.Content,
+		"lastEditBy", user,
+		"lastEditTime", editTime,
+	).Err()
+	if err != nil {
+		rlog.Error("Failed to save campaign", err, rlog.String("name", campaign.Name), rlog.String("user", user))
+		return err
+	}
+
+	rlog.Info("Campaign updated", rlog.String("name", campaign.Name), rlog.String("user", user))
+	return nil
+}
+
+// DeleteCampaign removes a campaign preset. Redirects using it keep their own UTM parameters.
+func DeleteCampaign(rdb *redis.Client, name string) error {
+	deleted, err := rdb.Del(context.Background(), campaignKey(name)).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return fmt.Errorf("%w: `%s`", ErrCampaignNotFound, name)
+	}
+	return nil
+}
+
+// campaignFromHash builds a campaign preset from its stored hash fields
+func campaignFromHash(name string, fields map[string]string) models.Campaign {
+	return models.Campaign{
+		Name: name,
+		UTMParams: models.UTMParams{
+			Source:   fields["source"],
+			Medium:   fields["medium"],
+			Campaign: fields["campaign"],
+			Term:     fields["term"],
+			Content:  fields["content"],
+		},
+	}
+}
+
+// trimUTMParams removes surrounding whitespace from all UTM parameters
+func trimUTMParams(params models.UTMParams) models.UTMParams {
+	return models.UTMParams{
+		Source:   strings.TrimSpace(params.Source),
+		Medium:   strings.TrimSpace(params.Medium),
+		Campaign: strings.TrimSpace(params.Campaign),
+		Term:     strings.TrimSpace(params.Term),
+		Content:  strings.TrimSpace(params.Content),
+	}
+}
@@ -0,0 +1,118 @@
+package redis
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/NorskHelsenett/shorty/internal/models"
+	"github.com/go-redis/redismock/v8"
+)
+
+func TestSetUTM(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "testuser"
+
+	t.Run("Preset with override", func(t *testing.T) {
+		mock.ExpectHGetAll("campaign:spring").SetVal(map[string]string{"source": "newsletter", "campaign": "spring"})
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com"})
+		mock.ExpectHSet("path:ab",
+			"utm", `{"preset":"spring","medium":"email"}`,
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(1)
+
+		utm := models.UTM{Preset: " Spring ", UTMParams: models.UTMParams{Medium: "email"}}
+		if err := SetUTM(db, "ab", utm, user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Unknown preset", func(t *testing.T) {
+		mock.ExpectHGetAll("campaign:missing").SetVal(map[string]string{})
+
+		err := SetUTM(db, "ab", models.UTM{Preset: "missing"}, user)
+		if !errors.Is(err, ErrCampaignNotFound) {
+			t.Errorf("expected ErrCampaignNotFound, got %v", err)
+		}
+	})
+
+	t.Run("Empty settings turn tagging off", func(t *testing.T) {
+		mock.ExpectHGetAll("path:ab").SetVal(map[string]string{"url": "https://example.com", "utm": `{"source":"x"}`})
+		mock.ExpectHSet("path:ab",
+			"utm", "",
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(0)
+
+		if err := SetUTM(db, "ab", models.UTM{}, user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
+
+func TestCampaigns(t *testing.T) {
+	db, mock := redismock.NewClientMock()
+	user := "admin"
+
+	t.Run("Set campaign", func(t *testing.T) {
+		mock.ExpectHSet("campaign:spring",
+			"source", "newsletter",
+			"medium", "email",
+			"campaign", "spring",
+			"term", "",
+			"content", "",
+			"lastEditBy", user,
+			"lastEditTime", time.Now().Format(time.RFC3339),
+		).SetVal(7)
+
+		campaign := models.Campaign{Name: "Spring", UTMParams: models.UTMParams{Source: "newsletter", Medium: "email", Campaign: "spring"}}
+		if err := SetCampaign(db, campaign, user); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("Invalid campaign name", func(t *testing.T) {
+		err := SetCampaign(db, models.Campaign{Name: "spring sale", UTMParams: models.UTMParams{Source: "x"}}, user)
+		if !errors.Is(err, ErrInvalidCampaign) {
+			t.Errorf("expected ErrInvalidCampaign, got %v", err)
+		}
+	})
+
+	t.Run("Campaign without parameters", func(t *testing.T) {
+		err := SetCampaign(db, models.Campaign{Name: "empty"}, user)
+		if !errors.Is(err, ErrInvalidCampaign) {
+			t.Errorf("expected ErrInvalidCampaign, got %v", err)
+		}
+	})
+
+	t.Run("List campaigns sorted", func(t *testing.T) {
+		mock.ExpectKeys("campaign:*").SetVal([]string{"campaign:summer", "campaign:spring"})
+		mock.ExpectHGetAll("campaign:summer").SetVal(map[string]string{"source": "web"})
+		mock.ExpectHGetAll("campaign:spring").SetVal(map[string]string{"source": "newsletter"})
+
+		campaigns, err := GetCampaigns(db)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(campaigns) != 2 || campaigns[0].Name != "spring" || campaigns[1].Source != "web" {
+			t.Errorf("unexpected campaigns: %+v", campaigns)
+		}
+	})
+
+	t.Run("Delete unknown campaign", func(t *testing.T) {
+		mock.ExpectDel("campaign:missing").SetVal(0)
+
+		if err := DeleteCampaign(db, "missing"); !errors.Is(err, ErrCampaignNotFound) {
+			t.Errorf("expected ErrCampaignNotFound, got %v", err)
+		}
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package redis
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+const (
+	// visitorSaltTTL keeps a daily salt a little longer than its day, after which it is gone for good
+	visitorSaltTTL = 48 * time.Hour
+	// visitorRetention is how long the daily unique visitor estimates are kept
+	visitorRetention = (MaxStatsDays + 31) * 24 * time.Hour
+)
+
+var visitorSaltCache struct {
+	sync.Mutex
+	date string
+	salt string
+}
+
+// visitorsKey returns the redis key of the HyperLogLog counting the visitors of a link
+func visitorsKey(key string) string {
+	return "visitors:" + NormalizeKey(key)
+}
+
+// dailyVisitorsKey returns the redis key of the HyperLogLog counting the visitors of a link on one day.
+// "|" cannot appear in keys, so the date never clashes with a namespaced key.
+func dailyVisitorsKey(key string, date string) string {
+	return visitorsKey(key) + "|" + date
+}
+
+// visitorSalt returns the random salt of a day, shared by all instances through redis.
+// Salts expire shortly after their day, so visitor ids cannot be linked across days or traced back.
+func visitorSalt(rdb *redis.Client, date string) (string, error) {
+	visitorSaltCache.Lock()
+	defer visitorSaltCache.Unlock()
+	if visitorSaltCache.date == date {
+		return visitorSaltCache.salt, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	saltKey := "visitor-salt:" + date
+	if err := rdb.SetNX(ctx, saltKey, hex.EncodeToString(random), visitorSaltTTL).Err(); err != nil {
+		return "", err
+	}
+	salt, err := rdb.Get(ctx, saltKey).Result()
+	if err != nil {
+		return "", err
+	}
+
+	visitorSaltCache.date, visitorSaltCache.salt = date, salt
+	return salt, nil
+}
+
+// VisitorID returns an anonymous id for a client on the day of at. The id is a hash of the
+// client address and User-Agent with the salt of the day, so raw addresses are never stored.
+func VisitorID(rdb *redis.Client, ip string, userAgent string, at time.Time) (string, error) {
+	salt, err := visitorSalt(rdb, at.UTC().Format(statsDateFormat))
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha256.Sum256([]byte(salt + "|" + ip + "|" + userAgent))
+	return hex.EncodeToString(sum[:16]), nil
+}